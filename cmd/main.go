@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"note/config"
+	"note/internal/admin"
+	"note/internal/jobs"
 	"note/internal/middleware"
 	"note/internal/models"
 	"note/internal/note"
@@ -34,16 +37,37 @@ func main() {
 	svcCtx := svc.NewServiceContext(cfg)
 	defer svcCtx.Close()
 
-	// 启动消费者
-	if svcCtx.Consumer != nil {
-		svcCtx.Consumer.Start()
+	// 迁移所有模型；表情回应的唯一索引要先清理掉重复数据才能建立
+	if err := note.DedupReactions(svcCtx.DB); err != nil {
+		zap.L().Panic("failed to dedup reactions", zap.Error(err))
 	}
-
-	// 迁移所有模型
-	err = svcCtx.DB.AutoMigrate(&models.User{}, &models.Note{}, &models.Tag{}, &models.Favorite{}, &models.Reaction{}, &models.UserFollow{}, &models.History{})
+	err = svcCtx.DB.AutoMigrate(&models.User{}, &models.Note{}, &models.Tag{}, &models.Favorite{}, &models.Reaction{}, &models.UserFollow{}, &models.History{}, &models.TagSuggestion{}, &models.UserSetting{}, &models.AITask{}, &models.AIUsage{}, &models.Flashcard{}, &models.FlashcardReview{}, &models.Topic{}, &models.NoteTopic{}, &models.Digest{}, &models.UserBlock{}, &models.TopicFollow{}, &models.NoteDailyStat{}, &models.Collection{}, &models.CollectionItem{}, &models.CollectionFollow{})
 	if err != nil {
 		zap.L().Panic("failed to migrate database", zap.Error(err))
 	}
+	if err := tag.BackfillTopics(svcCtx.DB); err != nil {
+		zap.L().Warn("backfill tag topics failed", zap.Error(err))
+	}
+	if err := note.BackfillSimHash(svcCtx.DB); err != nil {
+		zap.L().Warn("backfill note simhash failed", zap.Error(err))
+	}
+	// Redis 连不上时 svc 会继续以 nil 缓存运行
+	if svcCtx.Cache != nil {
+		if err := svcCtx.Cache.DropLegacyTimelines(context.Background()); err != nil {
+			zap.L().Warn("drop legacy timelines failed", zap.Error(err))
+		}
+	}
+
+	// 消费者和定时任务会读写上面迁移的表，迁移和回填完成后再启动
+	if svcCtx.Consumer != nil {
+		svcCtx.Consumer.Start()
+	}
+
+	// 启动定时任务
+	scheduler := jobs.NewScheduler(svcCtx.Cache)
+	jobs.Register(scheduler, svcCtx)
+	scheduler.Start()
+	defer scheduler.Stop()
 
 	r := gin.Default()
 	r.Use(otelgin.Middleware("note-service"))
@@ -65,11 +89,20 @@ func main() {
 
 			users.GET("/:id", userHandler.PersonalPage)
 			users.PUT("/me", userHandler.UpdateMyProfile)
+			users.GET("/me/settings", userHandler.GetMySettings)
+			users.PUT("/me/settings", userHandler.UpdateMySettings)
+			users.GET("/me/ai-usage", userHandler.GetMyAIUsage)
+			users.GET("/me/topics", userHandler.GetMyTopics)
+			users.POST("/me/topics/:tid/tag", userHandler.ConvertTopicToTag)
+
+			users.GET("/me/blocks", userHandler.ListMyBlocks)
 
 			users.POST("/:id/follow", userHandler.FollowUser)
 			users.DELETE("/:id/follow", userHandler.UnfollowUser)
 			users.GET("/:id/following", userHandler.GetFollowingList)
 			users.GET("/:id/followers", userHandler.GetFollowersList)
+			users.POST("/:id/block", userHandler.BlockUser)
+			users.DELETE("/:id/block", userHandler.UnblockUser)
 		}
 
 		noteHandler := note.NewNoteHandler(svcCtx)
@@ -85,8 +118,30 @@ func main() {
 			notes.POST("/images", middleware.RateLimitMiddleware(svcCtx.Cache, "upload_img", 3, time.Minute), noteHandler.UploadImage)
 			notes.GET("/search", noteHandler.SearchNotes)
 			notes.GET("/smartsearch", noteHandler.SmartSearch)
+			notes.GET("/:id/related", noteHandler.GetRelatedNotes)
+			notes.GET("/:id/reactions", noteHandler.ListNoteReactions)
+			notes.GET("/reaction-emojis", noteHandler.ListReactionEmojis)
+
+			notes.GET("/:id/ai-tasks", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.ListAITasks)
+			notes.POST("/:id/ai-tasks", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.CreateAITask)
+			notes.POST("/:id/ai-tasks/:tid/retry", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.RetryAITask)
+
+			notes.GET("/duplicates", noteHandler.ListDuplicates)
+			notes.POST("/:id/merge", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.MergeNotes)
+
+			notes.GET("/:id/flashcards", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.ListFlashcards)
+
+			notes.POST("/:id/assist", middleware.NoteOwnerMiddleware(svcCtx.DB), middleware.RateLimitMiddleware(svcCtx.Cache, "ai_assist", 10, time.Minute), noteHandler.Assist)
+
+			notes.POST("/:id/tag-suggestions", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.RequestTagSuggestions)
+			notes.GET("/:id/tag-suggestions", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.ListTagSuggestions)
+			notes.POST("/:id/tag-suggestions/:sid/accept", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.AcceptTagSuggestion)
+			notes.POST("/:id/tag-suggestions/:sid/reject", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.RejectTagSuggestion)
 
 			notes.GET("/recent", noteHandler.GetRecentNotes)
+			notes.GET("/history", noteHandler.ListHistory)
+			notes.DELETE("/history", noteHandler.ClearHistory)
+			notes.DELETE("/:id/history", noteHandler.DeleteHistoryEntry)
 
 			notes.PATCH("/:id/pin", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.TogglePin)
 			notes.POST("/:id/favorite", noteHandler.FavoriteNote)
@@ -95,6 +150,42 @@ func main() {
 
 			notes.GET("/community", noteHandler.ListPublicNotes)
 			notes.GET("/follow", noteHandler.GetFollowingFeed)
+			notes.GET("/foryou", noteHandler.GetForYouFeed)
+
+			notes.GET("/analytics", noteHandler.GetMyAnalytics)
+			notes.GET("/:id/analytics", middleware.NoteOwnerMiddleware(svcCtx.DB), noteHandler.GetNoteAnalytics)
+		}
+
+		topics := auth.Group("/topics")
+		{
+			topics.GET("/trending", noteHandler.TrendingTopics)
+			topics.GET("/following", noteHandler.ListFollowedTopics)
+			topics.GET("/:name", noteHandler.GetPublicTopic)
+			topics.GET("/:name/notes", noteHandler.ListTopicNotes)
+			topics.POST("/:name/follow", noteHandler.FollowTopic)
+			topics.DELETE("/:name/follow", noteHandler.UnfollowTopic)
+		}
+
+		collections := auth.Group("/collections")
+		{
+			collections.POST("", noteHandler.CreateCollection)
+			collections.GET("/following", noteHandler.ListFollowedCollections)
+			collections.GET("/:id", noteHandler.GetCollection)
+			collections.PUT("/:id", noteHandler.UpdateCollection)
+			collections.DELETE("/:id", noteHandler.DeleteCollection)
+			collections.GET("/:id/items", noteHandler.ListCollectionItems)
+			collections.POST("/:id/items", noteHandler.AddCollectionItem)
+			collections.PUT("/:id/items/:nid", noteHandler.UpdateCollectionItem)
+			collections.DELETE("/:id/items/:nid", noteHandler.RemoveCollectionItem)
+			collections.PUT("/:id/order", noteHandler.ReorderCollection)
+			collections.POST("/:id/follow", noteHandler.FollowCollection)
+			collections.DELETE("/:id/follow", noteHandler.UnfollowCollection)
+		}
+
+		flashcards := auth.Group("/flashcards")
+		{
+			flashcards.GET("/due", noteHandler.ListDueFlashcards)
+			flashcards.POST("/:cid/review", noteHandler.ReviewFlashcard)
 		}
 
 		tagHandler := tag.NewNoteTag(svcCtx)
@@ -106,6 +197,16 @@ func main() {
 			tags.PUT("/:id", tagHandler.UpdateTag)
 			tags.DELETE("/:id", tagHandler.DeleteTag)
 		}
+
+		adminHandler := admin.NewAdminHandler(svcCtx)
+		admins := auth.Group("/admin")
+		admins.Use(middleware.AdminMiddleware(cfg))
+		{
+			admins.POST("/reindex", adminHandler.StartReindex)
+			admins.GET("/reindex/:collection", adminHandler.ReindexStatus)
+			admins.GET("/reindex/:collection/failed", adminHandler.ReindexFailures)
+			admins.POST("/reconcile", adminHandler.Reconcile)
+		}
 	}
 
 	addr := ":" + cfg.ServerPort
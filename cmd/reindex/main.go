@@ -0,0 +1,97 @@
+// reindex 从 MySQL 全量重建向量索引。
+//
+// 更换向量模型 (VOLC_EMBED_MODEL_ID / AI_EMBED_DIM) 时，用新配置运行：
+//
+//	go run ./cmd/reindex -switch
+//
+// 会新建一个带时间戳的集合写入全部笔记，完成后把 VECTOR_COLLECTION 别名原子地切过去，
+// 之后用新配置重启服务即可。中断后带上同一个 -collection 再次运行会从断点继续。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"note/config"
+	"note/internal/indexer"
+	"note/internal/infra/ai"
+	"note/internal/infra/cache"
+	"note/internal/infra/db"
+	"note/internal/infra/vector"
+	"note/internal/utils"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		panic("failed to load config: " + err.Error())
+	}
+
+	collection := flag.String("collection", "", "目标集合，为空时新建 <VECTOR_COLLECTION>_<时间戳>；等于 VECTOR_COLLECTION 表示原地重建")
+	switchAlias := flag.Bool("switch", false, "完成后把 VECTOR_COLLECTION 别名切到目标集合")
+	restart := flag.Bool("restart", false, "忽略已有进度，从头开始")
+	batch := flag.Int("batch", cfg.ReindexBatchSize, "每批读取的笔记数")
+	rate := flag.Int("rate", cfg.ReindexRate, "每秒最多生成的向量数")
+	flag.Parse()
+
+	utils.InitLogger(cfg.AppEnv)
+	defer func() {
+		_ = zap.L().Sync()
+	}()
+
+	if *collection == "" {
+		*collection = fmt.Sprintf("%s_%s", cfg.VectorCollection, time.Now().Format("20060102150405"))
+	}
+
+	dbConn := db.InitMySQL(cfg)
+
+	rdb, err := cache.New(cfg)
+	if err != nil {
+		zap.L().Fatal("Redis is required to track reindex progress", zap.Error(err))
+	}
+
+	aiService, err := ai.NewAIService(cfg, rdb, nil)
+	if err != nil {
+		zap.L().Fatal("failed to init ai service", zap.Error(err))
+	}
+
+	admin, err := vector.NewAdmin(cfg)
+	if err != nil {
+		zap.L().Fatal("failed to init vector admin", zap.Error(err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reindexer := indexer.NewReindexer(dbConn, aiService, rdb, admin, cfg.VectorCollection, nil)
+	progress, err := reindexer.Run(ctx, indexer.ReindexOptions{
+		Collection:  *collection,
+		SwitchAlias: *switchAlias,
+		Restart:     *restart,
+		BatchSize:   *batch,
+		Rate:        *rate,
+	})
+	if err != nil {
+		zap.L().Fatal("Reindex failed", zap.String("collection", *collection), zap.Error(err))
+	}
+
+	zap.L().Info("Reindex finished",
+		zap.String("collection", progress.Collection),
+		zap.String("model", progress.Model),
+		zap.Int64("indexed", progress.Indexed),
+		zap.Int64("failed", progress.Failed),
+		zap.Bool("switched", progress.Switched))
+
+	if progress.Failed > 0 {
+		failed, _ := reindexer.FailedNotes(context.Background(), *collection)
+		for id, reason := range failed {
+			zap.L().Warn("Failed note", zap.Uint("note_id", id), zap.String("error", reason))
+		}
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"note/internal/dedup"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/viper"
 )
@@ -22,6 +26,14 @@ type Config struct {
 	JWTIssuer         string        `mapstructure:"JWT_ISSUER"`
 	JWTExpirationTime time.Duration `mapstructure:"JWT_EXPIRATION_TIME"`
 
+	// 列表分页：游标签名密钥 (必填，不能与 JWT 密钥相同)，limit 默认值和上限
+	CursorSecret     string `mapstructure:"CURSOR_SECRET"`
+	PageDefaultLimit int    `mapstructure:"PAGE_DEFAULT_LIMIT"`
+	PageMaxLimit     int    `mapstructure:"PAGE_MAX_LIMIT"`
+
+	// 管理员用户 ID，逗号分隔
+	AdminUserIDs []uint `mapstructure:"ADMIN_USER_IDS"`
+
 	RedisHost     string `mapstructure:"REDIS_HOST"`
 	RedisPort     string `mapstructure:"REDIS_PORT"`
 	RedisPassword string `mapstructure:"REDIS_PASSWORD"`
@@ -36,11 +48,102 @@ type Config struct {
 	QdrantPort   int    `mapstructure:"QDRANT_PORT"`
 	QdrantAPIKey string `mapstructure:"QDRANT_API_KEY"`
 
+	// 向量索引：qdrant 或 local (进程内，持久化到 VectorLocalDir)
+	VectorBackend    string `mapstructure:"VECTOR_BACKEND"`
+	VectorCollection string `mapstructure:"VECTOR_COLLECTION"`
+	VectorLocalDir   string `mapstructure:"VECTOR_LOCAL_DIR"`
+	// 删除/私密变更同步到向量索引失败时的最大重试次数
+	VectorSyncMaxRetries int `mapstructure:"VECTOR_SYNC_MAX_RETRIES"`
+
+	// 重建向量索引
+	ReindexBatchSize   int           `mapstructure:"REINDEX_BATCH_SIZE"`
+	ReindexRate        int           `mapstructure:"REINDEX_RATE"`
+	ReconcileInterval  time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	ReconcileMaxRepair int           `mapstructure:"RECONCILE_MAX_REPAIR"`
+
+	// 重复笔记检测：simhash 汉明距离不超过 DedupSimHashDistance (0-3)，或向量相似度不低于 DedupVectorThreshold 视为重复
+	DedupSimHashDistance int     `mapstructure:"DEDUP_SIMHASH_DISTANCE"`
+	DedupVectorThreshold float32 `mapstructure:"DEDUP_VECTOR_THRESHOLD"`
+
+	// 主题聚类：定时为有变化的用户重新聚类；笔记数低于 TopicMinNotes 不聚类；
+	// 新笔记和主题中心相似度不低于 TopicAssignThreshold 时直接归入，否则等下次重新聚类
+	TopicClusterInterval time.Duration `mapstructure:"TOPIC_CLUSTER_INTERVAL"`
+	TopicMinNotes        int           `mapstructure:"TOPIC_MIN_NOTES"`
+	TopicMaxClusters     int           `mapstructure:"TOPIC_MAX_CLUSTERS"`
+	TopicAssignThreshold float32       `mapstructure:"TOPIC_ASSIGN_THRESHOLD"`
+
+	// 时间线：粉丝数达到 FeedBigAuthorFans 的作者不推送，读时拉取 (0 表示全部推送)；推送时每批处理的粉丝数
+	FeedBigAuthorFans int `mapstructure:"FEED_BIG_AUTHOR_FANS"`
+	FeedPushBatch     int `mapstructure:"FEED_PUSH_BATCH"`
+	// 新关注时回填作者最近多少篇公开笔记到时间线
+	FeedBackfillCount int `mapstructure:"FEED_BACKFILL_COUNT"`
+
+	// 社区排行：互动分中收藏、表情回应、浏览的权重；热度衰减周期 (互动分多 10 倍相当于晚发布这么久)；
+	// 热门榜保留的条数；定时重算互动分 (纳入浏览数) 并重建热门榜的间隔，只有这段时间内发布的笔记进入热门榜
+	RankFavoriteWeight  float64       `mapstructure:"RANK_FAVORITE_WEIGHT"`
+	RankReactionWeight  float64       `mapstructure:"RANK_REACTION_WEIGHT"`
+	RankViewWeight      float64       `mapstructure:"RANK_VIEW_WEIGHT"`
+	RankHotDecay        time.Duration `mapstructure:"RANK_HOT_DECAY"`
+	RankHotSize         int           `mapstructure:"RANK_HOT_SIZE"`
+	RankRebuildInterval time.Duration `mapstructure:"RANK_REBUILD_INTERVAL"`
+	RankHotWindow       time.Duration `mapstructure:"RANK_HOT_WINDOW"`
+
+	// 为你推荐：每次生成的列表长度，探索内容 (最新、热门) 的占比，列表会话的过期时间，
+	// 每类互动 (收藏、表情回应、浏览) 取最近多少条计算兴趣向量
+	RecommendPoolSize     int           `mapstructure:"RECOMMEND_POOL_SIZE"`
+	RecommendExploreRatio float64       `mapstructure:"RECOMMEND_EXPLORE_RATIO"`
+	RecommendSessionTTL   time.Duration `mapstructure:"RECOMMEND_SESSION_TTL"`
+	RecommendSignalLimit  int           `mapstructure:"RECOMMEND_SIGNAL_LIMIT"`
+
+	// 表情回应可以使用的 emoji (逗号分隔，每个不超过 10 个字符)
+	ReactionEmojis []string `mapstructure:"REACTION_EMOJIS"`
+
+	// 笔记统计：同一用户在 AnalyticsViewWindow 内重复浏览同一篇笔记只计一次；计数先缓冲在 Redis，每隔 AnalyticsFlushInterval 写入数据库
+	AnalyticsViewWindow    time.Duration `mapstructure:"ANALYTICS_VIEW_WINDOW"`
+	AnalyticsFlushInterval time.Duration `mapstructure:"ANALYTICS_FLUSH_INTERVAL"`
+
+	// 阅读历史：保留多久 (按最后一次浏览时间)，清理过期历史的间隔
+	HistoryRetention     time.Duration `mapstructure:"HISTORY_RETENTION"`
+	HistoryPruneInterval time.Duration `mapstructure:"HISTORY_PRUNE_INTERVAL"`
+
+	// AI 摘要：检查哪些用户到期的间隔，摘要长度上限
+	DigestCheckInterval time.Duration `mapstructure:"DIGEST_CHECK_INTERVAL"`
+	DigestMaxLength     int           `mapstructure:"DIGEST_MAX_LENGTH"`
+
 	VolcEngineKey     string `mapstructure:"VOLC_ENGINE_KEY"`
 	VolcEngineBaseURL string `mapstructure:"VOLC_ENGINE_BASE_URL"`
 	VolcChatModelID   string `mapstructure:"VOLC_CHAT_MODEL_ID"`
 	VolcEmbedModelID  string `mapstructure:"VOLC_EMBED_MODEL_ID"`
 
+	// AI provider：openai (OpenAI 兼容接口) 或 local (离线确定性实现)，对话和向量可分开选
+	AIChatProvider    string        `mapstructure:"AI_CHAT_PROVIDER"`
+	AIEmbedProvider   string        `mapstructure:"AI_EMBED_PROVIDER"`
+	AIEmbedDim        int           `mapstructure:"AI_EMBED_DIM"`
+	AIChatTemperature float32       `mapstructure:"AI_CHAT_TEMPERATURE"`
+	AIChatTimeout     time.Duration `mapstructure:"AI_CHAT_TIMEOUT"`
+	AIEmbedTimeout    time.Duration `mapstructure:"AI_EMBED_TIMEOUT"`
+	AIMaxInputRunes   int           `mapstructure:"AI_MAX_INPUT_RUNES"`
+	// 向量缓存过期时间，0 表示不缓存
+	AIEmbedCacheTTL time.Duration `mapstructure:"AI_EMBED_CACHE_TTL"`
+	// 提示词模板：额外的模板目录 (覆盖内置模板或新增版本)、各任务使用的版本及 A/B 权重
+	AIPromptDir      string `mapstructure:"AI_PROMPT_DIR"`
+	AIPromptVersions string `mapstructure:"AI_PROMPT_VERSIONS"`
+	// 生成标题、摘要的长度上限 (中文为字数，其他语言为词数)
+	AITitleMaxLength   int `mapstructure:"AI_TITLE_MAX_LENGTH"`
+	AISummaryMaxLength int `mapstructure:"AI_SUMMARY_MAX_LENGTH"`
+	// AI 标签建议：每篇笔记最多建议几个，是否允许提出用户还没有的新标签，新标签接受时使用的颜色
+	AISuggestTagsMax  int    `mapstructure:"AI_SUGGEST_TAGS_MAX"`
+	AISuggestNewTags  bool   `mapstructure:"AI_SUGGEST_NEW_TAGS"`
+	AISuggestTagColor string `mapstructure:"AI_SUGGEST_TAG_COLOR"`
+	// 每篇笔记最多生成几张问答卡片
+	AIFlashcardsMax int `mapstructure:"AI_FLASHCARDS_MAX"`
+
+	// 各等级用户每日/每月的 AI token 配额，0 表示不限
+	AIQuotaFreeDaily   int64 `mapstructure:"AI_QUOTA_FREE_DAILY"`
+	AIQuotaFreeMonthly int64 `mapstructure:"AI_QUOTA_FREE_MONTHLY"`
+	AIQuotaProDaily    int64 `mapstructure:"AI_QUOTA_PRO_DAILY"`
+	AIQuotaProMonthly  int64 `mapstructure:"AI_QUOTA_PRO_MONTHLY"`
+
 	MinioEndpoint  string `mapstructure:"MINIO_ENDPOINT"`
 	MinioPublicURL string `mapstructure:"MINIO_PUBLIC_URL"`
 	MinioAccessKey string `mapstructure:"MINIO_ACCESS_KEY"`
@@ -78,10 +181,49 @@ func Load() (*Config, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal configuration: %w", err)
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
 
+// maxReactionEmojiLen reactions.emoji 列的长度 (size:10，按字符计)
+const maxReactionEmojiLen = 10
+
+// validate 检查并规整需要和数据库约束一致的配置
+func (c *Config) validate() error {
+	emojis := make([]string, 0, len(c.ReactionEmojis))
+	for _, e := range c.ReactionEmojis {
+		e = strings.TrimSpace(e)
+		if e == "" || slices.Contains(emojis, e) {
+			continue
+		}
+		if utf8.RuneCountInString(e) > maxReactionEmojiLen {
+			return fmt.Errorf("REACTION_EMOJIS: %q is longer than %d characters", e, maxReactionEmojiLen)
+		}
+		emojis = append(emojis, e)
+	}
+	if len(emojis) == 0 {
+		return errors.New("REACTION_EMOJIS must contain at least one emoji")
+	}
+	c.ReactionEmojis = emojis
+
+	// 指纹按 4 段索引，距离超过 3 时按段查找会漏掉相近的笔记
+	if c.DedupSimHashDistance < 0 || c.DedupSimHashDistance > dedup.MaxDistance {
+		return fmt.Errorf("DEDUP_SIMHASH_DISTANCE must be between 0 and %d", dedup.MaxDistance)
+	}
+
+	// 游标密钥单独配置，泄露或轮换时不影响登录态
+	if c.CursorSecret == "" {
+		return errors.New("CURSOR_SECRET is required")
+	}
+	if c.CursorSecret == c.JWTSecretKey {
+		return errors.New("CURSOR_SECRET must differ from JWT_SECRET_KEY")
+	}
+	return nil
+}
+
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("APP_ENV", "dev") // 默认为开发环境
 
@@ -91,6 +233,8 @@ func setDefaults(v *viper.Viper) {
 
 	v.SetDefault("SERVER_PORT", "8080")
 	v.SetDefault("JWT_EXPIRATION_TIME", "24h")
+	v.SetDefault("PAGE_DEFAULT_LIMIT", 20)
+	v.SetDefault("PAGE_MAX_LIMIT", 100)
 
 	v.SetDefault("REDIS_HOST", "localhost")
 	v.SetDefault("REDIS_PORT", "6379")
@@ -99,8 +243,64 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("QDRANT_HOST", "localhost")
 	v.SetDefault("QDRANT_PORT", 6334)
 
+	v.SetDefault("VECTOR_BACKEND", "qdrant")
+	v.SetDefault("VECTOR_COLLECTION", "notes_collection")
+	v.SetDefault("VECTOR_LOCAL_DIR", "./data/vector")
+	v.SetDefault("VECTOR_SYNC_MAX_RETRIES", 5)
+
+	v.SetDefault("REINDEX_BATCH_SIZE", 100)
+	v.SetDefault("REINDEX_RATE", 5)
+	v.SetDefault("RECONCILE_INTERVAL", "30m")
+	v.SetDefault("RECONCILE_MAX_REPAIR", 100)
+	v.SetDefault("DEDUP_SIMHASH_DISTANCE", 3)
+	v.SetDefault("DEDUP_VECTOR_THRESHOLD", 0.95)
+	v.SetDefault("TOPIC_CLUSTER_INTERVAL", "10m")
+	v.SetDefault("TOPIC_MIN_NOTES", 5)
+	v.SetDefault("TOPIC_MAX_CLUSTERS", 20)
+	v.SetDefault("TOPIC_ASSIGN_THRESHOLD", 0.75)
+	v.SetDefault("FEED_BIG_AUTHOR_FANS", 10000)
+	v.SetDefault("FEED_PUSH_BATCH", 1000)
+	v.SetDefault("FEED_BACKFILL_COUNT", 20)
+	v.SetDefault("RANK_FAVORITE_WEIGHT", 3)
+	v.SetDefault("RANK_REACTION_WEIGHT", 1)
+	v.SetDefault("RANK_VIEW_WEIGHT", 0.1)
+	v.SetDefault("RANK_HOT_DECAY", "12h30m")
+	v.SetDefault("RANK_HOT_SIZE", 1000)
+	v.SetDefault("RANK_REBUILD_INTERVAL", "1h")
+	v.SetDefault("RANK_HOT_WINDOW", "720h")
+	v.SetDefault("RECOMMEND_POOL_SIZE", 200)
+	v.SetDefault("RECOMMEND_EXPLORE_RATIO", 0.2)
+	v.SetDefault("RECOMMEND_SESSION_TTL", "30m")
+	v.SetDefault("RECOMMEND_SIGNAL_LIMIT", 100)
+	v.SetDefault("REACTION_EMOJIS", []string{"❤️", "👍", "🔥", "👏", "😂", "😮", "😢", "🎉", "👀"})
+	v.SetDefault("ANALYTICS_VIEW_WINDOW", "30m")
+	v.SetDefault("ANALYTICS_FLUSH_INTERVAL", "1m")
+	v.SetDefault("HISTORY_RETENTION", "2160h")
+	v.SetDefault("HISTORY_PRUNE_INTERVAL", "24h")
+	v.SetDefault("DIGEST_CHECK_INTERVAL", "1h")
+	v.SetDefault("DIGEST_MAX_LENGTH", 500)
+
 	v.SetDefault("VOLC_ENGINE_BASE_URL", "https://ark.cn-beijing.volces.com/api/v3")
 
+	v.SetDefault("AI_CHAT_PROVIDER", "openai")
+	v.SetDefault("AI_EMBED_PROVIDER", "openai")
+	v.SetDefault("AI_EMBED_DIM", 2048)
+	v.SetDefault("AI_CHAT_TEMPERATURE", 0.7)
+	v.SetDefault("AI_CHAT_TIMEOUT", "30s")
+	v.SetDefault("AI_EMBED_TIMEOUT", "30s")
+	v.SetDefault("AI_MAX_INPUT_RUNES", 2000)
+	v.SetDefault("AI_EMBED_CACHE_TTL", "720h")
+	v.SetDefault("AI_TITLE_MAX_LENGTH", 15)
+	v.SetDefault("AI_SUMMARY_MAX_LENGTH", 50)
+	v.SetDefault("AI_SUGGEST_TAGS_MAX", 3)
+	v.SetDefault("AI_SUGGEST_NEW_TAGS", true)
+	v.SetDefault("AI_SUGGEST_TAG_COLOR", "#8c8c8c")
+	v.SetDefault("AI_FLASHCARDS_MAX", 10)
+	v.SetDefault("AI_QUOTA_FREE_DAILY", 50000)
+	v.SetDefault("AI_QUOTA_FREE_MONTHLY", 1000000)
+	v.SetDefault("AI_QUOTA_PRO_DAILY", 0)
+	v.SetDefault("AI_QUOTA_PRO_MONTHLY", 0)
+
 	v.SetDefault("MINIO_ENDPOINT", "localhost:9000")
 	v.SetDefault("MINIO_PUBLIC_URL", "http://localhost:9000")
 	v.SetDefault("MINIO_BUCKET", "notes-images")
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateReactionEmojis(t *testing.T) {
+	tests := []struct {
+		name    string
+		emojis  []string
+		want    []string
+		wantErr bool
+	}{
+		{"trims and drops blanks", []string{" 👍", "🎉 ", "", "  "}, []string{"👍", "🎉"}, false},
+		{"drops duplicates", []string{"👍", " 👍"}, []string{"👍"}, false},
+		{"multi code point emoji fits", []string{"❤️", "👨‍👩‍👧"}, []string{"❤️", "👨‍👩‍👧"}, false},
+		{"longer than the column", []string{"👍", "abcdefghijk"}, nil, true},
+		{"empty", []string{" ", ""}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ReactionEmojis: tt.emojis, CursorSecret: "s"}
+			err := cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(cfg.ReactionEmojis, tt.want) {
+				t.Fatalf("ReactionEmojis = %q, want %q", cfg.ReactionEmojis, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCursorSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		jwt     string
+		wantErr bool
+	}{
+		{"separate secret", "cursor", "jwt", false},
+		{"missing", "", "jwt", true},
+		{"same as jwt", "jwt", "jwt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ReactionEmojis: []string{"👍"}, CursorSecret: tt.cursor, JWTSecretKey: tt.jwt}
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDedupDistance(t *testing.T) {
+	for _, tt := range []struct {
+		distance int
+		wantErr  bool
+	}{{0, false}, {3, false}, {4, true}, {-1, true}} {
+		cfg := &Config{ReactionEmojis: []string{"👍"}, CursorSecret: "s", DedupSimHashDistance: tt.distance}
+		if err := cfg.validate(); (err != nil) != tt.wantErr {
+			t.Fatalf("distance %d: validate() error = %v, wantErr %v", tt.distance, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"note/internal/indexer"
+	"note/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type reindexRequest struct {
+	// Collection 为空时新建一个带时间戳的集合
+	Collection  string `json:"collection"`
+	SwitchAlias bool   `json:"switch_alias"`
+	Restart     bool   `json:"restart"`
+}
+
+// StartReindex 后台重建向量索引，立即返回目标集合名，用 ReindexStatus 查看进度
+func (h *AdminHandler) StartReindex(c *gin.Context) {
+	var req reindexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Collection == "" {
+		req.Collection = fmt.Sprintf("%s_%s", h.svc.Config.VectorCollection, time.Now().Format("20060102150405"))
+	}
+
+	opts := indexer.ReindexOptions{
+		Collection:  req.Collection,
+		SwitchAlias: req.SwitchAlias,
+		Restart:     req.Restart,
+		BatchSize:   h.svc.Config.ReindexBatchSize,
+		Rate:        h.svc.Config.ReindexRate,
+	}
+
+	go func() {
+		_, err := h.svc.Reindexer.Run(context.Background(), opts)
+		if errors.Is(err, indexer.ErrReindexRunning) {
+			zap.L().Warn("Reindex skipped, another one is running", zap.String("collection", opts.Collection))
+		} else if err != nil {
+			zap.L().Error("Reindex failed", zap.String("collection", opts.Collection), zap.Error(err))
+		}
+	}()
+
+	utils.Success(c, gin.H{"collection": req.Collection, "message": "重建任务已开始"})
+}
+
+func (h *AdminHandler) ReindexStatus(c *gin.Context) {
+	collection := c.Param("collection")
+
+	progress, err := h.svc.Reindexer.Progress(c, collection)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			utils.Error(c, http.StatusNotFound, "没有该集合的重建记录")
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "查询进度失败")
+		}
+		return
+	}
+
+	utils.Success(c, progress)
+}
+
+func (h *AdminHandler) ReindexFailures(c *gin.Context) {
+	failed, err := h.svc.Reindexer.FailedNotes(c, c.Param("collection"))
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "查询失败记录失败")
+		return
+	}
+
+	type failure struct {
+		NoteID uint   `json:"note_id"`
+		Error  string `json:"error"`
+	}
+	result := make([]failure, 0, len(failed))
+	for id, reason := range failed {
+		result = append(result, failure{NoteID: id, Error: reason})
+	}
+
+	utils.Success(c, result)
+}
+
+// Reconcile 立即执行一次对账，dry_run=true 时只报告 MySQL 和向量索引之间的差异
+func (h *AdminHandler) Reconcile(c *gin.Context) {
+	report, err := h.svc.Indexer.Reconcile(c, indexer.ReconcileOptions{
+		MaxRepair: h.svc.Config.ReconcileMaxRepair,
+		DryRun:    c.Query("dry_run") == "true",
+	})
+	if err != nil {
+		zap.L().Error("Reconcile failed", zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "对账失败")
+		return
+	}
+
+	utils.Success(c, report)
+}
@@ -0,0 +1,13 @@
+package admin
+
+import (
+	"note/internal/svc"
+)
+
+type AdminHandler struct {
+	svc *svc.ServiceContext
+}
+
+func NewAdminHandler(svc *svc.ServiceContext) *AdminHandler {
+	return &AdminHandler{svc: svc}
+}
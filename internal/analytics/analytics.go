@@ -0,0 +1,194 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"note/config"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// bufferKey 计数缓冲区 (HASH，field 为 笔记ID:日期:类型，value 为增量)
+	bufferKey = "analytics:buffer"
+	// flushingKey 正在写入数据库的缓冲区，写入失败时保留到下次重试
+	flushingKey = "analytics:buffer:flushing"
+
+	// DayLayout NoteDailyStat.Day 的格式
+	DayLayout = "2006-01-02"
+
+	flushBatch = 500
+)
+
+// 计数类型
+const (
+	KindViews     = "views"
+	KindFavorites = "favorites"
+	KindReactions = "reactions"
+)
+
+// Service 记录笔记的浏览、收藏、表情回应计数。
+// 计数先在 Redis 中按 (笔记, 日期, 类型) 累加，定时批量写入 note_daily_stats 和 notes.view_count，避免每次浏览都写数据库
+type Service struct {
+	db    *gorm.DB
+	cache *cache.RedisCache
+	cfg   *config.Config
+}
+
+func New(db *gorm.DB, cache *cache.RedisCache, cfg *config.Config) *Service {
+	return &Service{db: db, cache: cache, cfg: cfg}
+}
+
+// RecordView 记录一次浏览：作者自己的浏览不计，同一用户在 AnalyticsViewWindow 内重复浏览同一篇只计一次
+func (s *Service) RecordView(ctx context.Context, viewerID, authorID, noteID uint) error {
+	if viewerID == authorID {
+		return nil
+	}
+	if s.cache != nil {
+		key := fmt.Sprintf("analytics:viewed:%d:%d", noteID, viewerID)
+		first, err := s.cache.SetNX(ctx, key, "1", s.cfg.AnalyticsViewWindow)
+		if err != nil || !first {
+			return err
+		}
+	}
+	return s.Add(ctx, noteID, KindViews, 1)
+}
+
+// Add 记录当天的计数变化；没有 Redis 时直接写数据库
+func (s *Service) Add(ctx context.Context, noteID uint, kind string, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	field := fmt.Sprintf("%d:%s:%s", noteID, time.Now().Format(DayLayout), kind)
+	if s.cache == nil {
+		return s.apply(ctx, map[string]string{field: strconv.FormatInt(delta, 10)})
+	}
+	return s.cache.HIncrBy(ctx, bufferKey, field, delta)
+}
+
+// Flush 把缓冲区写入数据库。先把缓冲区改名再读取，写入期间的新计数进入新的缓冲区；
+// 写入在一个事务里完成，失败时改名后的缓冲区保留，下次先重试它
+func (s *Service) Flush(ctx context.Context) error {
+	if s.cache == nil {
+		return nil
+	}
+	pending, err := s.cache.Exists(ctx, flushingKey)
+	if err != nil {
+		return err
+	}
+	if pending == 0 {
+		buffered, err := s.cache.Exists(ctx, bufferKey)
+		if err != nil || buffered == 0 {
+			return err
+		}
+		if err := s.cache.Rename(ctx, bufferKey, flushingKey); err != nil {
+			return err
+		}
+	}
+
+	counts, err := s.cache.HGetAll(ctx, flushingKey)
+	if err != nil {
+		return err
+	}
+	if err := s.apply(ctx, counts); err != nil {
+		return err
+	}
+	return s.cache.Del(ctx, flushingKey)
+}
+
+type statKey struct {
+	noteID uint
+	day    string
+}
+
+// apply 把 field -> 增量 写入数据库，已删除的笔记直接丢弃
+func (s *Service) apply(ctx context.Context, counts map[string]string) error {
+	stats := make(map[statKey]*models.NoteDailyStat)
+	views := make(map[uint]int64)
+	var noteIDs []uint
+	for field, value := range counts {
+		parts := strings.Split(field, ":")
+		delta, err := strconv.ParseInt(value, 10, 64)
+		if len(parts) != 3 || err != nil {
+			zap.L().Warn("Skip malformed analytics counter", zap.String("field", field), zap.String("value", value))
+			continue
+		}
+		id, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		key := statKey{noteID: uint(id), day: parts[1]}
+		stat, ok := stats[key]
+		if !ok {
+			stat = &models.NoteDailyStat{NoteID: key.noteID, Day: key.day}
+			stats[key] = stat
+			if _, seen := views[key.noteID]; !seen {
+				noteIDs = append(noteIDs, key.noteID)
+				views[key.noteID] = 0
+			}
+		}
+		switch parts[2] {
+		case KindViews:
+			stat.Views += delta
+			views[key.noteID] += delta
+		case KindFavorites:
+			stat.Favorites += delta
+		case KindReactions:
+			stat.Reactions += delta
+		}
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+
+	authors := make(map[uint]uint, len(noteIDs))
+	for start := 0; start < len(noteIDs); start += flushBatch {
+		end := min(start+flushBatch, len(noteIDs))
+		var notes []models.Note
+		if err := s.db.WithContext(ctx).Select("id, user_id").Where("id IN ?", noteIDs[start:end]).Find(&notes).Error; err != nil {
+			return err
+		}
+		for _, n := range notes {
+			authors[n.ID] = n.UserID
+		}
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stat := range stats {
+			author, ok := authors[stat.NoteID]
+			if !ok {
+				continue
+			}
+			stat.UserID = author
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "note_id"}, {Name: "day"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"views":     gorm.Expr("views + ?", stat.Views),
+					"favorites": gorm.Expr("favorites + ?", stat.Favorites),
+					"reactions": gorm.Expr("reactions + ?", stat.Reactions),
+				}),
+			}).Create(stat).Error
+			if err != nil {
+				return err
+			}
+		}
+		for noteID, n := range views {
+			if _, ok := authors[noteID]; !ok || n == 0 {
+				continue
+			}
+			// UpdateColumn 不会改 updated_at
+			if err := tx.Model(&models.Note{}).Where("id = ?", noteID).UpdateColumn("view_count", gorm.Expr("view_count + ?", n)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
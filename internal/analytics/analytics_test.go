@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"note/config"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestRecordAndFlush(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.NoteDailyStat{})
+	rdb, mr := testutil.Cache(t)
+	s := New(db, rdb, &config.Config{AnalyticsViewWindow: time.Hour})
+	ctx := t.Context()
+
+	const author uint = 1
+	note := models.Note{UserID: author, Title: "t", Content: "c"}
+	db.Create(&note)
+
+	tests := []struct {
+		name   string
+		viewer uint
+	}{
+		{"first view", 2},
+		{"repeat view within the window", 2},
+		{"another viewer", 3},
+		{"author's own view", author},
+	}
+	for _, tt := range tests {
+		if err := s.RecordView(ctx, tt.viewer, author, note.ID); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+	}
+	if err := s.Add(ctx, note.ID, KindFavorites, 1); err != nil {
+		t.Fatal(err)
+	}
+	// 已删除笔记的计数直接丢弃
+	if err := s.Add(ctx, 999, KindViews, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(bufferKey) || mr.Exists(flushingKey) {
+		t.Fatal("buffer not cleared after flush")
+	}
+
+	// 第二次写入累加到同一天的记录
+	if err := s.Add(ctx, note.ID, KindViews, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats []models.NoteDailyStat
+	db.Find(&stats)
+	if len(stats) != 1 {
+		t.Fatalf("expected one stat row, got %+v", stats)
+	}
+	st := stats[0]
+	if st.NoteID != note.ID || st.UserID != author || st.Day != time.Now().Format(DayLayout) || st.Views != 3 || st.Favorites != 1 {
+		t.Fatalf("stat = %+v", st)
+	}
+	var got models.Note
+	db.First(&got, note.ID)
+	if got.ViewCount != 3 {
+		t.Fatalf("view_count = %d, want 3", got.ViewCount)
+	}
+}
@@ -0,0 +1,60 @@
+package dedup
+
+// 指纹按 16 位切成 4 段。汉明距离不超过 3 的两个指纹至少有一段完全相同 (抽屉原理)，
+// 所以只需比较至少有一段相同的指纹，不用两两比较
+const (
+	Bands    = 4
+	bandBits = 64 / Bands
+)
+
+// MaxDistance 按段查找能保证不漏掉的最大汉明距离
+const MaxDistance = Bands - 1
+
+// Split 把指纹切成 Bands 段，第 i 段为第 i*16 到 i*16+15 位
+func Split(hash uint64) [Bands]uint16 {
+	var bands [Bands]uint16
+	for i := range bands {
+		bands[i] = uint16(hash >> (i * bandBits))
+	}
+	return bands
+}
+
+// Buckets 内存中按段分桶的指纹，用来在一批笔记里找相近的指纹
+type Buckets struct {
+	byBand [Bands]map[uint16][]uint
+	hashes map[uint]uint64
+}
+
+func NewBuckets() *Buckets {
+	b := &Buckets{hashes: make(map[uint]uint64)}
+	for i := range b.byBand {
+		b.byBand[i] = make(map[uint16][]uint)
+	}
+	return b
+}
+
+// Near 已加入的指纹中和 hash 距离不超过 maxDistance 的笔记 ID (maxDistance 不应超过 MaxDistance)
+func (b *Buckets) Near(hash uint64, maxDistance int) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for i, band := range Split(hash) {
+		for _, id := range b.byBand[i][band] {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if Distance(hash, b.hashes[id]) <= maxDistance {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Add 加入一篇笔记的指纹
+func (b *Buckets) Add(id uint, hash uint64) {
+	b.hashes[id] = hash
+	for i, band := range Split(hash) {
+		b.byBand[i][band] = append(b.byBand[i][band], id)
+	}
+}
@@ -0,0 +1,56 @@
+package dedup
+
+import "sort"
+
+// Clusters 用并查集把两两相似的笔记合并成簇，只返回至少两篇笔记的簇，簇内和簇之间都按 ID 升序
+type Clusters struct {
+	parent map[uint]uint
+}
+
+func NewClusters() *Clusters {
+	return &Clusters{parent: make(map[uint]uint)}
+}
+
+func (c *Clusters) find(id uint) uint {
+	if _, ok := c.parent[id]; !ok {
+		c.parent[id] = id
+	}
+	for c.parent[id] != id {
+		c.parent[id] = c.parent[c.parent[id]]
+		id = c.parent[id]
+	}
+	return id
+}
+
+// Link 标记两篇笔记互为重复
+func (c *Clusters) Link(a, b uint) {
+	ra, rb := c.find(a), c.find(b)
+	if ra == rb {
+		return
+	}
+	if ra < rb {
+		c.parent[rb] = ra
+	} else {
+		c.parent[ra] = rb
+	}
+}
+
+// Groups 所有簇
+func (c *Clusters) Groups() [][]uint {
+	byRoot := make(map[uint][]uint)
+	for id := range c.parent {
+		root := c.find(id)
+		byRoot[root] = append(byRoot[root], id)
+	}
+
+	groups := make([][]uint, 0, len(byRoot))
+	for _, ids := range byRoot {
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		groups = append(groups, ids)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
@@ -0,0 +1,66 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// shingleSize 按连续 3 个字符切分特征，中英文都适用
+const shingleSize = 3
+
+// SimHash 64 位 simhash：内容越相近，汉明距离越小。空内容返回 0，表示没有指纹
+func SimHash(content string) uint64 {
+	runes := normalize(content)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature []rune) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(string(feature)))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(runes) < shingleSize {
+		add(runes)
+	} else {
+		for i := 0; i+shingleSize <= len(runes); i++ {
+			add(runes[i : i+shingleSize])
+		}
+	}
+
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// Distance 两个指纹的汉明距离
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// normalize 忽略大小写、空白和标点，只保留文字和数字
+func normalize(content string) []rune {
+	content = strings.ToLower(content)
+	runes := make([]rune, 0, len(content))
+	for _, r := range content {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
@@ -0,0 +1,102 @@
+package dedup
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSimHash(t *testing.T) {
+	base := "Go 的并发模型基于 goroutine 和 channel，调度器把 goroutine 复用到少量系统线程上。"
+	tests := []struct {
+		name        string
+		a, b        string
+		maxDistance int
+	}{
+		{"identical", base, base, 0},
+		{"case, spaces and punctuation ignored", "Hello, World!", "hello world", 0},
+		{"small edit", base, strings.Replace(base, "少量", "少数", 1), 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := Distance(SimHash(tt.a), SimHash(tt.b)); d > tt.maxDistance {
+				t.Fatalf("distance %d, want <= %d", d, tt.maxDistance)
+			}
+		})
+	}
+
+	if SimHash("") != 0 || SimHash(" ，。!") != 0 {
+		t.Fatal("empty content should have no fingerprint")
+	}
+	if d := Distance(SimHash(base), SimHash("完全不同的一段文字，讲的是做菜的步骤和火候")); d <= 10 {
+		t.Fatalf("unrelated content too close: distance %d", d)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	got := Split(0x0123_4567_89ab_cdef)
+	want := [Bands]uint16{0xcdef, 0x89ab, 0x4567, 0x0123}
+	if got != want {
+		t.Fatalf("Split = %x, want %x", got, want)
+	}
+}
+
+// 按段分桶找到的结果必须和两两比较完全一致
+func TestBucketsNearMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	hashes := make([]uint64, 0, 400)
+	for len(hashes) < cap(hashes) {
+		h := rng.Uint64()
+		hashes = append(hashes, h)
+		// 造一些近似的指纹：随机翻转 0-4 位
+		near := h
+		for range rng.Intn(5) {
+			near ^= 1 << rng.Intn(64)
+		}
+		hashes = append(hashes, near)
+	}
+
+	for maxDistance := 0; maxDistance <= MaxDistance; maxDistance++ {
+		b := NewBuckets()
+		for i, h := range hashes {
+			var want []uint
+			for j := range i {
+				if Distance(h, hashes[j]) <= maxDistance {
+					want = append(want, uint(j))
+				}
+			}
+			got := b.Near(h, maxDistance)
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("distance %d, hash %d: Near = %v, want %v", maxDistance, i, got, want)
+			}
+			b.Add(uint(i), h)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	tests := []struct {
+		name  string
+		links [][2]uint
+		want  [][]uint
+	}{
+		{"no links", nil, [][]uint{}},
+		{"one pair", [][2]uint{{2, 1}}, [][]uint{{1, 2}}},
+		{"transitive", [][2]uint{{5, 3}, {3, 9}, {1, 2}}, [][]uint{{1, 2}, {3, 5, 9}}},
+		{"self link is not a cluster", [][2]uint{{4, 4}}, [][]uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClusters()
+			for _, l := range tt.links {
+				c.Link(l[0], l[1])
+			}
+			if got := c.Groups(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Groups = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
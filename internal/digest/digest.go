@@ -0,0 +1,252 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note/internal/infra/ai"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 每篇笔记最多取多少字给模型
+	noteExcerptRunes = 200
+	// 最多列出多少篇笔记、多少篇互动最多的公开笔记
+	maxDigestNotes    = 30
+	maxActivityNotes  = 5
+	dateLayout        = "2006-01-02"
+	digestTitlePrefix = "AI 摘要"
+
+	// 生成失败后的重试间隔从 retryBaseDelay 开始翻倍，最长 retryMaxDelay
+	retryBaseDelay = time.Hour
+	retryMaxDelay  = 24 * time.Hour
+)
+
+// Service 为开启了摘要的用户定期生成 AI 摘要
+type Service struct {
+	db    *gorm.DB
+	ai    *ai.AIService
+	cache *cache.RedisCache
+}
+
+func New(db *gorm.DB, ai *ai.AIService, cache *cache.RedisCache) *Service {
+	return &Service{db: db, ai: ai, cache: cache}
+}
+
+func period(frequency string) time.Duration {
+	if frequency == models.DigestDaily {
+		return 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// retryDelay 连续失败 failures 次之后等多久再试
+func retryDelay(failures int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < failures && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+// RunDue 为到期的用户生成摘要，每次最多处理 limit 个用户。
+// 失败的用户按退避时间推迟重试，不会一直排在最前面占满每一批
+func (s *Service) RunDue(ctx context.Context, limit int) error {
+	now := time.Now()
+	var settings []models.UserSetting
+	err := s.db.WithContext(ctx).
+		Where("(digest_frequency = ? AND (last_digest_at IS NULL OR last_digest_at <= ?)) OR (digest_frequency = ? AND (last_digest_at IS NULL OR last_digest_at <= ?))",
+			models.DigestDaily, now.Add(-period(models.DigestDaily)),
+			models.DigestWeekly, now.Add(-period(models.DigestWeekly))).
+		Where("digest_retry_at IS NULL OR digest_retry_at <= ?", now).
+		Order("last_digest_at ASC").
+		Limit(limit).
+		Find(&settings).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range settings {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := s.Generate(ctx, &settings[i], now)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ai.ErrQuotaExceeded) {
+			// 不更新 last_digest_at，额度恢复后再生成
+			zap.L().Info("Skip digest, ai quota exceeded", zap.Uint("uid", settings[i].UserID))
+		} else {
+			zap.L().Error("Generate digest failed", zap.Uint("uid", settings[i].UserID), zap.Error(err))
+		}
+		if err := s.recordFailure(ctx, &settings[i], now); err != nil {
+			zap.L().Error("Record digest failure failed", zap.Uint("uid", settings[i].UserID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// recordFailure 记录一次失败并推迟下次尝试
+func (s *Service) recordFailure(ctx context.Context, setting *models.UserSetting, now time.Time) error {
+	failures := setting.DigestFailures + 1
+	return s.db.WithContext(ctx).Model(setting).UpdateColumns(map[string]interface{}{
+		"digest_failures": failures,
+		"digest_retry_at": now.Add(retryDelay(failures)),
+	}).Error
+}
+
+// advanced 生成成功 (或没有内容) 后推进摘要时间并清除失败记录
+func advanced(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"last_digest_at":  now,
+		"digest_failures": 0,
+		"digest_retry_at": nil,
+	}
+}
+
+// Generate 生成 [上次摘要时间, now) 这段时间的摘要并保存为私密笔记。没有任何内容时只推进时间，不创建笔记
+func (s *Service) Generate(ctx context.Context, setting *models.UserSetting, now time.Time) error {
+	start := now.Add(-period(setting.DigestFrequency))
+	if setting.LastDigestAt != nil && setting.LastDigestAt.After(start) {
+		start = *setting.LastDigestAt
+	}
+
+	var sections []string
+	if wants(setting, models.DigestSectionNotes) {
+		section, err := s.notesSection(ctx, setting.UserID, start, now)
+		if err != nil {
+			return err
+		}
+		sections = append(sections, section...)
+	}
+	if wants(setting, models.DigestSectionActivity) {
+		section, err := s.activitySection(ctx, setting.UserID, start, now)
+		if err != nil {
+			return err
+		}
+		sections = append(sections, section...)
+	}
+
+	if len(sections) == 0 {
+		return s.db.WithContext(ctx).Model(setting).Updates(advanced(now)).Error
+	}
+
+	gen, err := s.ai.GenerateDigest(setting.UserID, strings.Join(sections, "\n"),
+		ai.Preferences{Language: setting.AILanguage, Style: setting.AIStyle})
+	if err != nil {
+		return err
+	}
+
+	note := models.Note{
+		UserID:    setting.UserID,
+		Title:     fmt.Sprintf("%s %s ~ %s", digestTitlePrefix, start.Format(dateLayout), now.Format(dateLayout)),
+		Content:   gen.Content,
+		IsPrivate: true,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.Digest{
+			UserID:        setting.UserID,
+			NoteID:        note.ID,
+			Frequency:     setting.DigestFrequency,
+			PeriodStart:   start,
+			PeriodEnd:     now,
+			PromptVersion: gen.PromptVersion,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(setting).Updates(advanced(now)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.cache != nil {
+		_ = s.cache.ClearCacheByPattern(ctx, s.cache, fmt.Sprintf("notes:user:%d*", setting.UserID))
+	}
+	zap.L().Info("Digest generated", zap.Uint("uid", setting.UserID), zap.Uint("note_id", note.ID))
+	return nil
+}
+
+func wants(setting *models.UserSetting, section string) bool {
+	if len(setting.DigestSections) == 0 {
+		return true
+	}
+	for _, s := range setting.DigestSections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// notesSection 这段时间新建或修改的笔记 (不包括之前生成的摘要)
+func (s *Service) notesSection(ctx context.Context, userID uint, start, end time.Time) ([]string, error) {
+	var notes []models.Note
+	err := s.db.WithContext(ctx).Select("id, title, content, summary, created_at, updated_at").
+		Where("user_id = ? AND updated_at >= ? AND updated_at < ?", userID, start, end).
+		Where("id NOT IN (?)", s.db.Model(&models.Digest{}).Select("note_id").Where("user_id = ?", userID)).
+		Order("updated_at DESC").
+		Limit(maxDigestNotes).
+		Find(&notes).Error
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+
+	lines := []string{"## 笔记"}
+	for _, n := range notes {
+		excerpt := n.Summary
+		if excerpt == "" {
+			excerpt = strings.Join(strings.Fields(n.Content), " ")
+		}
+		if utf8.RuneCountInString(excerpt) > noteExcerptRunes {
+			excerpt = string([]rune(excerpt)[:noteExcerptRunes]) + "…"
+		}
+		status := "修改"
+		if !n.CreatedAt.Before(start) {
+			status = "新建"
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] %s：%s", status, n.Title, excerpt))
+	}
+	return lines, nil
+}
+
+type activity struct {
+	NoteID    uint
+	Title     string
+	Favorites int64
+	Reactions int64
+}
+
+// activitySection 这段时间公开笔记收到的收藏和表情，按互动总数取前几篇
+func (s *Service) activitySection(ctx context.Context, userID uint, start, end time.Time) ([]string, error) {
+	var rows []activity
+	err := s.db.WithContext(ctx).Raw(`
+SELECT n.id AS note_id, n.title,
+	(SELECT COUNT(*) FROM favorites f WHERE f.note_id = n.id AND f.created_at >= ? AND f.created_at < ?) AS favorites,
+	(SELECT COUNT(*) FROM reactions r WHERE r.note_id = n.id AND r.created_at >= ? AND r.created_at < ?) AS reactions
+FROM notes n
+WHERE n.user_id = ? AND n.is_private = false
+HAVING favorites + reactions > 0
+ORDER BY favorites + reactions DESC
+LIMIT ?`, start, end, start, end, userID, maxActivityNotes).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	lines := []string{"## 互动"}
+	for _, r := range rows {
+		lines = append(lines, fmt.Sprintf("- %s：%d 次收藏，%d 个表情", r.Title, r.Favorites, r.Reactions))
+	}
+	return lines, nil
+}
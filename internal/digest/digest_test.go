@@ -0,0 +1,65 @@
+package digest
+
+import (
+	"context"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Hour},
+		{2, 2 * time.Hour},
+		{4, 8 * time.Hour},
+		{5, 16 * time.Hour},
+		{6, 24 * time.Hour},
+		{50, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.failures); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// 失败的用户推迟重试，不会挡住后面到期的用户
+func TestRunDueBacksOffFailingUsers(t *testing.T) {
+	// 不建 favorites/reactions 表，activity 部分的查询会失败
+	db := testutil.DB(t, &models.UserSetting{}, &models.Note{}, &models.Digest{})
+	s := New(db, nil, nil)
+	ctx := context.Background()
+
+	weekAgo := time.Now().Add(-8 * 24 * time.Hour)
+	db.Create(&models.UserSetting{UserID: 1, DigestFrequency: models.DigestWeekly, DigestSections: []string{models.DigestSectionActivity}})
+	db.Create(&models.UserSetting{UserID: 2, DigestFrequency: models.DigestWeekly, DigestSections: []string{models.DigestSectionNotes}, LastDigestAt: &weekAgo})
+
+	load := func(userID uint) models.UserSetting {
+		var setting models.UserSetting
+		db.First(&setting, "user_id = ?", userID)
+		return setting
+	}
+
+	if err := s.RunDue(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	failing := load(1)
+	if failing.DigestFailures != 1 || failing.DigestRetryAt == nil || time.Until(*failing.DigestRetryAt) < 50*time.Minute {
+		t.Fatalf("failure not recorded with backoff: %+v", failing)
+	}
+
+	// 同一批大小下，下一次轮到后面的用户
+	if err := s.RunDue(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok := load(2); ok.LastDigestAt == nil || ok.LastDigestAt.Before(time.Now().Add(-time.Minute)) {
+		t.Fatalf("second user was not processed: %+v", ok)
+	}
+	if again := load(1); again.DigestFailures != 1 {
+		t.Fatalf("failing user retried before its backoff: %+v", again)
+	}
+}
@@ -0,0 +1,35 @@
+package history
+
+import (
+	"context"
+	"note/internal/models"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 每次删除的行数，避免一次删除大量数据长时间锁表
+const pruneBatch = 1000
+
+// Prune 删除最后一次浏览早于 before 的阅读历史
+func Prune(ctx context.Context, db *gorm.DB, before time.Time) error {
+	var total int64
+	for {
+		result := db.WithContext(ctx).
+			Where("updated_at < ?", before).
+			Limit(pruneBatch).
+			Delete(&models.History{})
+		if result.Error != nil {
+			return result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < pruneBatch {
+			break
+		}
+	}
+	if total > 0 {
+		zap.L().Info("Reading history pruned", zap.Int64("rows", total), zap.Time("before", before))
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package history
+
+import (
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	db := testutil.DB(t, &models.History{})
+	now := time.Now()
+	ages := []time.Duration{time.Hour, 40 * 24 * time.Hour, 100 * 24 * time.Hour}
+	for i, age := range ages {
+		h := models.History{UserID: 1, NoteID: uint(i + 1)}
+		db.Create(&h)
+		// updated_at 会被自动填成当前时间，单独改回去
+		db.Model(&h).UpdateColumn("updated_at", now.Add(-age))
+	}
+
+	if err := Prune(t.Context(), db, now.Add(-30*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var noteIDs []uint
+	db.Model(&models.History{}).Order("note_id").Pluck("note_id", &noteIDs)
+	if len(noteIDs) != 1 || noteIDs[0] != 1 {
+		t.Fatalf("remaining history %v, want [1]", noteIDs)
+	}
+}
@@ -0,0 +1,97 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note/internal/infra/ai"
+	"note/internal/infra/cache"
+	"note/internal/infra/vector"
+	"note/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Indexer 负责把笔记写入向量索引，创建/更新笔记、AI 任务和重建索引都走这里
+type Indexer struct {
+	db    *gorm.DB
+	ai    *ai.AIService
+	index vector.VectorIndex
+	cache *cache.RedisCache
+}
+
+func New(db *gorm.DB, ai *ai.AIService, index vector.VectorIndex, cache *cache.RedisCache) *Indexer {
+	return &Indexer{db: db, ai: ai, index: index, cache: cache}
+}
+
+// Index 当前线上使用的向量索引
+func (i *Indexer) Index() vector.VectorIndex {
+	return i.index
+}
+
+// IndexNote 为笔记生成向量并写入线上索引
+func (i *Indexer) IndexNote(ctx context.Context, note *models.Note) error {
+	// 用量算在笔记作者头上，额度用完时跳过，等对账任务之后补建
+	if err := indexInto(ctx, i.ai, i.index, note, note.UserID); err != nil {
+		return err
+	}
+	i.clearRelated(ctx, note.ID)
+	return nil
+}
+
+// clearRelated 向量变了，这篇笔记的相关推荐缓存也随之失效
+func (i *Indexer) clearRelated(ctx context.Context, noteID uint) {
+	if i.cache == nil {
+		return
+	}
+	_ = i.cache.ClearCacheByPattern(ctx, i.cache, fmt.Sprintf("note:related:%d:*", noteID))
+}
+
+// SyncPayload 只同步过滤字段 (作者、是否私密)，内容没变时不需要重新生成向量。
+// 索引中还没有这条笔记时退回到完整写入
+func (i *Indexer) SyncPayload(ctx context.Context, note *models.Note) error {
+	err := i.index.SetPayload(ctx, note.ID, payloadOf(note))
+	if errors.Is(err, vector.ErrPointNotFound) {
+		return i.IndexNote(ctx, note)
+	}
+	return err
+}
+
+// Sync 按数据库中的最新状态同步一条笔记的向量记录：笔记已删除则删掉向量，否则同步 payload
+func (i *Indexer) Sync(ctx context.Context, noteID uint, action string) error {
+	if action == models.VectorSyncDelete {
+		i.clearRelated(ctx, noteID)
+		return i.index.Delete(ctx, noteID)
+	}
+
+	var note models.Note
+	err := i.db.WithContext(ctx).Select("id, user_id, title, content, is_private").First(&note, noteID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return i.index.Delete(ctx, noteID)
+	}
+	if err != nil {
+		return err
+	}
+	return i.SyncPayload(ctx, &note)
+}
+
+// NoteText 参与向量化的文本：拼接标题和内容，让搜索更准
+func NoteText(note *models.Note) string {
+	return fmt.Sprintf("%s\n%s", note.Title, note.Content)
+}
+
+func indexInto(ctx context.Context, aiSvc *ai.AIService, index vector.VectorIndex, note *models.Note, billTo uint) error {
+	vec, err := aiSvc.GetEmbedding(billTo, NoteText(note))
+	if err != nil {
+		return fmt.Errorf("embedding failed: %w", err)
+	}
+
+	if err := index.Upsert(ctx, note.ID, vec, payloadOf(note)); err != nil {
+		return fmt.Errorf("vector upsert failed: %w", err)
+	}
+	return nil
+}
+
+func payloadOf(note *models.Note) vector.Payload {
+	return vector.Payload{UserID: note.UserID, IsPrivate: note.IsPrivate}
+}
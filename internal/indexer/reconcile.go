@@ -0,0 +1,188 @@
+package indexer
+
+import (
+	"context"
+	"note/internal/infra/ai"
+	"note/internal/infra/vector"
+	"note/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const reconcileBatchSize = 500
+
+// ReconcileOptions 对账参数
+type ReconcileOptions struct {
+	// MaxRepair 最多为多少条缺失的笔记补建向量，避免一次对账产生大量 AI 调用
+	MaxRepair int
+	// DryRun 只报告不一致，不做修复
+	DryRun bool
+}
+
+// ReconcileReport 一次对账的结果
+type ReconcileReport struct {
+	Scanned int `json:"scanned"`
+	// Missing MySQL 中有、索引中没有的笔记
+	Missing []uint `json:"missing"`
+	// Orphaned 索引中有、MySQL 中已经删除的笔记
+	Orphaned []uint `json:"orphaned"`
+	// Drifted 索引中的作者/私密状态和 MySQL 不一致的笔记
+	Drifted  []uint `json:"drifted"`
+	Repaired int    `json:"repaired"`
+	Failed   []uint `json:"failed"`
+}
+
+// Reconcile 对比 MySQL 和向量索引，找出缺失、孤儿和 payload 不一致的记录。
+// 孤儿和 payload 修复不需要调用 AI，全部修复；缺失的笔记最多补建 MaxRepair 条。
+func (i *Indexer) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileReport, error) {
+	report := &ReconcileReport{Missing: []uint{}, Orphaned: []uint{}, Drifted: []uint{}, Failed: []uint{}}
+
+	if err := i.scanNotes(ctx, report); err != nil {
+		return report, err
+	}
+	if err := i.scanPoints(ctx, report); err != nil {
+		return report, err
+	}
+
+	if !opts.DryRun {
+		i.repair(ctx, report, opts.MaxRepair)
+	}
+
+	zap.L().Info("Vector index reconciled",
+		zap.Int("scanned", report.Scanned),
+		zap.Int("missing", len(report.Missing)),
+		zap.Int("orphaned", len(report.Orphaned)),
+		zap.Int("drifted", len(report.Drifted)),
+		zap.Int("repaired", report.Repaired),
+		zap.Int("failed", len(report.Failed)),
+		zap.Bool("dry_run", opts.DryRun))
+	return report, nil
+}
+
+// scanNotes 遍历 MySQL，找出索引中缺失或 payload 不一致的笔记
+func (i *Indexer) scanNotes(ctx context.Context, report *ReconcileReport) error {
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var notes []models.Note
+		err := i.db.WithContext(ctx).
+			Select("id, user_id, is_private").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(reconcileBatchSize).
+			Find(&notes).Error
+		if err != nil {
+			return err
+		}
+		if len(notes) == 0 {
+			return nil
+		}
+		lastID = notes[len(notes)-1].ID
+		report.Scanned += len(notes)
+
+		ids := make([]uint, len(notes))
+		for idx, n := range notes {
+			ids[idx] = n.ID
+		}
+		points, err := i.index.Retrieve(ctx, ids, false)
+		if err != nil {
+			return err
+		}
+		indexed := make(map[uint]vector.Payload, len(points))
+		for _, p := range points {
+			indexed[p.ID] = p.Payload
+		}
+
+		for idx := range notes {
+			payload, ok := indexed[notes[idx].ID]
+			if !ok {
+				report.Missing = append(report.Missing, notes[idx].ID)
+			} else if payload != payloadOf(&notes[idx]) {
+				report.Drifted = append(report.Drifted, notes[idx].ID)
+			}
+		}
+	}
+}
+
+// scanPoints 遍历向量索引，找出 MySQL 中已经不存在的笔记
+func (i *Indexer) scanPoints(ctx context.Context, report *ReconcileReport) error {
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		points, err := i.index.Scroll(ctx, lastID, reconcileBatchSize, false)
+		if err != nil {
+			return err
+		}
+		if len(points) == 0 {
+			return nil
+		}
+		lastID = points[len(points)-1].ID
+
+		ids := make([]uint, len(points))
+		for idx, p := range points {
+			ids[idx] = p.ID
+		}
+		var existing []uint
+		if err := i.db.WithContext(ctx).Model(&models.Note{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		exists := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			exists[id] = true
+		}
+		for _, id := range ids {
+			if !exists[id] {
+				report.Orphaned = append(report.Orphaned, id)
+			}
+		}
+	}
+}
+
+func (i *Indexer) repair(ctx context.Context, report *ReconcileReport, maxRepair int) {
+	if len(report.Orphaned) > 0 {
+		if err := i.index.Delete(ctx, report.Orphaned...); err != nil {
+			zap.L().Warn("Reconcile delete orphaned points failed", zap.Error(err))
+			report.Failed = append(report.Failed, report.Orphaned...)
+		} else {
+			report.Repaired += len(report.Orphaned)
+		}
+	}
+
+	for _, id := range report.Drifted {
+		if err := i.Sync(ctx, id, models.VectorSyncPayload); err != nil {
+			zap.L().Warn("Reconcile sync payload failed", zap.Uint("note_id", id), zap.Error(err))
+			report.Failed = append(report.Failed, id)
+			continue
+		}
+		report.Repaired++
+	}
+
+	indexed := 0
+	for _, id := range report.Missing {
+		if indexed >= maxRepair {
+			break
+		}
+		indexed++
+
+		var note models.Note
+		if err := i.db.WithContext(ctx).First(&note, id).Error; err != nil {
+			report.Failed = append(report.Failed, id)
+			continue
+		}
+		// 按系统用户计费：因为作者额度用完而跳过的笔记要在这里补上，不能再被作者的额度挡住，
+		// 否则同一个作者的笔记每次都占满 MaxRepair，其他人的缺失永远补不到
+		if err := indexInto(ctx, i.ai, i.index, &note, ai.SystemUserID); err != nil {
+			zap.L().Warn("Reconcile index note failed", zap.Uint("note_id", id), zap.Error(err))
+			report.Failed = append(report.Failed, id)
+			continue
+		}
+		i.clearRelated(ctx, note.ID)
+		report.Repaired++
+	}
+}
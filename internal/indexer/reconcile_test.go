@@ -0,0 +1,121 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"note/config"
+	"note/internal/infra/ai"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+	"time"
+)
+
+const testDim = 8
+
+// denyMeter 拒绝 denied 中的用户，记录实际计费的用户
+type denyMeter struct {
+	denied map[uint]bool
+	billed []uint
+}
+
+func (m *denyMeter) Allow(_ context.Context, userID uint) error {
+	if m.denied[userID] {
+		return ai.ErrQuotaExceeded
+	}
+	return nil
+}
+
+func (m *denyMeter) Record(_ context.Context, userID uint, _ string, _ ai.Usage) {
+	m.billed = append(m.billed, userID)
+}
+
+func newTestAI(t *testing.T, meter ai.UsageMeter) *ai.AIService {
+	t.Helper()
+	svc, err := ai.NewAIService(&config.Config{
+		AIChatProvider:  ai.ProviderLocal,
+		AIEmbedProvider: ai.ProviderLocal,
+		AIEmbedDim:      testDim,
+		AIChatTimeout:   time.Second,
+		AIEmbedTimeout:  time.Second,
+	}, nil, meter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func newTestIndex(t *testing.T, name string) *vector.LocalIndex {
+	t.Helper()
+	idx, err := vector.NewLocalIndex(t.TempDir(), name, testDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = idx.Close() })
+	return idx
+}
+
+func TestReconcile(t *testing.T) {
+	db := testutil.DB(t, &models.Note{})
+	meter := &denyMeter{denied: map[uint]bool{1: true}}
+	aiSvc := newTestAI(t, meter)
+	index := newTestIndex(t, "notes")
+	i := New(db, aiSvc, index, nil)
+	ctx := t.Context()
+
+	// 作者 1 的额度用完，创建时没能建索引
+	missing := models.Note{UserID: 1, Title: "missing", Content: "额度用完时跳过的笔记"}
+	drifted := models.Note{UserID: 2, Title: "drifted", Content: "改成私密的笔记", IsPrivate: true}
+	db.Create(&missing)
+	db.Create(&drifted)
+	if err := i.IndexNote(ctx, &missing); !errors.Is(err, ai.ErrQuotaExceeded) {
+		t.Fatalf("IndexNote for an exhausted author: %v", err)
+	}
+	vec := make([]float32, testDim)
+	vec[0] = 1
+	_ = index.Upsert(ctx, drifted.ID, vec, vector.Payload{UserID: 2})
+	_ = index.Upsert(ctx, 999, vec, vector.Payload{UserID: 2})
+
+	report, err := i.Reconcile(ctx, ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 1 || len(report.Drifted) != 1 || len(report.Orphaned) != 1 || report.Repaired != 0 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+
+	report, err = i.Reconcile(ctx, ReconcileOptions{MaxRepair: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Repaired != 3 || len(report.Failed) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	for _, uid := range meter.billed {
+		if uid == 1 {
+			t.Fatal("repair billed the exhausted author")
+		}
+	}
+
+	points, _ := index.Retrieve(ctx, []uint{missing.ID, drifted.ID, 999}, false)
+	got := make(map[uint]vector.Payload)
+	for _, p := range points {
+		got[p.ID] = p.Payload
+	}
+	if _, ok := got[missing.ID]; !ok {
+		t.Fatal("missing note not indexed")
+	}
+	if !got[drifted.ID].IsPrivate {
+		t.Fatal("drifted payload not synced")
+	}
+	if _, ok := got[999]; ok {
+		t.Fatal("orphaned point not deleted")
+	}
+
+	// 修复之后再对账没有不一致
+	report, _ = i.Reconcile(ctx, ReconcileOptions{MaxRepair: 10})
+	if len(report.Missing)+len(report.Drifted)+len(report.Orphaned) != 0 {
+		t.Fatalf("still inconsistent: %+v", report)
+	}
+}
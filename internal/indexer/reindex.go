@@ -0,0 +1,353 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"note/internal/infra/ai"
+	"note/internal/infra/cache"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+
+	reindexLockKey = "reindex:lock"
+	reindexLockTTL = 6 * time.Hour
+
+	catchUpBatch = 100
+	// catchUpSlack 追赶时多往前看一点，避免 updated_at 和本机时钟的误差漏掉写入
+	catchUpSlack = time.Minute
+)
+
+// ErrReindexRunning 已经有一个重建任务在跑
+var ErrReindexRunning = errors.New("reindex already running")
+
+// ReindexOptions 重建参数
+type ReindexOptions struct {
+	// Collection 写入的集合，等于别名时表示原地重建
+	Collection string
+	// SwitchAlias 完成后把别名切到 Collection
+	SwitchAlias bool
+	// Restart 忽略已有进度从头开始
+	Restart bool
+	// BatchSize 每批从 MySQL 读取的笔记数
+	BatchSize int
+	// Rate 每秒最多生成多少个向量
+	Rate int
+}
+
+// Progress 重建进度，保存在 Redis 中，中断后可以从 LastID 继续
+type Progress struct {
+	Collection string     `json:"collection"`
+	Model      string     `json:"model"`
+	Status     string     `json:"status"`
+	LastID     uint       `json:"last_id"`
+	Total      int64      `json:"total"`
+	Indexed    int64      `json:"indexed"`
+	Failed     int64      `json:"failed"`
+	Switched   bool       `json:"switched"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Reindexer 从 MySQL 全量重建向量索引
+type Reindexer struct {
+	db    *gorm.DB
+	ai    *ai.AIService
+	cache *cache.RedisCache
+	admin vector.Admin
+	alias string
+	live  vector.VectorIndex
+}
+
+// NewReindexer alias 为线上读写使用的集合名；live 为进程内正在使用的索引，可以为 nil
+func NewReindexer(db *gorm.DB, ai *ai.AIService, cache *cache.RedisCache, admin vector.Admin, alias string, live vector.VectorIndex) *Reindexer {
+	return &Reindexer{db: db, ai: ai, cache: cache, admin: admin, alias: alias, live: live}
+}
+
+func progressKey(collection string) string {
+	return "reindex:progress:" + collection
+}
+
+func failedKey(collection string) string {
+	return "reindex:failed:" + collection
+}
+
+// Run 执行重建，阻塞直到完成、出错或 ctx 被取消。
+// 写入新集合时，线上的写入在重建期间只进旧集合，完成后按 updated_at 追赶一次，切换别名后再追赶一次
+func (r *Reindexer) Run(ctx context.Context, opts ReindexOptions) (*Progress, error) {
+	if opts.Collection == "" {
+		return nil, errors.New("collection is required")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.Rate <= 0 {
+		opts.Rate = 5
+	}
+
+	locked, err := r.cache.SetNX(ctx, reindexLockKey, opts.Collection, reindexLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("acquire reindex lock failed: %w", err)
+	}
+	if !locked {
+		return nil, ErrReindexRunning
+	}
+	defer func() { _ = r.cache.Del(context.Background(), reindexLockKey) }()
+
+	progress, err := r.startProgress(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	target, closeTarget, err := r.openTarget(ctx, opts.Collection)
+	if err != nil {
+		return r.finish(progress, err)
+	}
+	defer closeTarget()
+
+	if err := r.db.WithContext(ctx).Model(&models.Note{}).Count(&progress.Total).Error; err != nil {
+		return r.finish(progress, err)
+	}
+
+	// 简单的限速：每生成一个向量至少间隔 1/Rate 秒
+	ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+	defer ticker.Stop()
+
+	for {
+		var notes []models.Note
+		err := r.db.WithContext(ctx).
+			Select("id, user_id, title, content, is_private").
+			Where("id > ?", progress.LastID).
+			Order("id ASC").
+			Limit(opts.BatchSize).
+			Find(&notes).Error
+		if err != nil {
+			return r.finish(progress, err)
+		}
+		if len(notes) == 0 {
+			break
+		}
+
+		for idx := range notes {
+			select {
+			case <-ctx.Done():
+				return r.finish(progress, ctx.Err())
+			case <-ticker.C:
+			}
+
+			note := &notes[idx]
+			if err := indexInto(ctx, r.ai, target, note, ai.SystemUserID); err != nil {
+				progress.Failed++
+				_ = r.cache.HSet(ctx, failedKey(opts.Collection), strconv.Itoa(int(note.ID)), err.Error())
+				zap.L().Warn("Reindex note failed", zap.Uint("note_id", note.ID), zap.Error(err))
+				continue
+			}
+			progress.Indexed++
+		}
+
+		progress.LastID = notes[len(notes)-1].ID
+		r.saveProgress(ctx, progress)
+		zap.L().Info("Reindex batch done",
+			zap.String("collection", opts.Collection),
+			zap.Uint("last_id", progress.LastID),
+			zap.Int64("indexed", progress.Indexed),
+			zap.Int64("failed", progress.Failed),
+			zap.Int64("total", progress.Total))
+	}
+
+	if opts.Collection != r.alias {
+		// 重建期间线上的写入只进了别名当前指向的集合，先追上这段时间的变化
+		mark := time.Now()
+		if err := r.catchUp(ctx, target, progress, progress.StartedAt); err != nil {
+			return r.finish(progress, fmt.Errorf("catch up failed: %w", err))
+		}
+		if opts.SwitchAlias {
+			// 先关闭目标集合把数据落盘：本地索引切换别名时线上实例会从文件重新加载，
+			// 不关的话两个实例各自持有一份数据，之后还会互相覆盖文件
+			closeTarget()
+			if err := r.admin.SwitchAlias(ctx, r.alias, opts.Collection); err != nil {
+				return r.finish(progress, fmt.Errorf("switch alias failed: %w", err))
+			}
+			progress.Switched = true
+			zap.L().Info("Vector alias switched", zap.String("alias", r.alias), zap.String("collection", opts.Collection))
+			// 切换后线上直接写新集合，再补上追赶和切换之间的写入；
+			// 有线上索引时通过它写，没有就重新打开新集合
+			after, closeAfter, err := r.openAfterSwitch(ctx, opts.Collection)
+			if err != nil {
+				return r.finish(progress, fmt.Errorf("open collection after switch failed: %w", err))
+			}
+			defer closeAfter()
+			if err := r.catchUp(ctx, after, progress, mark); err != nil {
+				return r.finish(progress, fmt.Errorf("catch up after switch failed: %w", err))
+			}
+		}
+	}
+
+	return r.finish(progress, nil)
+}
+
+// catchUp 把 since 之后修改过的笔记重新写入 target，并从 target 删掉已经删除的笔记
+func (r *Reindexer) catchUp(ctx context.Context, target vector.VectorIndex, progress *Progress, since time.Time) error {
+	var notes []models.Note
+	synced := 0
+	err := r.db.WithContext(ctx).
+		Select("id, user_id, title, content, is_private").
+		Where("updated_at >= ?", since.Add(-catchUpSlack)).
+		FindInBatches(&notes, catchUpBatch, func(_ *gorm.DB, _ int) error {
+			for idx := range notes {
+				note := &notes[idx]
+				if err := indexInto(ctx, r.ai, target, note, ai.SystemUserID); err != nil {
+					progress.Failed++
+					_ = r.cache.HSet(ctx, failedKey(progress.Collection), strconv.Itoa(int(note.ID)), err.Error())
+					zap.L().Warn("Reindex catch-up note failed", zap.Uint("note_id", note.ID), zap.Error(err))
+					continue
+				}
+				synced++
+			}
+			return ctx.Err()
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	report := &ReconcileReport{}
+	if err := (&Indexer{db: r.db, index: target}).scanPoints(ctx, report); err != nil {
+		return err
+	}
+	if len(report.Orphaned) > 0 {
+		if err := target.Delete(ctx, report.Orphaned...); err != nil {
+			return err
+		}
+	}
+	r.saveProgress(ctx, progress)
+	zap.L().Info("Reindex caught up",
+		zap.String("collection", progress.Collection),
+		zap.Time("since", since),
+		zap.Int("synced", synced),
+		zap.Int("deleted", len(report.Orphaned)))
+	return nil
+}
+
+// startProgress 读取已有进度实现断点续跑；向量模型变了必须从头开始
+func (r *Reindexer) startProgress(ctx context.Context, opts ReindexOptions) (*Progress, error) {
+	model := r.ai.EmbedModel()
+
+	if !opts.Restart {
+		prev, err := r.Progress(ctx, opts.Collection)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if prev != nil && prev.Status != StatusDone {
+			if prev.Model != model {
+				return nil, fmt.Errorf("collection %s was built with model %s, current model is %s; restart required",
+					opts.Collection, prev.Model, model)
+			}
+			prev.Status = StatusRunning
+			prev.Error = ""
+			prev.FinishedAt = nil
+			r.saveProgress(ctx, prev)
+			zap.L().Info("Resuming reindex", zap.String("collection", opts.Collection), zap.Uint("last_id", prev.LastID))
+			return prev, nil
+		}
+	}
+
+	_ = r.cache.Del(ctx, failedKey(opts.Collection))
+	progress := &Progress{
+		Collection: opts.Collection,
+		Model:      model,
+		Status:     StatusRunning,
+		StartedAt:  time.Now(),
+	}
+	r.saveProgress(ctx, progress)
+	return progress, nil
+}
+
+// openTarget 原地重建时直接写线上索引，否则打开 (或创建) 新集合
+func (r *Reindexer) openTarget(ctx context.Context, collection string) (vector.VectorIndex, func(), error) {
+	if collection == r.alias && r.live != nil {
+		return r.live, func() {}, nil
+	}
+
+	target, err := r.admin.Open(ctx, collection, r.ai.EmbedDimension())
+	if err != nil {
+		return nil, nil, err
+	}
+	// 切换别名前会提前关闭，关闭函数要能重复调用
+	return target, sync.OnceFunc(func() {
+		if err := target.Close(); err != nil {
+			zap.L().Warn("Close reindex target failed", zap.Error(err))
+		}
+	}), nil
+}
+
+// openAfterSwitch 别名切换之后用于追赶的索引
+func (r *Reindexer) openAfterSwitch(ctx context.Context, collection string) (vector.VectorIndex, func(), error) {
+	if r.live != nil {
+		return r.live, func() {}, nil
+	}
+	return r.openTarget(ctx, collection)
+}
+
+func (r *Reindexer) finish(progress *Progress, err error) (*Progress, error) {
+	now := time.Now()
+	progress.FinishedAt = &now
+	if err != nil {
+		progress.Status = StatusFailed
+		progress.Error = err.Error()
+	} else {
+		progress.Status = StatusDone
+	}
+	r.saveProgress(context.Background(), progress)
+	return progress, err
+}
+
+func (r *Reindexer) saveProgress(ctx context.Context, progress *Progress) {
+	data, _ := json.Marshal(progress)
+	if err := r.cache.Set(ctx, progressKey(progress.Collection), string(data), 0); err != nil {
+		zap.L().Warn("Save reindex progress failed", zap.Error(err))
+	}
+}
+
+// Progress 查询某个集合的重建进度
+func (r *Reindexer) Progress(ctx context.Context, collection string) (*Progress, error) {
+	data, err := r.cache.Get(ctx, progressKey(collection))
+	if err != nil {
+		return nil, err
+	}
+	var progress Progress
+	if err := json.Unmarshal([]byte(data), &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// FailedNotes 重建失败的笔记及原因
+func (r *Reindexer) FailedNotes(ctx context.Context, collection string) (map[uint]string, error) {
+	raw, err := r.cache.HGetAll(ctx, failedKey(collection))
+	if err != nil {
+		return nil, err
+	}
+	failed := make(map[uint]string, len(raw))
+	for idStr, reason := range raw {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		failed[uint(id)] = reason
+	}
+	return failed, nil
+}
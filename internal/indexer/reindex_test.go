@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"note/config"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+)
+
+func TestReindexSwitchLocalAlias(t *testing.T) {
+	db := testutil.DB(t, &models.Note{})
+	rdb, _ := testutil.Cache(t)
+	aiSvc := newTestAI(t, nil)
+	dir := t.TempDir()
+	ctx := t.Context()
+
+	live, err := vector.NewLocalIndex(dir, "notes", testDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := vector.NewAdmin(&config.Config{VectorBackend: vector.BackendLocal, VectorLocalDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧集合里只有一条过期的点
+	vec := make([]float32, testDim)
+	vec[0] = 1
+	_ = live.Upsert(ctx, 999, vec, vector.Payload{UserID: 1})
+	for n := range 3 {
+		db.Create(&models.Note{UserID: 1, Title: "note", Content: "重建索引的笔记 " + string(rune('a'+n))})
+	}
+
+	r := NewReindexer(db, aiSvc, rdb, admin, "notes", live)
+	progress, err := r.Run(ctx, ReindexOptions{Collection: "notes_v2", SwitchAlias: true, Rate: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Status != StatusDone || !progress.Switched || progress.Indexed != 3 {
+		t.Fatalf("progress = %+v", progress)
+	}
+	if n, _ := rdb.Exists(ctx, reindexLockKey); n != 0 {
+		t.Fatal("reindex lock not released")
+	}
+
+	// 线上实例切到新集合后能看到重建的数据，旧数据不再可见
+	if n, _ := live.Count(ctx, vector.Filter{}); n != 3 {
+		t.Fatalf("live index has %d points after switch, want 3", n)
+	}
+	if points, _ := live.Retrieve(ctx, []uint{999}, false); len(points) != 0 {
+		t.Fatal("live index still serves the old collection")
+	}
+
+	// 切换后通过线上实例写入，落盘后重新打开别名数据一致
+	db.Create(&models.Note{UserID: 1, Title: "after", Content: "切换之后的笔记"})
+	if _, err := r.Run(ctx, ReindexOptions{Collection: "notes", Rate: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := vector.NewLocalIndex(dir, "notes", testDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if n, _ := reopened.Count(ctx, vector.Filter{}); n != 4 {
+		t.Fatalf("reopened alias has %d points, want 4", n)
+	}
+}
@@ -4,128 +4,179 @@ import (
 	"context"
 	"fmt"
 	"note/config"
+	"note/internal/infra/cache"
 	"strings"
-	"time"
 	"unicode/utf8"
-
-	"github.com/sashabaranov/go-openai"
 )
 
+// AIService 业务侧使用的 AI 能力入口，对话和向量可以分别选择不同的 provider
 type AIService struct {
-	client *openai.Client
-	cfg    *config.Config
+	llm      LLM
+	embedder Embedder
+	cfg      *config.Config
+	// cache 为 nil 时不缓存向量
+	cache *cache.RedisCache
+	stats embedCacheStats
+	// meter 为 nil 时不统计用量也不限额
+	meter   UsageMeter
+	prompts *Prompts
 }
 
-func NewAIService(cfg *config.Config) *AIService {
-	// 使用配置文件中的 BaseURL 和 Key 初始化
-	aiConfig := openai.DefaultConfig(cfg.VolcEngineKey)
-	aiConfig.BaseURL = cfg.VolcEngineBaseURL
+// NewAIService meter 可以为 nil (例如后台重建索引的命令)
+func NewAIService(cfg *config.Config, cache *cache.RedisCache, meter UsageMeter) (*AIService, error) {
+	llm, err := newLLM(cfg)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := newEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	prompts, err := NewPrompts(cfg.AIPromptDir, cfg.AIPromptVersions)
+	if err != nil {
+		return nil, err
+	}
 
 	return &AIService{
-		client: openai.NewClientWithConfig(aiConfig),
-		cfg:    cfg,
+		llm:      llm,
+		embedder: embedder,
+		cfg:      cfg,
+		cache:    cache,
+		meter:    meter,
+		prompts:  prompts,
+	}, nil
+}
+
+func newLLM(cfg *config.Config) (LLM, error) {
+	switch cfg.AIChatProvider {
+	case ProviderOpenAI:
+		return newOpenAIProvider(cfg.VolcEngineKey, cfg.VolcEngineBaseURL, cfg.VolcChatModelID, cfg.VolcEmbedModelID, cfg.AIEmbedDim), nil
+	case ProviderLocal:
+		return newLocalProvider(cfg.AIEmbedDim), nil
+	default:
+		return nil, fmt.Errorf("unknown chat provider: %q", cfg.AIChatProvider)
 	}
 }
 
-// GenerateTitle 使用 Chat 模型 (读取 VOLC_CHAT_MODEL_ID)
-func (s *AIService) GenerateTitle(content string) (string, error) {
-	// 设置 30 秒超时：如果 30 秒没生成完，强制取消，报错返回
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+func newEmbedder(cfg *config.Config) (Embedder, error) {
+	switch cfg.AIEmbedProvider {
+	case ProviderOpenAI:
+		return newOpenAIProvider(cfg.VolcEngineKey, cfg.VolcEngineBaseURL, cfg.VolcChatModelID, cfg.VolcEmbedModelID, cfg.AIEmbedDim), nil
+	case ProviderLocal:
+		return newLocalProvider(cfg.AIEmbedDim), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %q", cfg.AIEmbedProvider)
+	}
+}
+
+// EmbedModel 当前向量模型标识
+func (s *AIService) EmbedModel() string {
+	return s.embedder.Model()
+}
 
-	safeContent := truncateContent(content, 2000)
-
-	resp, err := s.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-
-			Model: s.cfg.VolcChatModelID,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "你是一个笔记助手，请为以下内容生成一个15字以内的标题，不要包含引号：",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: safeContent,
-				},
-			},
-			Temperature: 0.7,
-		},
-	)
+// EmbedDimension 当前向量模型的维度
+func (s *AIService) EmbedDimension() int {
+	return s.embedder.Dimension()
+}
+
+// GenerateTitle 为笔记内容生成标题，userID 为计费的用户
+func (s *AIService) GenerateTitle(userID uint, content string, prefs Preferences) (*Generation, error) {
+	gen, err := s.generate(userID, TaskTitle, content, PromptVars{
+		Language:  prefs.Language,
+		Style:     prefs.Style,
+		MaxLength: s.cfg.AITitleMaxLength,
+	})
 	if err != nil {
-		return "", fmt.Errorf("title generation failed: %w", err)
-	}
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("api returned no choices")
+		return nil, fmt.Errorf("title generation failed: %w", err)
 	}
-
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	return gen, nil
 }
 
 // GenerateSummary 调用 AI 生成摘要
-func (s *AIService) GenerateSummary(content string) (string, error) {
-	// 设置 30 秒超时：如果 30 秒没生成完，强制取消，报错返回
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+func (s *AIService) GenerateSummary(userID uint, content string, prefs Preferences) (*Generation, error) {
+	gen, err := s.generate(userID, TaskSummary, content, PromptVars{
+		Language:  prefs.Language,
+		Style:     prefs.Style,
+		MaxLength: s.cfg.AISummaryMaxLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("summary generation failed: %w", err)
+	}
+	return gen, nil
+}
 
-	safeContent := truncateContent(content, 2000)
-
-	resp, err := s.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: s.cfg.VolcChatModelID,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "请为以下笔记生成一段50字以内的简短摘要。",
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: safeContent,
-				},
-			},
-		},
-	)
+// generate 用任务对应的提示词模板 (按用户分配版本) 生成文本
+func (s *AIService) generate(userID uint, task, content string, vars PromptVars) (*Generation, error) {
+	system, version, err := s.prompts.Render(task, userID, vars)
 	if err != nil {
-		return "", fmt.Errorf("summary generation failed: %w", err)
+		return nil, err
 	}
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("api returned no choices")
+
+	resp, err := s.chat(userID, ChatRequest{
+		Task:        task,
+		System:      system,
+		User:        truncateContent(content, s.cfg.AIMaxInputRunes),
+		Temperature: s.cfg.AIChatTemperature,
+		Candidates:  vars.Tags,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &Generation{Content: resp.Content, PromptVersion: version}, nil
+}
 
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+// chat 检查配额后调用对话模型并记录用量
+func (s *AIService) chat(userID uint, req ChatRequest) (*ChatResponse, error) {
+	// 超时没生成完，强制取消，报错返回
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.AIChatTimeout)
+	defer cancel()
+
+	if err := s.allow(ctx, userID); err != nil {
+		return nil, err
+	}
+	resp, err := s.llm.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.record(userID, req.Task, resp.Usage)
+	return resp, nil
 }
 
-// GetEmbedding 使用 Embedding 模型 (读取 VOLC_EMBED_MODEL_ID)
-func (s *AIService) GetEmbedding(text string) ([]float32, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// GetEmbedding 将文本转成向量，相同模型下相同文本直接复用缓存的结果 (命中缓存不计用量)
+func (s *AIService) GetEmbedding(userID uint, text string) ([]float32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.AIEmbedTimeout)
 	defer cancel()
 	// 预处理：去除换行符能提升向量质量
 	text = strings.ReplaceAll(text, "\n", " ")
+	text = truncateContent(normalizeEmbedText(text), s.cfg.AIMaxInputRunes)
+
+	var key string
+	if s.embedCacheEnabled() {
+		key = embedCacheKey(s.embedder.Model(), text)
+		if vec, ok := s.loadEmbedding(ctx, key); ok {
+			s.recordEmbedLookup(true)
+			return vec, nil
+		}
+		s.recordEmbedLookup(false)
+	}
 
-	safeText := truncateContent(text, 2000)
-
-	resp, err := s.client.CreateEmbeddings(
-		ctx,
-		openai.EmbeddingRequest{
-			Input: []string{safeText},
-			Model: openai.EmbeddingModel(s.cfg.VolcEmbedModelID),
-		},
-	)
-
-	if err != nil {
+	if err := s.allow(ctx, userID); err != nil {
 		return nil, err
 	}
-	if len(resp.Data) == 0 {
-		return nil, fmt.Errorf("embedding data is empty")
+	vec, usage, err := s.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
 	}
+	s.record(userID, TaskEmbed, usage)
 
-	return resp.Data[0].Embedding, nil
+	if key != "" {
+		s.storeEmbedding(ctx, key, vec)
+	}
+	return vec, nil
 }
 
 func truncateContent(content string, limit int) string {
-	if utf8.RuneCountInString(content) <= limit {
+	if limit <= 0 || utf8.RuneCountInString(content) <= limit {
 		return content
 	}
 	runes := []rune(content)
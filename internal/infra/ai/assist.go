@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 写作助手支持的操作
+const (
+	AssistRewrite   = "rewrite"   // 润色改写
+	AssistShorten   = "shorten"   // 精简
+	AssistExpand    = "expand"    // 扩写
+	AssistGrammar   = "grammar"   // 修正语法和错别字
+	AssistTone      = "tone"      // 改变语气
+	AssistTranslate = "translate" // 翻译
+	AssistContinue  = "continue"  // 续写
+)
+
+// ErrInputTooLong 需要整体改写的文本超过了 AI_MAX_INPUT_RUNES，截断会丢内容，直接拒绝
+var ErrInputTooLong = errors.New("ai input too long")
+
+// AssistRequest 一次写作助手调用
+type AssistRequest struct {
+	Action string
+	Text   string
+	// Tone 改变语气时的目标语气，例如 "正式"、"轻松"
+	Tone string
+	// TargetLanguage 翻译的目标语言 (zh/en/ja)
+	TargetLanguage string
+}
+
+// Assist 对文本执行写作助手操作，返回替换 (续写时为追加) 的文本
+func (s *AIService) Assist(userID uint, req AssistRequest, prefs Preferences) (*Generation, error) {
+	text := req.Text
+	if req.Action == AssistContinue {
+		// 续写只需要结尾部分作为上下文
+		text = tailContent(text, s.cfg.AIMaxInputRunes)
+	} else if s.cfg.AIMaxInputRunes > 0 && utf8.RuneCountInString(text) > s.cfg.AIMaxInputRunes {
+		return nil, ErrInputTooLong
+	}
+
+	vars := PromptVars{
+		Language: prefs.Language,
+		Style:    prefs.Style,
+		Action:   req.Action,
+		Tone:     req.Tone,
+	}
+	if req.Action == AssistTranslate {
+		if !SupportedLanguage(req.TargetLanguage) {
+			return nil, fmt.Errorf("unsupported target language %q", req.TargetLanguage)
+		}
+		vars.TargetLanguage = languageNames[req.TargetLanguage]
+	}
+
+	gen, err := s.generate(userID, TaskAssist, text, vars)
+	if err != nil {
+		return nil, fmt.Errorf("assist %s failed: %w", req.Action, err)
+	}
+	gen.Content = strings.TrimSpace(gen.Content)
+	return gen, nil
+}
+
+func tailContent(content string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(content) <= limit {
+		return content
+	}
+	runes := []rune(content)
+	return string(runes[len(runes)-limit:])
+}
@@ -0,0 +1,16 @@
+package ai
+
+import "fmt"
+
+// GenerateDigest 根据整理好的笔记和互动情况生成一段时间的摘要
+func (s *AIService) GenerateDigest(userID uint, material string, prefs Preferences) (*Generation, error) {
+	gen, err := s.generate(userID, TaskDigest, material, PromptVars{
+		Language:  prefs.Language,
+		Style:     prefs.Style,
+		MaxLength: s.cfg.DigestMaxLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("digest generation failed: %w", err)
+	}
+	return gen, nil
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 每处理这么多次查询打印一次命中率
+const embedStatsLogEvery = 100
+
+// embedCacheStats 进程内的向量缓存命中统计
+type embedCacheStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// EmbedCacheStats 返回进程启动以来向量缓存的命中/未命中次数
+func (s *AIService) EmbedCacheStats() (hits, misses int64) {
+	return s.stats.hits.Load(), s.stats.misses.Load()
+}
+
+// normalizeEmbedText 合并连续空白，保证只有空白差异的文本命中同一个缓存
+func normalizeEmbedText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// embedCacheKey 按 (模型, 文本哈希) 缓存，换模型后自然失效
+func embedCacheKey(model, text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return fmt.Sprintf("embed:%s:%s", model, hex.EncodeToString(sum[:]))
+}
+
+func (s *AIService) embedCacheEnabled() bool {
+	return s.cache != nil && s.cfg.AIEmbedCacheTTL > 0
+}
+
+func (s *AIService) loadEmbedding(ctx context.Context, key string) ([]float32, bool) {
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			zap.L().Warn("Read embedding cache failed", zap.Error(err))
+		}
+		return nil, false
+	}
+
+	vec, ok := decodeVector([]byte(data))
+	if !ok || len(vec) != s.embedder.Dimension() {
+		return nil, false
+	}
+	return vec, true
+}
+
+func (s *AIService) storeEmbedding(ctx context.Context, key string, vec []float32) {
+	if err := s.cache.Set(ctx, key, encodeVector(vec), s.cfg.AIEmbedCacheTTL); err != nil {
+		zap.L().Warn("Write embedding cache failed", zap.Error(err))
+	}
+}
+
+func (s *AIService) recordEmbedLookup(hit bool) {
+	if hit {
+		s.stats.hits.Add(1)
+	} else {
+		s.stats.misses.Add(1)
+	}
+
+	hits, misses := s.EmbedCacheStats()
+	if (hits+misses)%embedStatsLogEvery == 0 {
+		zap.L().Info("Embedding cache stats",
+			zap.Int64("hits", hits),
+			zap.Int64("misses", misses),
+			zap.Float64("hit_rate", float64(hits)/float64(hits+misses)))
+	}
+}
+
+// encodeVector 用小端 float32 存储，比 JSON 小得多
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) ([]float32, bool) {
+	if len(buf) == 0 || len(buf)%4 != 0 {
+		return nil, false
+	}
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vec, true
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Flashcard 一张问答卡片
+type Flashcard struct {
+	Question string `json:"q"`
+	Answer   string `json:"a"`
+}
+
+// GenerateFlashcards 把笔记内容整理成问答卡片。existing 为之前生成的卡片，
+// 会写进提示词让模型尽量原样保留仍然准确的卡片，这样它们的复习记录不会丢。第二个返回值为提示词版本
+func (s *AIService) GenerateFlashcards(userID uint, content string, existing []Flashcard, prefs Preferences) ([]Flashcard, string, error) {
+	max := s.cfg.AIFlashcardsMax
+	if max <= 0 {
+		return nil, "", nil
+	}
+
+	vars := PromptVars{
+		Language:  prefs.Language,
+		Style:     prefs.Style,
+		MaxLength: max,
+	}
+	if len(existing) > 0 {
+		data, _ := json.Marshal(existing)
+		vars.Existing = string(data)
+	}
+
+	gen, err := s.generate(userID, TaskFlashcards, content, vars)
+	if err != nil {
+		return nil, "", fmt.Errorf("flashcard generation failed: %w", err)
+	}
+
+	cards := parseFlashcards(gen.Content)
+	if len(cards) > max {
+		cards = cards[:max]
+	}
+	return cards, gen.PromptVersion, nil
+}
+
+func parseFlashcards(content string) []Flashcard {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.Trim(content, "`\n ")
+
+	var raw []Flashcard
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(raw))
+	cards := make([]Flashcard, 0, len(raw))
+	for _, card := range raw {
+		card.Question = strings.TrimSpace(card.Question)
+		card.Answer = strings.TrimSpace(card.Answer)
+		if card.Question == "" || card.Answer == "" || seen[card.Question] {
+			continue
+		}
+		seen[card.Question] = true
+		cards = append(cards, card)
+	}
+	return cards
+}
@@ -0,0 +1,230 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const localEmbedModel = "local-hash-v1"
+
+// localProvider 不依赖任何外部服务的确定性实现：
+// 向量用特征哈希 (feature hashing) 生成，标题和摘要按规则截取。
+// 同样的输入永远得到同样的输出，适合离线开发和测试。
+type localProvider struct {
+	dim int
+}
+
+func newLocalProvider(dim int) *localProvider {
+	if dim <= 0 {
+		dim = 256
+	}
+	return &localProvider{dim: dim}
+}
+
+func (p *localProvider) Chat(_ context.Context, req ChatRequest) (*ChatResponse, error) {
+	var content string
+	switch req.Task {
+	case TaskTitle:
+		content = ruleTitle(req.User, 15)
+	case TaskSummary:
+		content = ruleSummary(req.User, 50)
+	case TaskSuggestTags:
+		content = ruleTags(req.User, req.Candidates)
+	case TaskFlashcards:
+		content = ruleFlashcards(req.User, 10)
+	case TaskTopicName:
+		content = ruleTopicName(req.User)
+	case TaskDigest:
+		content = ruleSummary(req.User, 500)
+	case TaskAssist:
+		// 本地没法真正改写，原样返回，只保证接口流程可用
+		content = req.User
+	default:
+		content = ruleSummary(req.User, 200)
+	}
+	// 本地实现不花钱，但按词数估算用量，让配额在离线环境下也能生效
+	usage := Usage{
+		PromptTokens:     len(tokenize(req.System)) + len(tokenize(req.User)),
+		CompletionTokens: len(tokenize(content)),
+	}
+	return &ChatResponse{Content: content, Model: "local-rule-v1", Usage: usage}, nil
+}
+
+func (p *localProvider) Embed(_ context.Context, text string) ([]float32, Usage, error) {
+	vec := make([]float32, p.dim)
+
+	tokens := tokenize(text)
+	usage := Usage{PromptTokens: len(tokens)}
+	if len(tokens) == 0 {
+		// 空文本也给一个固定方向，避免零向量导致余弦相似度无意义
+		tokens = []string{""}
+	}
+
+	for _, tok := range tokens {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(tok))
+		sum := h.Sum64()
+
+		idx := int(sum % uint64(p.dim))
+		if sum>>63 == 0 {
+			vec[idx] += 1
+		} else {
+			vec[idx] -= 1
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		vec[0] = 1
+		return vec, usage, nil
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] = float32(float64(vec[i]) / norm)
+	}
+	return vec, usage, nil
+}
+
+func (p *localProvider) Model() string {
+	return localEmbedModel
+}
+
+func (p *localProvider) Dimension() int {
+	return p.dim
+}
+
+// tokenize 英文等按单词切分，中日韩文字按单字 + 相邻双字切分
+func tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	var prevCJK rune
+
+	flushWord := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			tokens = append(tokens, string(r))
+			if prevCJK != 0 {
+				tokens = append(tokens, string([]rune{prevCJK, r}))
+			}
+			prevCJK = r
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+			prevCJK = 0
+		default:
+			flushWord()
+			prevCJK = 0
+		}
+	}
+	flushWord()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// ruleTitle 取第一行非空文本作为标题
+func ruleTitle(content string, limit int) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.Trim(line, " \t\r#>*-`")
+		if line != "" {
+			return truncateContent(line, limit)
+		}
+	}
+	return "无标题"
+}
+
+// ruleSummary 取开头的若干字作为摘要，尽量在句末截断
+func ruleSummary(content string, limit int) string {
+	text := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(truncateContent(text, limit))
+	for i := len(runes) - 1; i >= limit/2; i-- {
+		switch runes[i] {
+		case '。', '！', '？', '.', '!', '?':
+			return string(runes[:i+1])
+		}
+	}
+	return string(runes) + "…"
+}
+
+// ruleTags 选出在内容中出现过的候选标签，按 JSON 数组返回
+func ruleTags(content string, candidates []string) string {
+	lower := strings.ToLower(content)
+	matched := make([]string, 0, len(candidates))
+	for _, name := range candidates {
+		if name != "" && strings.Contains(lower, strings.ToLower(name)) {
+			matched = append(matched, name)
+		}
+	}
+	data, _ := json.Marshal(matched)
+	return string(data)
+}
+
+// ruleFlashcards 每个较长的句子生成一张卡片：问题是句子的前半截，答案是整句
+func ruleFlashcards(content string, limit int) string {
+	sentences := strings.FieldsFunc(content, func(r rune) bool {
+		switch r {
+		case '。', '！', '？', '.', '!', '?', '\n':
+			return true
+		}
+		return false
+	})
+
+	cards := make([]Flashcard, 0, limit)
+	for _, sentence := range sentences {
+		sentence = strings.Trim(sentence, " \t\r#>*-`")
+		runes := []rune(sentence)
+		if len(tokenize(sentence)) < 4 {
+			continue
+		}
+		cards = append(cards, Flashcard{
+			Question: string(runes[:len(runes)/2]) + "……？",
+			Answer:   sentence,
+		})
+		if len(cards) >= limit {
+			break
+		}
+	}
+	data, _ := json.Marshal(cards)
+	return string(data)
+}
+
+// ruleTopicName 取标题中出现次数最多的词 (中文为双字词) 作为主题名
+func ruleTopicName(titles string) string {
+	counts := make(map[string]int)
+	best, bestCount := "", 0
+	for _, tok := range tokenize(titles) {
+		if utf8.RuneCountInString(tok) < 2 || (!isCJK([]rune(tok)[0]) && len(tok) < 3) {
+			continue
+		}
+		counts[tok]++
+		if counts[tok] > bestCount {
+			best, bestCount = tok, counts[tok]
+		}
+	}
+	if best == "" {
+		return ruleTitle(titles, topicNameMaxLength)
+	}
+	return best
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// openAIProvider 基于 OpenAI 兼容接口 (火山引擎方舟) 的实现
+type openAIProvider struct {
+	client     *openai.Client
+	chatModel  string
+	embedModel string
+	embedDim   int
+}
+
+func newOpenAIProvider(apiKey, baseURL, chatModel, embedModel string, embedDim int) *openAIProvider {
+	aiConfig := openai.DefaultConfig(apiKey)
+	aiConfig.BaseURL = baseURL
+
+	return &openAIProvider{
+		client:     openai.NewClientWithConfig(aiConfig),
+		chatModel:  chatModel,
+		embedModel: embedModel,
+		embedDim:   embedDim,
+	}
+}
+
+func (p *openAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := p.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: p.chatModel,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: req.System,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: req.User,
+				},
+			},
+			Temperature: req.Temperature,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("api returned no choices")
+	}
+
+	return &ChatResponse{
+		Content: strings.TrimSpace(resp.Choices[0].Message.Content),
+		Model:   resp.Model,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, text string) ([]float32, Usage, error) {
+	resp, err := p.client.CreateEmbeddings(
+		ctx,
+		openai.EmbeddingRequest{
+			Input: []string{text},
+			Model: openai.EmbeddingModel(p.embedModel),
+		},
+	)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, Usage{}, fmt.Errorf("embedding data is empty")
+	}
+
+	return resp.Data[0].Embedding, Usage{PromptTokens: resp.Usage.PromptTokens}, nil
+}
+
+func (p *openAIProvider) Model() string {
+	return p.embedModel
+}
+
+func (p *openAIProvider) Dimension() int {
+	return p.embedDim
+}
@@ -0,0 +1,232 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 内置的提示词模板，目录结构为 prompts/<任务>/<版本>.tmpl
+//
+//go:embed prompts
+var builtinPrompts embed.FS
+
+const defaultLanguage = "zh"
+
+// 支持的输出语言
+var languageNames = map[string]string{
+	"zh": "Chinese",
+	"en": "English",
+	"ja": "Japanese",
+}
+
+// SupportedLanguage 是否是支持的输出语言
+func SupportedLanguage(lang string) bool {
+	_, ok := languageNames[lang]
+	return ok
+}
+
+// Preferences 用户对 AI 输出的偏好
+type Preferences struct {
+	Language string // zh / en / ja，空表示中文
+	Style    string // 例如 "简洁"、"formal"，空表示不限
+}
+
+// PromptVars 模板里可以使用的变量
+type PromptVars struct {
+	Language     string
+	LanguageName string
+	Chinese      bool
+	MaxLength    int
+	Style        string
+	Tags         []string
+	AllowNew     bool
+	// Existing 之前生成过的结果 (JSON)，例如问答卡片
+	Existing string
+	// 写作助手使用
+	Action         string
+	Tone           string
+	TargetLanguage string
+}
+
+// Generation 一次生成的结果及产生它的提示词版本
+type Generation struct {
+	Content       string
+	PromptVersion string // 例如 "title@v1"
+}
+
+type weightedVersion struct {
+	version string
+	weight  int
+}
+
+// Prompts 提示词模板仓库。每个任务可以有多个版本，按配置的权重给用户分配版本 (同一用户始终落在同一版本)，
+// 用于 A/B 测试；把权重全部给旧版本即可回滚。
+type Prompts struct {
+	templates map[string]map[string]*template.Template
+	rollout   map[string][]weightedVersion
+}
+
+// NewPrompts 加载内置模板，dir 不为空时用其中的同名文件覆盖或新增版本。
+// spec 格式："title=v1:90,v2:10;summary=v2"，没有配置的任务使用最大的版本号
+func NewPrompts(dir, spec string) (*Prompts, error) {
+	p := &Prompts{
+		templates: make(map[string]map[string]*template.Template),
+		rollout:   make(map[string][]weightedVersion),
+	}
+
+	sub, _ := fs.Sub(builtinPrompts, "prompts")
+	if err := p.load(sub); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := p.load(os.DirFS(dir)); err != nil {
+			return nil, fmt.Errorf("load prompts from %s: %w", dir, err)
+		}
+	}
+
+	if err := p.parseRollout(spec); err != nil {
+		return nil, err
+	}
+	for name, versions := range p.templates {
+		if _, ok := p.rollout[name]; ok {
+			continue
+		}
+		latest := ""
+		for v := range versions {
+			if latest == "" || versionLess(latest, v) {
+				latest = v
+			}
+		}
+		p.rollout[name] = []weightedVersion{{version: latest, weight: 1}}
+	}
+	return p, nil
+}
+
+func (p *Prompts) load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		name := path.Dir(file)
+		version := strings.TrimSuffix(path.Base(file), ".tmpl")
+
+		tmpl, err := template.New(name + "@" + version).
+			Funcs(template.FuncMap{"join": strings.Join}).
+			Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("parse prompt %s: %w", file, err)
+		}
+		if p.templates[name] == nil {
+			p.templates[name] = make(map[string]*template.Template)
+		}
+		p.templates[name][version] = tmpl
+	}
+	return nil
+}
+
+func (p *Prompts) parseRollout(spec string) error {
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, versions, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid prompt rollout %q", item)
+		}
+		name = strings.TrimSpace(name)
+
+		var weighted []weightedVersion
+		for _, v := range strings.Split(versions, ",") {
+			version, weightStr, hasWeight := strings.Cut(strings.TrimSpace(v), ":")
+			weight := 1
+			if hasWeight {
+				w, err := strconv.Atoi(weightStr)
+				if err != nil || w < 0 {
+					return fmt.Errorf("invalid weight in prompt rollout %q", item)
+				}
+				weight = w
+			}
+			if _, ok := p.templates[name][version]; !ok {
+				return fmt.Errorf("prompt %s@%s not found", name, version)
+			}
+			if weight > 0 {
+				weighted = append(weighted, weightedVersion{version: version, weight: weight})
+			}
+		}
+		if len(weighted) == 0 {
+			return fmt.Errorf("prompt rollout for %s has no version with positive weight", name)
+		}
+		p.rollout[name] = weighted
+	}
+	return nil
+}
+
+// pick 按用户 ID 哈希分桶，同一用户总是得到同一个版本
+func (p *Prompts) pick(name string, userID uint) (string, error) {
+	versions := p.rollout[name]
+	if len(versions) == 0 {
+		return "", fmt.Errorf("prompt %s not found", name)
+	}
+	if len(versions) == 1 {
+		return versions[0].version, nil
+	}
+
+	total := 0
+	for _, v := range versions {
+		total += v.weight
+	}
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%s:%d", name, userID)
+	bucket := int(h.Sum32() % uint32(total))
+	for _, v := range versions {
+		if bucket < v.weight {
+			return v.version, nil
+		}
+		bucket -= v.weight
+	}
+	return versions[len(versions)-1].version, nil
+}
+
+// Render 为用户选择版本并渲染模板，返回提示词和版本标识
+func (p *Prompts) Render(name string, userID uint, vars PromptVars) (string, string, error) {
+	version, err := p.pick(name, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if vars.Language == "" || !SupportedLanguage(vars.Language) {
+		vars.Language = defaultLanguage
+	}
+	vars.LanguageName = languageNames[vars.Language]
+	vars.Chinese = vars.Language == defaultLanguage
+
+	var buf bytes.Buffer
+	if err := p.templates[name][version].Execute(&buf, vars); err != nil {
+		return "", "", fmt.Errorf("render prompt %s@%s: %w", name, version, err)
+	}
+	return strings.TrimSpace(buf.String()), name + "@" + version, nil
+}
+
+// versionLess 比较 "v2" 和 "v10" 这样的版本号，不符合格式时按字符串比较
+func versionLess(a, b string) bool {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
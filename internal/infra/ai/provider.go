@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+)
+
+// 任务类型，本地实现根据它决定用什么规则生成结果
+const (
+	TaskTitle       = "title"
+	TaskSummary     = "summary"
+	TaskSuggestTags = "suggest_tags"
+	TaskEmbed       = "embed"
+	TaskAssist      = "assist"
+	TaskFlashcards  = "flashcards"
+	TaskTopicName   = "topic_name"
+	TaskDigest      = "digest"
+)
+
+// Usage 一次调用消耗的 token 数
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// ChatRequest 一次对话补全请求
+type ChatRequest struct {
+	Task        string
+	System      string
+	User        string
+	Temperature float32
+	// Candidates 可选项 (如用户已有的标签名)，已经写进了 prompt，本地实现用它做规则匹配
+	Candidates []string
+}
+
+// ChatResponse 对话补全结果
+type ChatResponse struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+// LLM 对话模型 (标题、摘要等文本生成)
+type LLM interface {
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+}
+
+// Embedder 向量模型
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, Usage, error)
+	// Model 模型标识，用于区分不同模型产出的向量
+	Model() string
+	// Dimension 向量维度，0 表示未知
+	Dimension() int
+}
+
+const (
+	ProviderOpenAI = "openai" // OpenAI 兼容接口 (火山引擎)
+	ProviderLocal  = "local"  // 离线确定性实现，开发和测试用
+)
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 单个标签名最长字符数，与 models.Tag.Name 的长度保持一致
+const maxTagNameRunes = 64
+
+// TagSuggestion 模型给出的一个标签建议
+type TagSuggestion struct {
+	Name string
+	// IsNew 不在用户已有标签里，是模型新提出的
+	IsNew bool
+}
+
+// SuggestTags 从用户已有的标签中为笔记挑选合适的标签，配置允许时也可以提出新标签。
+// 第二个返回值为使用的提示词版本
+func (s *AIService) SuggestTags(userID uint, content string, vocabulary []string, prefs Preferences) ([]TagSuggestion, string, error) {
+	max, allowNew := s.cfg.AISuggestTagsMax, s.cfg.AISuggestNewTags
+	if max <= 0 || (len(vocabulary) == 0 && !allowNew) {
+		return nil, "", nil
+	}
+
+	gen, err := s.generate(userID, TaskSuggestTags, content, PromptVars{
+		Language:  prefs.Language,
+		Style:     prefs.Style,
+		MaxLength: max,
+		Tags:      vocabulary,
+		AllowNew:  allowNew,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("tag suggestion failed: %w", err)
+	}
+
+	known := make(map[string]string, len(vocabulary))
+	for _, name := range vocabulary {
+		known[strings.ToLower(name)] = name
+	}
+
+	seen := make(map[string]bool)
+	suggestions := make([]TagSuggestion, 0, max)
+	for _, name := range parseTagList(gen.Content) {
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if existing, ok := known[key]; ok {
+			suggestions = append(suggestions, TagSuggestion{Name: existing})
+		} else if allowNew {
+			suggestions = append(suggestions, TagSuggestion{Name: name, IsNew: true})
+		}
+		if len(suggestions) >= max {
+			break
+		}
+	}
+	return suggestions, gen.PromptVersion, nil
+}
+
+// parseTagList 优先按 JSON 数组解析，模型没按要求输出时退回按逗号/换行切分
+func parseTagList(content string) []string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.Trim(content, "`\n ")
+
+	var names []string
+	if err := json.Unmarshal([]byte(content), &names); err != nil {
+		names = strings.FieldsFunc(content, func(r rune) bool {
+			return r == ',' || r == '，' || r == '、' || r == '\n'
+		})
+	}
+
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.Trim(strings.TrimSpace(name), "\"'#[]")
+		if name == "" || utf8.RuneCountInString(name) > maxTagNameRunes {
+			continue
+		}
+		result = append(result, name)
+	}
+	return result
+}
@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// topicNameMaxLength 主题名的长度上限 (中文按字数，其他语言按词数)
+const topicNameMaxLength = 8
+
+// NameTopic 根据同一簇笔记的标题为主题命名
+func (s *AIService) NameTopic(userID uint, titles []string, prefs Preferences) (*Generation, error) {
+	gen, err := s.generate(userID, TaskTopicName, strings.Join(titles, "\n"), PromptVars{
+		Language:  prefs.Language,
+		Style:     prefs.Style,
+		MaxLength: topicNameMaxLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("topic naming failed: %w", err)
+	}
+	gen.Content = strings.Trim(strings.TrimSpace(gen.Content), "\"'“”「」《》。.")
+	return gen, nil
+}
@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrQuotaExceeded 用户的 AI 额度已用完，调用方应跳过 AI 功能而不是报错
+var ErrQuotaExceeded = errors.New("ai quota exceeded")
+
+// SystemUserID 后台任务 (重建索引、对账等) 使用的计费用户，不受配额限制
+const SystemUserID uint = 0
+
+// UsageMeter 按用户统计 AI 用量并执行配额
+type UsageMeter interface {
+	// Allow 调用前检查配额，用完时返回 ErrQuotaExceeded
+	Allow(ctx context.Context, userID uint) error
+	// Record 记录一次调用的用量
+	Record(ctx context.Context, userID uint, task string, usage Usage)
+}
+
+func (s *AIService) allow(ctx context.Context, userID uint) error {
+	if s.meter == nil || userID == SystemUserID {
+		return nil
+	}
+	return s.meter.Allow(ctx, userID)
+}
+
+func (s *AIService) record(userID uint, task string, usage Usage) {
+	if s.meter == nil {
+		return
+	}
+	// 调用本身的 ctx 可能已经超时，记录用量不能因此丢失
+	s.meter.Record(context.Background(), userID, task, usage)
+}
@@ -48,6 +48,10 @@ func (c *RedisCache) SetWithRandomTTL(ctx context.Context, key string, value int
 	return c.client.Set(ctx, key, value, actualTTL).Err()
 }
 
+func (c *RedisCache) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
 	return c.client.Get(ctx, key).Result()
 }
@@ -64,6 +68,10 @@ func (c *RedisCache) HGet(ctx context.Context, key, field string) (string, error
 	return c.client.HGet(ctx, key, field).Result()
 }
 
+func (c *RedisCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return c.client.HGetAll(ctx, key).Result()
+}
+
 func (c *RedisCache) ZAdd(ctx context.Context, key string, members ...redis.Z) (int64, error) {
 	return c.client.ZAdd(ctx, key, members...).Result()
 }
@@ -80,6 +88,14 @@ func (c *RedisCache) ZRevRange(ctx context.Context, key string, start, stop int6
 	return c.client.ZRevRange(ctx, key, start, stop).Result()
 }
 
+func (c *RedisCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return c.client.SAdd(ctx, key, members...).Err()
+}
+
+func (c *RedisCache) SPopN(ctx context.Context, key string, count int64) ([]string, error) {
+	return c.client.SPopN(ctx, key, count).Result()
+}
+
 func (c *RedisCache) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
 	return c.client.Expire(ctx, key, expiration).Result()
 }
@@ -92,6 +108,91 @@ func (c *RedisCache) LRange(ctx context.Context, key string, start, stop int64)
 	return c.client.LRange(ctx, key, start, stop).Result()
 }
 
+func (c *RedisCache) HIncrBy(ctx context.Context, key, field string, incr int64) error {
+	return c.client.HIncrBy(ctx, key, field, incr).Err()
+}
+
+func (c *RedisCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return c.client.Exists(ctx, keys...).Result()
+}
+
+func (c *RedisCache) Rename(ctx context.Context, key, newKey string) error {
+	return c.client.Rename(ctx, key, newKey).Err()
+}
+
+// TimelineMaxLength 每个用户推送时间线最多保留的条数
+const TimelineMaxLength = 500
+
+// TimelineKey 用户的推送时间线 (ZSET，member 为笔记 ID，score 为发布时间)
+func TimelineKey(userID uint) string {
+	return fmt.Sprintf("timeline:z:user:%d", userID)
+}
+
+// TimelineSentinel 时间线初始化时写入的占位 member (score 为 0)，让关注列表为空的时间线也是"已初始化"的。
+// 读取时间线时要跳过它；时间线写满后它排在最后，会被截断掉
+const TimelineSentinel = 0
+
+// timelineAddScript 只往已经初始化的时间线写入并截断到 TimelineMaxLength 条。
+// 不存在的时间线由读取时从数据库整体重建，这里写入的话会让只有一两条的时间线被当成完整的
+const timelineAddScript = `
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		return 0
+	end
+	redis.call("ZADD", KEYS[1], unpack(ARGV, 2))
+	redis.call("ZREMRANGEBYRANK", KEYS[1], 0, -tonumber(ARGV[1]) - 1)
+	return 1
+`
+
+// TimelineAdd 在 pipeline 中把条目加入用户的时间线，时间线还没初始化时跳过
+func TimelineAdd(ctx context.Context, pipe redis.Pipeliner, userID uint, entries ...redis.Z) {
+	args := make([]interface{}, 0, 1+2*len(entries))
+	args = append(args, TimelineMaxLength)
+	for _, e := range entries {
+		args = append(args, e.Score, e.Member)
+	}
+	pipe.Eval(ctx, timelineAddScript, []string{TimelineKey(userID)}, args...)
+}
+
+// SeedTimeline 用数据库中查到的最近条目初始化时间线 (entries 不超过 TimelineMaxLength 条)
+func (c *RedisCache) SeedTimeline(ctx context.Context, userID uint, entries []redis.Z) error {
+	members := append([]redis.Z{{Score: 0, Member: TimelineSentinel}}, entries...)
+	return c.client.ZAdd(ctx, TimelineKey(userID), members...).Err()
+}
+
+// DropLegacyTimelines 删除旧版本的 LIST 时间线 (timeline:user:*)，只执行一次。
+// 新的 ZSET 时间线在用户第一次读取时从数据库重建，不需要迁移旧数据
+func (c *RedisCache) DropLegacyTimelines(ctx context.Context) error {
+	const marker = "migrations:timeline_zset"
+	first, err := c.SetNX(ctx, marker, "1", 0)
+	if err != nil || !first {
+		return err
+	}
+	if err := c.ClearCacheByPattern(ctx, c, "timeline:user:*"); err != nil {
+		_ = c.Del(ctx, marker)
+		return err
+	}
+	return nil
+}
+
+func (c *RedisCache) ZRevRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) ([]redis.Z, error) {
+	return c.client.ZRevRangeByScoreWithScores(ctx, key, opt).Result()
+}
+
+func (c *RedisCache) ZCount(ctx context.Context, key, min, max string) (int64, error) {
+	return c.client.ZCount(ctx, key, min, max).Result()
+}
+
+// HistoryPausedKey 用户是否暂停了阅读历史 ("1"/"0")，修改设置时删除
+func HistoryPausedKey(userID uint) string {
+	return fmt.Sprintf("history:paused:%d", userID)
+}
+
+// ClearNoteCache 笔记内容或标签变化后清理笔记详情和作者的笔记列表缓存
+func (c *RedisCache) ClearNoteCache(ctx context.Context, noteID, userID uint) {
+	_ = c.Del(ctx, fmt.Sprintf("note:%d", noteID))
+	_ = c.ClearCacheByPattern(ctx, c, fmt.Sprintf("notes:user:%d*", userID))
+}
+
 func (c *RedisCache) ClearCacheByPattern(ctx context.Context, cache *RedisCache, pattern string) error {
 	var cursor uint64
 	var keys []string
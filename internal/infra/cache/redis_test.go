@@ -0,0 +1,82 @@
+package cache_test
+
+import (
+	"context"
+	"note/internal/infra/cache"
+	"note/internal/testutil"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTimelineAddOnlySeeded(t *testing.T) {
+	rdb, mr := testutil.Cache(t)
+	ctx := context.Background()
+
+	push := func(userID uint, entries ...redis.Z) {
+		pipe := rdb.Pipeline()
+		cache.TimelineAdd(ctx, pipe, userID, entries...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	}
+
+	// 未初始化的时间线不写入，读取时会从数据库重建
+	push(1, redis.Z{Score: 100, Member: 42})
+	if mr.Exists(cache.TimelineKey(1)) {
+		t.Fatal("push created an unseeded timeline")
+	}
+
+	if err := rdb.SeedTimeline(ctx, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	push(1, redis.Z{Score: 100, Member: 42})
+	members, _ := mr.ZMembers(cache.TimelineKey(1))
+	if len(members) != 2 {
+		t.Fatalf("expected sentinel + pushed note, got %v", members)
+	}
+
+	// 写满后截断到 TimelineMaxLength 条，占位 member 最先被截掉
+	entries := make([]redis.Z, cache.TimelineMaxLength)
+	for i := range entries {
+		entries[i] = redis.Z{Score: float64(1000 + i), Member: 1000 + i}
+	}
+	push(1, entries...)
+	members, _ = mr.ZMembers(cache.TimelineKey(1))
+	if len(members) != cache.TimelineMaxLength {
+		t.Fatalf("expected %d entries after trim, got %d", cache.TimelineMaxLength, len(members))
+	}
+	for _, m := range members {
+		if m == "0" || m == "42" {
+			t.Fatalf("oldest entry %s should have been trimmed", m)
+		}
+	}
+}
+
+func TestDropLegacyTimelines(t *testing.T) {
+	rdb, mr := testutil.Cache(t)
+	ctx := context.Background()
+
+	_, _ = mr.Lpush("timeline:user:1", "10")
+	_, _ = mr.Lpush("timeline:user:2", "11")
+	_, _ = mr.ZAdd(cache.TimelineKey(1), 10, "10")
+
+	if err := rdb.DropLegacyTimelines(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("timeline:user:1") || mr.Exists("timeline:user:2") {
+		t.Fatal("legacy list timelines were not dropped")
+	}
+	if !mr.Exists(cache.TimelineKey(1)) {
+		t.Fatal("zset timeline must be kept")
+	}
+
+	// 只执行一次
+	_, _ = mr.Lpush("timeline:user:3", "12")
+	if err := rdb.DropLegacyTimelines(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("timeline:user:3") {
+		t.Fatal("migration ran twice")
+	}
+}
@@ -26,6 +26,8 @@ func InitMySQL(cfg *config.Config) *gorm.DB {
 
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
 		Logger: gormLogger,
+		// 把唯一索引冲突等驱动错误转换为 gorm.ErrDuplicatedKey，方便用 errors.Is 判断
+		TranslateError: true,
 	})
 	if err != nil {
 		panic("failed to connect database: " + err.Error())
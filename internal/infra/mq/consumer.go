@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"note/config"
+	"note/internal/analytics"
+	"note/internal/indexer"
 	"note/internal/infra/ai"
 	"note/internal/infra/cache"
-	"note/internal/infra/vector"
 	"note/internal/models"
+	"note/internal/ranking"
+	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -21,17 +25,23 @@ type Consumer struct {
 	cache  *cache.RedisCache
 	rabbit *RabbitMQ
 	ai     *ai.AIService
-	qdrant *vector.QdrantService
+	index  *indexer.Indexer
+	rank   *ranking.Service
+	stats  *analytics.Service
+	cfg    *config.Config
 }
 
 // NewConsumer 初始化消费者管理器
-func NewConsumer(db *gorm.DB, cache *cache.RedisCache, rabbit *RabbitMQ, ai *ai.AIService, qdrant *vector.QdrantService) *Consumer {
+func NewConsumer(db *gorm.DB, cache *cache.RedisCache, rabbit *RabbitMQ, ai *ai.AIService, index *indexer.Indexer, rank *ranking.Service, stats *analytics.Service, cfg *config.Config) *Consumer {
 	return &Consumer{
 		db:     db,
 		cache:  cache,
 		rabbit: rabbit,
 		ai:     ai,
-		qdrant: qdrant,
+		index:  index,
+		rank:   rank,
+		stats:  stats,
+		cfg:    cfg,
 	}
 }
 
@@ -42,6 +52,7 @@ func (c *Consumer) Start() {
 	go c.ConsumeHistory()
 	go c.consumeFeedPush()
 	go c.consumeAITasks()
+	go c.consumeVectorSync()
 }
 
 func (c *Consumer) consumeFavorite() {
@@ -60,6 +71,8 @@ func (c *Consumer) consumeFavorite() {
 			continue
 		}
 
+		// 收藏数的实际变化，重复收藏或取消不存在的收藏为 0
+		var delta int64
 		err := c.db.Transaction(func(tx *gorm.DB) error {
 			if msg.Action == "add" {
 				fav := models.Favorite{UserID: msg.UserID, NoteID: msg.NoteID}
@@ -72,6 +85,7 @@ func (c *Consumer) consumeFavorite() {
 					return err // 其他错误抛出
 				}
 
+				delta = 1
 				return tx.Model(&models.Note{}).Where("id = ?", msg.NoteID).
 					Update("favorite_count", gorm.Expr("favorite_count + 1")).Error
 
@@ -84,6 +98,7 @@ func (c *Consumer) consumeFavorite() {
 				}
 
 				if result.RowsAffected > 0 {
+					delta = -1
 					return tx.Model(&models.Note{}).Where("id = ?", msg.NoteID).
 						Update("favorite_count", gorm.Expr("GREATEST(favorite_count - 1, 0)")).Error
 				}
@@ -104,6 +119,8 @@ func (c *Consumer) consumeFavorite() {
 				zap.Uint("uid", msg.UserID),
 				zap.Uint("nid", msg.NoteID),
 			)
+			c.refreshRank(msg.NoteID)
+			c.addStat(msg.NoteID, analytics.KindFavorites, delta)
 		}
 	}
 }
@@ -130,6 +147,8 @@ func (c *Consumer) consumeReaction() {
 }
 
 func (c *Consumer) handleToggleReaction(msg models.ReactionMsg) {
+	// 定义变更量：1 (新增) 或 -1 (取消)
+	var delta int
 	err := c.db.Transaction(func(tx *gorm.DB) error {
 
 		result := tx.Where("user_id = ? AND note_id = ? AND emoji = ?", msg.UserID, msg.NoteID, msg.Emoji).
@@ -139,8 +158,6 @@ func (c *Consumer) handleToggleReaction(msg models.ReactionMsg) {
 			return result.Error
 		}
 
-		// 定义变更量：1 (新增) 或 -1 (取消)
-		delta := 0
 		if result.RowsAffected > 0 {
 			delta = -1 // 之前有点赞，现在取消
 			zap.L().Info("Reaction removed", zap.Uint("uid", msg.UserID), zap.String("emoji", msg.Emoji))
@@ -187,7 +204,9 @@ func (c *Consumer) handleToggleReaction(msg models.ReactionMsg) {
 			note.ReactionCounts[msg.Emoji] = newCount
 		}
 
-		if err := tx.Model(&note).Update("reaction_counts", note.ReactionCounts).Error; err != nil {
+		// 单列更新不会经过字段的 serializer，需要自己编码
+		counts, _ := json.Marshal(note.ReactionCounts)
+		if err := tx.Model(&note).Update("reaction_counts", string(counts)).Error; err != nil {
 			return err
 		}
 
@@ -196,6 +215,23 @@ func (c *Consumer) handleToggleReaction(msg models.ReactionMsg) {
 
 	if err != nil {
 		zap.L().Error("Handle reaction failed", zap.Error(err))
+		return
+	}
+	c.refreshRank(msg.NoteID)
+	c.addStat(msg.NoteID, analytics.KindReactions, int64(delta))
+}
+
+// refreshRank 收藏、表情回应变化后更新笔记的互动分和热度
+func (c *Consumer) refreshRank(noteID uint) {
+	if err := c.rank.Refresh(context.Background(), noteID); err != nil {
+		zap.L().Warn("Refresh note rank failed", zap.Uint("nid", noteID), zap.Error(err))
+	}
+}
+
+// addStat 记录笔记当天的收藏、表情回应变化
+func (c *Consumer) addStat(noteID uint, kind string, delta int64) {
+	if err := c.stats.Add(context.Background(), noteID, kind, delta); err != nil {
+		zap.L().Warn("Record note stat failed", zap.Uint("nid", noteID), zap.String("kind", kind), zap.Error(err))
 	}
 }
 
@@ -237,8 +273,6 @@ func (c *Consumer) consumeFeedPush() {
 		return
 	}
 
-	const MaxFeedLength = 500
-
 	for d := range msgs {
 		var msg models.FeedMsg
 		if err := json.Unmarshal(d.Body, &msg); err != nil {
@@ -246,30 +280,21 @@ func (c *Consumer) consumeFeedPush() {
 			continue
 		}
 
-		var fanIDs []uint
-		c.db.Model(&models.UserFollow{}).
-			Where("followed_id = ?", msg.AuthorID).
-			Pluck("follower_id", &fanIDs)
-
-		if len(fanIDs) == 0 {
-			continue
-		}
-
-		ctx := context.Background()
-		pipe := c.cache.Pipeline()
-
-		for _, fanID := range fanIDs {
-			key := fmt.Sprintf("timeline:user:%d", fanID)
-
-			pipe.LPush(ctx, key, msg.NoteID)
-			pipe.LTrim(ctx, key, 0, MaxFeedLength-1)
-		}
-
-		_, err := pipe.Exec(ctx)
-		if err != nil {
-			zap.L().Error("Feed push pipeline failed", zap.Error(err))
-		} else {
-			zap.L().Info("Feed pushed to fans", zap.Uint("author_id", msg.AuthorID), zap.Int("fan_count", len(fanIDs)))
+		switch msg.Event {
+		case "", models.FeedPost:
+			c.pushFeed(msg)
+			c.refreshRank(msg.NoteID)
+		case models.FeedRemove:
+			c.removeFromFans(msg)
+			if err := c.rank.Remove(context.Background(), msg.NoteID); err != nil {
+				zap.L().Warn("Remove note from hot ranking failed", zap.Uint("nid", msg.NoteID), zap.Error(err))
+			}
+		case models.FeedFollow:
+			c.backfillTimeline(msg)
+		case models.FeedUnfollow:
+			c.removeAuthorFromTimeline(msg)
+		default:
+			zap.L().Warn("Unknown feed event", zap.String("event", msg.Event))
 		}
 	}
 }
@@ -293,58 +318,187 @@ func (c *Consumer) consumeAITasks() {
 		sem <- struct{}{}
 		go func(taskMsg models.AITaskMsg) {
 			defer func() { <-sem }()
-			zap.L().Info("Processing AI task", zap.Uint("note_id", msg.NoteID), zap.String("task", msg.Task))
+			zap.L().Info("Processing AI task", zap.Uint("note_id", taskMsg.NoteID), zap.String("task", taskMsg.Task))
 
-			var note models.Note
-			if err := c.db.First(&note, msg.NoteID).Error; err != nil {
-				zap.L().Error("Note not found", zap.Uint("id", msg.NoteID))
-				return
+			c.startAITask(taskMsg.TaskID)
+			promptVersion, err := c.runAITask(taskMsg)
+			c.finishAITask(taskMsg.TaskID, promptVersion, err)
+			if err != nil {
+				zap.L().Warn("AI task failed", zap.Uint("note_id", taskMsg.NoteID), zap.String("task", taskMsg.Task), zap.Error(err))
 			}
+		}(msg)
+	}
+}
 
-			var updateMap = make(map[string]interface{})
-			titleChanged := false
-
-			if msg.Task == "generate_title" {
-				newTitle, err := c.ai.GenerateTitle(note.Content)
-				if err == nil && newTitle != "" {
-					updateMap["title"] = newTitle
-					note.Title = newTitle
-					titleChanged = true
-				} else {
-					zap.L().Warn("AI generate title failed or empty", zap.Error(err))
-				}
-			} else if msg.Task == "generate_summary" {
-				summary, err := c.ai.GenerateSummary(note.Content)
-				if err == nil && summary != "" {
-					updateMap["summary"] = summary
-				} else {
-					zap.L().Warn("AI generate summary failed", zap.Error(err))
-				}
-			}
+// runAITask 执行任务，返回使用的提示词版本
+func (c *Consumer) runAITask(msg models.AITaskMsg) (string, error) {
+	var note models.Note
+	if err := c.db.First(&note, msg.NoteID).Error; err != nil {
+		return "", fmt.Errorf("note %d not found: %w", msg.NoteID, err)
+	}
 
-			if len(updateMap) > 0 {
-				if err := c.db.Model(&note).Updates(updateMap).Error; err != nil {
-					zap.L().Error("Failed to update note with AI result", zap.Error(err))
-					return
-				}
+	setting := c.userSetting(note.UserID)
+	prefs := ai.Preferences{Language: setting.AILanguage, Style: setting.AIStyle}
 
-				zap.L().Info("AI Update success", zap.Uint("nid", note.ID))
+	var updateMap = make(map[string]interface{})
+	titleChanged := false
+	var gen *ai.Generation
+	var err error
 
-				ctx := context.Background()
-				_ = c.cache.Del(ctx, fmt.Sprintf("note:%d", note.ID))
-				_ = c.cache.ClearCacheByPattern(ctx, c.cache, fmt.Sprintf("notes:user:%d*", note.UserID))
+	switch msg.Task {
+	case models.AITaskGenerateTitle:
+		gen, err = c.ai.GenerateTitle(note.UserID, note.Content, prefs)
+		if err != nil {
+			return "", err
+		}
+		if gen.Content == "" {
+			return gen.PromptVersion, errors.New("AI returned an empty title")
+		}
+		// 标题没变就不用更新，也省一次向量生成
+		if gen.Content != note.Title {
+			updateMap["title"] = gen.Content
+			note.Title = gen.Content
+			titleChanged = true
+		}
+	case models.AITaskGenerateSummary:
+		gen, err = c.ai.GenerateSummary(note.UserID, note.Content, prefs)
+		if err != nil {
+			return "", err
+		}
+		if gen.Content == "" {
+			return gen.PromptVersion, errors.New("AI returned an empty summary")
+		}
+		updateMap["summary"] = gen.Content
+	case models.AITaskSuggestTags:
+		return c.suggestTags(&note, setting)
+	case models.AITaskFlashcards:
+		return c.generateFlashcards(&note, setting)
+	default:
+		return "", fmt.Errorf("unknown AI task %q", msg.Task)
+	}
 
-				if titleChanged {
-					textToEmbed := fmt.Sprintf("%s\n%s", note.Title, note.Content)
-					vec, err := c.ai.GetEmbedding(textToEmbed)
-					if err == nil {
-						_ = c.qdrant.Upsert(ctx, note.ID, vec, note.UserID, note.IsPrivate)
-						zap.L().Info("Qdrant index updated for AI title", zap.Uint("nid", note.ID))
-					} else {
-						zap.L().Error("Failed to update embedding for AI title", zap.Error(err))
-					}
-				}
-			}
-		}(msg)
+	if len(updateMap) == 0 {
+		return gen.PromptVersion, nil
+	}
+	if err := c.db.Model(&note).Updates(updateMap).Error; err != nil {
+		return gen.PromptVersion, fmt.Errorf("update note with AI result failed: %w", err)
+	}
+
+	zap.L().Info("AI Update success", zap.Uint("nid", note.ID))
+
+	ctx := context.Background()
+	c.cache.ClearNoteCache(ctx, note.ID, note.UserID)
+
+	if titleChanged {
+		// 向量更新失败不影响任务结果，定期对账会补上
+		if err := c.index.IndexNote(ctx, &note); err == nil {
+			zap.L().Info("Vector index updated for AI title", zap.Uint("nid", note.ID))
+		} else {
+			zap.L().Error("Failed to update embedding for AI title", zap.Error(err))
+		}
+	}
+	return gen.PromptVersion, nil
+}
+
+// userSetting 读取用户设置，没有记录或出错时返回默认值
+func (c *Consumer) userSetting(userID uint) models.UserSetting {
+	setting := models.UserSetting{UserID: userID}
+	if err := c.db.Where("user_id = ?", userID).First(&setting).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		zap.L().Warn("Load user setting failed", zap.Uint("uid", userID), zap.Error(err))
+	}
+	return setting
+}
+
+// startAITask 标记任务开始执行；旧版本发出的消息没有 TaskID，不记录
+func (c *Consumer) startAITask(taskID uint) {
+	if taskID == 0 {
+		return
+	}
+	now := time.Now()
+	err := c.db.Model(&models.AITask{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+		"status":     models.AITaskRunning,
+		"attempts":   gorm.Expr("attempts + 1"),
+		"started_at": now,
+		"error":      "",
+	}).Error
+	if err != nil {
+		zap.L().Error("Mark AI task running failed", zap.Uint("task_id", taskID), zap.Error(err))
+	}
+}
+
+func (c *Consumer) finishAITask(taskID uint, promptVersion string, taskErr error) {
+	if taskID == 0 {
+		return
+	}
+	updates := map[string]interface{}{
+		"status":         models.AITaskSucceeded,
+		"prompt_version": promptVersion,
+		"finished_at":    time.Now(),
+	}
+	if taskErr != nil {
+		updates["status"] = models.AITaskFailed
+		updates["error"] = taskErr.Error()
+	}
+	if err := c.db.Model(&models.AITask{}).Where("id = ?", taskID).Updates(updates).Error; err != nil {
+		zap.L().Error("Mark AI task finished failed", zap.Uint("task_id", taskID), zap.Error(err))
+	}
+}
+
+func (c *Consumer) consumeVectorSync() {
+	msgs, err := c.rabbit.Consume("vector_queue")
+	if err != nil {
+		zap.L().Error("Failed to start vector sync consumer", zap.Error(err))
+		return
+	}
+
+	zap.L().Info("Waiting for vector sync messages...")
+	for d := range msgs {
+		var msg models.VectorSyncMsg
+		if err := json.Unmarshal(d.Body, &msg); err != nil {
+			zap.L().Error("Failed to unmarshal msg", zap.Error(err))
+			continue
+		}
+
+		if err := c.index.Sync(context.Background(), msg.NoteID, msg.Action); err != nil {
+			c.retryVectorSync(msg, err)
+			continue
+		}
+		zap.L().Debug("Vector synced", zap.Uint("note_id", msg.NoteID), zap.String("action", msg.Action))
+	}
+}
+
+// retryVectorSync 指数退避后重新投递；超过次数只记日志，剩下的交给定期对账
+func (c *Consumer) retryVectorSync(msg models.VectorSyncMsg, cause error) {
+	if msg.Attempt >= c.cfg.VectorSyncMaxRetries {
+		zap.L().Error("Vector sync gave up",
+			zap.Uint("note_id", msg.NoteID),
+			zap.String("action", msg.Action),
+			zap.Int("attempt", msg.Attempt),
+			zap.Error(cause))
+		return
+	}
+
+	msg.Attempt++
+	backoff := vectorSyncBackoff(msg.Attempt)
+	zap.L().Warn("Vector sync failed, will retry",
+		zap.Uint("note_id", msg.NoteID),
+		zap.String("action", msg.Action),
+		zap.Int("attempt", msg.Attempt),
+		zap.Duration("backoff", backoff),
+		zap.Error(cause))
+
+	// 通过 RabbitMQ 的延迟队列重新投递，等待期间进程重启也不会丢
+	body, _ := json.Marshal(msg)
+	if err := c.rabbit.PublishDelayed("vector_queue", body, backoff); err != nil {
+		zap.L().Error("Schedule vector sync retry failed", zap.Uint("note_id", msg.NoteID), zap.Error(err))
+	}
+}
+
+// vectorSyncBackoff 第 attempt 次重试前的等待时间：2 秒起每次翻倍，最多 5 分钟
+func vectorSyncBackoff(attempt int) time.Duration {
+	backoff := time.Duration(1<<attempt) * time.Second
+	if attempt > 8 || backoff > 5*time.Minute {
+		backoff = 5 * time.Minute
 	}
+	return backoff
 }
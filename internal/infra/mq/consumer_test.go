@@ -0,0 +1,25 @@
+package mq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVectorSyncBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{5, 32 * time.Second},
+		{8, 256 * time.Second},
+		{9, 5 * time.Minute},
+		{70, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := vectorSyncBackoff(tt.attempt); got != tt.want {
+			t.Fatalf("vectorSyncBackoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"note/internal/infra/cache"
+	"note/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PublishFeed 发送时间线事件 (发布、删除、关注、取消关注)。没有 MQ 时时间线功能整体不可用，直接忽略
+func (r *RabbitMQ) PublishFeed(msg models.FeedMsg) {
+	if r == nil {
+		return
+	}
+	body, _ := json.Marshal(msg)
+	if err := r.Publish("feed_queue", body); err != nil {
+		zap.L().Error("Publish feed event failed", zap.String("event", msg.Event), zap.Uint("author_id", msg.AuthorID),
+			zap.Uint("note_id", msg.NoteID), zap.Uint("follower_id", msg.FollowerID), zap.Error(err))
+	}
+}
+
+// pushFeed 把新笔记推到粉丝的时间线。粉丝数达到 FeedBigAuthorFans 的大 V 不推送，
+// 由粉丝读时间线时再拉取 (见 GetFollowingFeed)；粉丝按批读取、按批写入 Redis，避免一次性加载全部粉丝
+func (c *Consumer) pushFeed(msg models.FeedMsg) {
+	var author models.User
+	if err := c.db.Select("id, fan_count").First(&author, msg.AuthorID).Error; err != nil {
+		zap.L().Warn("Load feed author failed", zap.Uint("author_id", msg.AuthorID), zap.Error(err))
+		return
+	}
+	if threshold := c.cfg.FeedBigAuthorFans; threshold > 0 && author.FanCount >= threshold {
+		zap.L().Info("Skip feed push for big author", zap.Uint("author_id", msg.AuthorID), zap.Int("fan_count", author.FanCount))
+		return
+	}
+
+	ctx := context.Background()
+	entry := redis.Z{Score: float64(msg.PostTime), Member: msg.NoteID}
+
+	pushed, err := c.forEachFanBatch(msg.AuthorID, func(pipe redis.Pipeliner, fanIDs []uint) {
+		for _, fanID := range fanIDs {
+			// 只写入已初始化的时间线，只保留最新的 TimelineMaxLength 条
+			cache.TimelineAdd(ctx, pipe, fanID, entry)
+		}
+	})
+	if err != nil {
+		zap.L().Error("Feed push failed", zap.Uint("author_id", msg.AuthorID), zap.Int("pushed", pushed), zap.Error(err))
+		return
+	}
+	if pushed > 0 {
+		zap.L().Info("Feed pushed to fans", zap.Uint("author_id", msg.AuthorID), zap.Int("fan_count", pushed))
+	}
+}
+
+// forEachFanBatch 按批读取作者的粉丝，每批在一个 pipeline 里执行 fn 写入的命令，返回处理过的粉丝数
+func (c *Consumer) forEachFanBatch(authorID uint, fn func(pipe redis.Pipeliner, fanIDs []uint)) (int, error) {
+	batchSize := c.cfg.FeedPushBatch
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	ctx := context.Background()
+	var follows []models.UserFollow
+	processed := 0
+	err := c.db.Select("id, follower_id").
+		Where("followed_id = ?", authorID).
+		FindInBatches(&follows, batchSize, func(_ *gorm.DB, _ int) error {
+			fanIDs := make([]uint, len(follows))
+			for i, f := range follows {
+				fanIDs[i] = f.FollowerID
+			}
+			pipe := c.cache.Pipeline()
+			fn(pipe, fanIDs)
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+			processed += len(fanIDs)
+			return nil
+		}).Error
+	return processed, err
+}
+
+// removeFromFans 笔记删除或改为私密后，从所有粉丝的时间线里移除。
+// 大 V 的笔记可能是成为大 V 之前推送的，所以不论粉丝数都要处理
+func (c *Consumer) removeFromFans(msg models.FeedMsg) {
+	ctx := context.Background()
+	removed, err := c.forEachFanBatch(msg.AuthorID, func(pipe redis.Pipeliner, fanIDs []uint) {
+		for _, fanID := range fanIDs {
+			pipe.ZRem(ctx, cache.TimelineKey(fanID), msg.NoteID)
+		}
+	})
+	if err != nil {
+		zap.L().Error("Remove note from timelines failed", zap.Uint("note_id", msg.NoteID), zap.Int("processed", removed), zap.Error(err))
+		return
+	}
+	zap.L().Info("Note removed from timelines", zap.Uint("note_id", msg.NoteID), zap.Int("fan_count", removed))
+}
+
+// backfillTimeline 新关注后把作者最近的公开笔记按发布时间写入关注者已初始化的时间线。大 V 的笔记读时拉取，不需要回填
+func (c *Consumer) backfillTimeline(msg models.FeedMsg) {
+	var author models.User
+	if err := c.db.Select("id, fan_count").First(&author, msg.AuthorID).Error; err != nil {
+		return
+	}
+	if threshold := c.cfg.FeedBigAuthorFans; threshold > 0 && author.FanCount >= threshold {
+		return
+	}
+
+	var notes []models.Note
+	err := c.db.Select("id, created_at").
+		Where("user_id = ? AND is_private = ?", msg.AuthorID, false).
+		Order("created_at DESC").
+		Limit(c.cfg.FeedBackfillCount).
+		Find(&notes).Error
+	if err != nil || len(notes) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	entries := make([]redis.Z, len(notes))
+	for i, n := range notes {
+		entries[i] = redis.Z{Score: float64(n.CreatedAt.Unix()), Member: n.ID}
+	}
+	// 时间线还没初始化时不回填：只写入这一位作者的笔记会让读取时以为时间线已经完整，
+	// 不再从数据库重建，其他关注的人的历史笔记就看不到了。重建时会包含这位作者
+	pipe := c.cache.Pipeline()
+	cache.TimelineAdd(ctx, pipe, msg.FollowerID, entries...)
+	cmds, err := pipe.Exec(ctx)
+	if err != nil {
+		zap.L().Error("Backfill timeline failed", zap.Uint("uid", msg.FollowerID), zap.Error(err))
+		return
+	}
+	if added, _ := cmds[0].(*redis.Cmd).Int(); added == 0 {
+		return
+	}
+	zap.L().Info("Timeline backfilled", zap.Uint("uid", msg.FollowerID), zap.Uint("author_id", msg.AuthorID), zap.Int("notes", len(notes)))
+}
+
+// removeAuthorFromTimeline 取消关注后移除该作者的笔记。时间线最多 TimelineMaxLength 条，只需要看作者最近这么多篇
+func (c *Consumer) removeAuthorFromTimeline(msg models.FeedMsg) {
+	var noteIDs []uint
+	err := c.db.Model(&models.Note{}).
+		Where("user_id = ?", msg.AuthorID).
+		Order("created_at DESC").
+		Limit(cache.TimelineMaxLength).
+		Pluck("id", &noteIDs).Error
+	if err != nil || len(noteIDs) == 0 {
+		return
+	}
+
+	members := make([]interface{}, len(noteIDs))
+	for i, id := range noteIDs {
+		members[i] = id
+	}
+	if _, err := c.cache.ZRem(context.Background(), cache.TimelineKey(msg.FollowerID), members...); err != nil {
+		zap.L().Error("Remove author from timeline failed", zap.Uint("uid", msg.FollowerID), zap.Error(err))
+	}
+}
@@ -0,0 +1,38 @@
+package mq
+
+import (
+	"note/config"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+)
+
+func TestBackfillTimelineOnlySeeded(t *testing.T) {
+	db := testutil.DB(t, &models.User{}, &models.Note{}, &models.UserFollow{})
+	rdb, mr := testutil.Cache(t)
+	c := &Consumer{db: db, cache: rdb, cfg: &config.Config{FeedBackfillCount: 10}}
+
+	author := models.User{Username: "author"}
+	db.Create(&author)
+	for _, private := range []bool{false, false, true} {
+		db.Create(&models.Note{UserID: author.ID, Title: "t", Content: "c", IsPrivate: private})
+	}
+	msg := models.FeedMsg{Event: models.FeedFollow, AuthorID: author.ID, FollowerID: 99}
+
+	// 未初始化的时间线不回填，留给读取时整体重建
+	c.backfillTimeline(msg)
+	if mr.Exists(cache.TimelineKey(99)) {
+		t.Fatal("backfill created an unseeded timeline")
+	}
+
+	if err := rdb.SeedTimeline(t.Context(), 99, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.backfillTimeline(msg)
+	members, _ := mr.ZMembers(cache.TimelineKey(99))
+	// 占位 member + 两篇公开笔记
+	if len(members) != 3 {
+		t.Fatalf("expected 2 public notes backfilled, got %v", members)
+	}
+}
@@ -0,0 +1,128 @@
+package mq
+
+import (
+	"errors"
+	"fmt"
+	"note/internal/infra/ai"
+	"note/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// generateFlashcards 为笔记生成问答卡片。重新生成时问题和答案都没变的卡片原样保留；
+// 问题相同 (忽略标点、空白、大小写) 只是答案改写了的卡片就地更新，保留原来的复习进度和记录；
+// 不再出现的卡片连同复习记录一起删除
+func (c *Consumer) generateFlashcards(note *models.Note, setting models.UserSetting) (string, error) {
+	var existing []models.Flashcard
+	if err := c.db.Where("note_id = ?", note.ID).Find(&existing).Error; err != nil {
+		return "", fmt.Errorf("load flashcards failed: %w", err)
+	}
+	previous := make([]ai.Flashcard, len(existing))
+	for i, card := range existing {
+		previous[i] = ai.Flashcard{Question: card.Question, Answer: card.Answer}
+	}
+
+	prefs := ai.Preferences{Language: setting.AILanguage, Style: setting.AIStyle}
+	generated, promptVersion, err := c.ai.GenerateFlashcards(note.UserID, note.Title+"\n"+note.Content, previous, prefs)
+	if err != nil {
+		return "", err
+	}
+	// 解析失败或模型没给出结果时不能把已有卡片全删了
+	if len(generated) == 0 {
+		return promptVersion, errors.New("AI returned no flashcards")
+	}
+
+	cards := make([]models.Flashcard, 0, len(generated))
+	seen := make(map[string]bool, len(generated))
+	for _, g := range generated {
+		card := models.NewFlashcard(note.ID, note.UserID, g.Question, g.Answer)
+		card.PromptVersion = promptVersion
+		if !seen[card.Hash] {
+			seen[card.Hash] = true
+			cards = append(cards, card)
+		}
+	}
+
+	// 先按内容完全匹配，剩下的再按问题匹配，避免改写的卡片抢走原样保留的卡片
+	matched := make(map[uint]bool, len(existing))
+	byHash := make(map[string]uint, len(existing))
+	for _, card := range existing {
+		byHash[card.Hash] = card.ID
+	}
+	var remaining []models.Flashcard
+	for _, card := range cards {
+		if id, ok := byHash[card.Hash]; ok {
+			matched[id] = true
+		} else {
+			remaining = append(remaining, card)
+		}
+	}
+
+	byQuestion := make(map[string]uint, len(existing))
+	for _, card := range existing {
+		key := models.FlashcardQuestionKey(card.Question)
+		if _, ok := byQuestion[key]; !ok && !matched[card.ID] {
+			byQuestion[key] = card.ID
+		}
+	}
+	updated := make(map[uint]models.Flashcard)
+	var created []models.Flashcard
+	for _, card := range remaining {
+		key := models.FlashcardQuestionKey(card.Question)
+		if id, ok := byQuestion[key]; ok && key != "" && !matched[id] {
+			matched[id] = true
+			updated[id] = card
+		} else {
+			created = append(created, card)
+		}
+	}
+
+	var stale []uint
+	for _, card := range existing {
+		if !matched[card.ID] {
+			stale = append(stale, card.ID)
+		}
+	}
+
+	var inserted int64
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		if len(stale) > 0 {
+			if err := tx.Where("flashcard_id IN ?", stale).Delete(&models.FlashcardReview{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Flashcard{}, stale).Error; err != nil {
+				return err
+			}
+		}
+		for id, card := range updated {
+			if err := tx.Model(&models.Flashcard{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"question":       card.Question,
+				"answer":         card.Answer,
+				"hash":           card.Hash,
+				"prompt_version": card.PromptVersion,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		// 并发重新生成时可能已经有人插入了同样的卡片，命中唯一索引时跳过
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&created)
+		inserted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return promptVersion, fmt.Errorf("save flashcards failed: %w", err)
+	}
+
+	zap.L().Info("Flashcards generated",
+		zap.Uint("note_id", note.ID),
+		zap.Int64("created", inserted),
+		zap.Int("updated", len(updated)),
+		zap.Int("removed", len(stale)),
+		zap.Int("kept", len(cards)-len(updated)-len(created)))
+	return promptVersion, nil
+}
@@ -0,0 +1,72 @@
+package mq
+
+import (
+	"note/config"
+	"note/internal/infra/ai"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestGenerateFlashcardsKeepsProgress(t *testing.T) {
+	db := testutil.DB(t, &models.Flashcard{}, &models.FlashcardReview{})
+	cfg := &config.Config{
+		AIChatProvider:  ai.ProviderLocal,
+		AIEmbedProvider: ai.ProviderLocal,
+		AIEmbedDim:      8,
+		AIChatTimeout:   time.Second,
+		AIFlashcardsMax: 10,
+	}
+	aiService, err := ai.NewAIService(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Consumer{db: db, ai: aiService, cfg: cfg}
+
+	// 本地模型每句话生成一张卡片：问题是前半句 + "……？"，答案是整句
+	note := &models.Note{ID: 1, UserID: 7, Title: "调度", Content: "调度器把协程复用到少量系统线程上。垃圾回收器使用三色标记并发清扫。"}
+	if _, err := c.generateFlashcards(note, models.UserSetting{}); err != nil {
+		t.Fatal(err)
+	}
+	var cards []models.Flashcard
+	db.Order("id").Find(&cards)
+	if len(cards) != 2 {
+		t.Fatalf("expected 2 cards, got %+v", cards)
+	}
+
+	// 复习过第一张卡片
+	reviewed := cards[0]
+	reviewed.Review(5, time.Now())
+	db.Save(&reviewed)
+	db.Create(&models.FlashcardReview{FlashcardID: reviewed.ID, UserID: 7, Grade: 5})
+
+	// 改写第一句的后半句：问题不变、答案变了；删掉第二句，加一句新的
+	note.Content = "调度器把协程复用到少数系统线程上。逃逸分析决定变量分配在栈上还是堆上。"
+	if _, err := c.generateFlashcards(note, models.UserSetting{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var after []models.Flashcard
+	db.Order("id").Find(&after)
+	if len(after) != 2 {
+		t.Fatalf("expected 2 cards after regeneration, got %+v", after)
+	}
+	kept := after[0]
+	if kept.ID != reviewed.ID || kept.Repetitions != 1 || kept.Answer != "调度器把协程复用到少数系统线程上" {
+		t.Fatalf("reworded card lost its progress: %+v", kept)
+	}
+	if kept.Hash != models.FlashcardHash(kept.Question, kept.Answer) {
+		t.Fatal("hash not updated with the new answer")
+	}
+	if after[1].ID == cards[1].ID {
+		t.Fatal("removed card was kept")
+	}
+
+	var reviews, orphans int64
+	db.Model(&models.FlashcardReview{}).Where("flashcard_id = ?", reviewed.ID).Count(&reviews)
+	db.Model(&models.FlashcardReview{}).Where("flashcard_id = ?", cards[1].ID).Count(&orphans)
+	if reviews != 1 || orphans != 0 {
+		t.Fatalf("reviews kept %d, orphans %d", reviews, orphans)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"note/config"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -13,6 +14,8 @@ import (
 type RabbitMQ struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
+	// delayQueues 已经声明过的延迟队列
+	delayQueues sync.Map
 }
 
 // New 初始化 RabbitMQ 连接
@@ -42,6 +45,7 @@ func New(cfg *config.Config) (*RabbitMQ, error) {
 		"history_queue",
 		"feed_queue",
 		"ai_queue",
+		"vector_queue",
 	}
 
 	// 遍历初始化，只要有一个失败，整个启动过程就应该失败
@@ -111,6 +115,32 @@ func (r *RabbitMQ) Publish(queueName string, body []byte) error {
 	return nil
 }
 
+// PublishDelayed 延迟 delay 后投递到 queueName：消息先进入带 TTL 的延迟队列，过期后经死信转回原队列。
+// 延迟中的消息保存在 RabbitMQ 里，进程重启不会丢失；每个延迟时长一个队列，短延迟的消息不会排在长延迟的后面
+func (r *RabbitMQ) PublishDelayed(queueName string, body []byte, delay time.Duration) error {
+	delayQueue := fmt.Sprintf("%s.delay.%s", queueName, delay)
+	if _, ok := r.delayQueues.Load(delayQueue); !ok {
+		_, err := r.channel.QueueDeclare(
+			delayQueue,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		)
+		if err != nil {
+			zap.L().Error("Failed to declare delay queue", zap.String("queue", delayQueue), zap.Error(err))
+			return err
+		}
+		r.delayQueues.Store(delayQueue, struct{}{})
+	}
+	return r.Publish(delayQueue, body)
+}
+
 // Consume 消费消息的通用方法 (返回一个只读通道)
 func (r *RabbitMQ) Consume(queueName string) (<-chan amqp.Delivery, error) {
 	msgs, err := r.channel.Consume(
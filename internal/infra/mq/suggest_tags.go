@@ -0,0 +1,87 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"note/internal/infra/ai"
+	"note/internal/models"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// suggestTags 让 AI 从用户已有的标签里为笔记挑选标签，保存为待确认的建议；
+// 用户开启了自动应用时，已有标签直接打到笔记上，新标签仍然等用户确认
+func (c *Consumer) suggestTags(note *models.Note, setting models.UserSetting) (string, error) {
+	var vocabulary []models.Tag
+	if err := c.db.Where("user_id = ?", note.UserID).Find(&vocabulary).Error; err != nil {
+		return "", fmt.Errorf("load user tags failed: %w", err)
+	}
+	var current []models.Tag
+	if err := c.db.Model(note).Association("Tags").Find(&current); err != nil {
+		return "", fmt.Errorf("load note tags failed: %w", err)
+	}
+
+	names := make([]string, len(vocabulary))
+	byName := make(map[string]models.Tag, len(vocabulary))
+	for i, t := range vocabulary {
+		names[i] = t.Name
+		byName[strings.ToLower(t.Name)] = t
+	}
+	attached := make(map[uint]bool, len(current))
+	for _, t := range current {
+		attached[t.ID] = true
+	}
+
+	prefs := ai.Preferences{Language: setting.AILanguage, Style: setting.AIStyle}
+	suggested, promptVersion, err := c.ai.SuggestTags(note.UserID, note.Title+"\n"+note.Content, names, prefs)
+	if err != nil {
+		return "", err
+	}
+
+	var toApply []models.Tag
+	for _, s := range suggested {
+		suggestion := models.TagSuggestion{
+			NoteID: note.ID,
+			UserID: note.UserID,
+			Name:   s.Name,
+			Status: models.SuggestionPending,
+
+			PromptVersion: promptVersion,
+		}
+		if !s.IsNew {
+			tag := byName[strings.ToLower(s.Name)]
+			if attached[tag.ID] {
+				continue
+			}
+			suggestion.TagID = &tag.ID
+			if setting.AutoApplyTags {
+				suggestion.Status = models.SuggestionAccepted
+			}
+		}
+
+		// 同一篇笔记同名的建议只保留一条，用户拒绝过的不会再次出现，也不会被自动应用
+		result := c.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&suggestion)
+		if result.Error != nil {
+			zap.L().Error("Save tag suggestion failed", zap.Uint("nid", note.ID), zap.Error(result.Error))
+			continue
+		}
+		if result.RowsAffected > 0 && suggestion.Status == models.SuggestionAccepted {
+			toApply = append(toApply, byName[strings.ToLower(s.Name)])
+		}
+	}
+
+	if len(toApply) > 0 {
+		if err := c.db.Model(note).Association("Tags").Append(toApply); err != nil {
+			return promptVersion, fmt.Errorf("auto apply tags failed: %w", err)
+		}
+		c.cache.ClearNoteCache(context.Background(), note.ID, note.UserID)
+	}
+
+	zap.L().Info("Tag suggestions saved",
+		zap.Uint("nid", note.ID),
+		zap.Int("suggested", len(suggested)),
+		zap.Int("auto_applied", len(toApply)))
+	return promptVersion, nil
+}
@@ -0,0 +1,114 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note/config"
+)
+
+const (
+	BackendQdrant = "qdrant" // 独立部署的 Qdrant
+	BackendLocal  = "local"  // 进程内暴力检索，数据持久化到本地文件
+)
+
+// ErrDimensionMismatch 已有集合的向量维度和当前向量模型不一致
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// ErrPointNotFound 索引中没有这条记录
+var ErrPointNotFound = errors.New("vector point not found")
+
+// Payload 和向量一起存储的笔记元数据，用于检索时过滤
+type Payload struct {
+	UserID    uint
+	IsPrivate bool
+}
+
+// Filter 检索条件，零值字段表示不限制
+type Filter struct {
+	// ViewerID 只返回该用户自己的笔记或公开笔记
+	ViewerID uint
+	// OwnerID 只返回该用户的笔记
+	OwnerID uint
+	// ExcludeIDs 排除这些笔记
+	ExcludeIDs []uint
+	// PublicOnly 只返回公开笔记 (包括 ViewerID 自己的私密笔记也不返回)
+	PublicOnly bool
+	// ExcludeOwnerIDs 排除这些用户的笔记
+	ExcludeOwnerIDs []uint
+}
+
+// Hit 一条检索结果
+type Hit struct {
+	ID    uint
+	Score float32
+}
+
+// Point 索引中的一条记录，Vector 只有在请求时才会返回
+type Point struct {
+	ID      uint
+	Vector  []float32
+	Payload Payload
+}
+
+// VectorIndex 笔记向量索引，id 即 MySQL 中的 Note ID
+type VectorIndex interface {
+	Upsert(ctx context.Context, id uint, vector []float32, payload Payload) error
+	Delete(ctx context.Context, ids ...uint) error
+	// SetPayload 只更新过滤字段 (如 is_private)，不重新写向量；点不存在时返回 ErrPointNotFound
+	SetPayload(ctx context.Context, id uint, payload Payload) error
+	Search(ctx context.Context, vector []float32, limit uint64, filter Filter) ([]Hit, error)
+	// Recommend 以已有记录的向量为查询，找最相似的记录 (不包含自身)；记录不存在时返回 ErrPointNotFound
+	Recommend(ctx context.Context, id uint, limit uint64, filter Filter) ([]Hit, error)
+	Count(ctx context.Context, filter Filter) (uint64, error)
+	// Retrieve 按 ID 批量读取，不存在的 ID 不会出现在结果里
+	Retrieve(ctx context.Context, ids []uint, withVectors bool) ([]Point, error)
+	// Scroll 按 ID 升序遍历 ID 大于 afterID 的记录，最多 limit 条
+	Scroll(ctx context.Context, afterID uint, limit int, withVectors bool) ([]Point, error)
+	// Dimension 索引的向量维度
+	Dimension() int
+	Close() error
+}
+
+// New 按配置创建向量索引，dim 为当前向量模型的维度
+func New(cfg *config.Config, dim int) (VectorIndex, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("invalid vector dimension %d, check AI_EMBED_DIM", dim)
+	}
+
+	switch cfg.VectorBackend {
+	case BackendQdrant:
+		return NewQdrantService(cfg.QdrantHost, cfg.QdrantPort, cfg.VectorCollection, cfg.QdrantAPIKey, dim)
+	case BackendLocal:
+		return NewLocalIndex(cfg.VectorLocalDir, cfg.VectorCollection, dim)
+	default:
+		return nil, fmt.Errorf("unknown vector backend: %q", cfg.VectorBackend)
+	}
+}
+
+// Admin 集合级别的管理操作，重建索引和切换向量模型时使用
+type Admin interface {
+	// Open 打开指定集合，不存在则按 dim 创建
+	Open(ctx context.Context, collection string, dim int) (VectorIndex, error)
+	// SwitchAlias 原子地把 alias 指向 collection，之后通过 alias 的读写都落到新集合
+	SwitchAlias(ctx context.Context, alias, collection string) error
+}
+
+// NewAdmin 按配置创建集合管理器
+func NewAdmin(cfg *config.Config) (Admin, error) {
+	switch cfg.VectorBackend {
+	case BackendQdrant:
+		return newQdrantAdmin(cfg.QdrantHost, cfg.QdrantPort, cfg.QdrantAPIKey)
+	case BackendLocal:
+		return &localAdmin{dir: cfg.VectorLocalDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown vector backend: %q", cfg.VectorBackend)
+	}
+}
+
+func checkDimension(dim int, vector []float32) error {
+	if len(vector) != dim {
+		return fmt.Errorf("%w: index expects %d, got %d", ErrDimensionMismatch, dim, len(vector))
+	}
+	return nil
+}
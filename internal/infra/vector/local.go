@@ -0,0 +1,479 @@
+package vector
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LocalIndex 进程内的向量索引：暴力计算余弦相似度，数据定期落盘。
+// 适合几万条以内的小规模部署和离线开发，不依赖 Qdrant。
+// 每个集合是 dir 下的一个 <集合名>.gob 文件，别名记录在 dir/aliases.json。
+type LocalIndex struct {
+	mu     sync.RWMutex
+	dir    string
+	name   string // 打开时使用的名字，可能是别名
+	path   string // 实际读写的文件
+	dim    int
+	points map[uint]localPoint
+
+	dirty   bool
+	flushMu sync.Mutex // 保证同一时间只有一次落盘
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type localPoint struct {
+	Vector  []float32 // 已归一化
+	Payload Payload
+}
+
+// localFile 落盘格式
+type localFile struct {
+	Dim    int
+	Points map[uint]localPoint
+}
+
+const (
+	localFlushInterval = 2 * time.Second
+	localAliasFile     = "aliases.json"
+)
+
+// liveLocal 当前进程里打开着的索引，切换别名后需要通知它们重新加载
+var liveLocal = struct {
+	sync.Mutex
+	m map[string]*LocalIndex
+}{m: make(map[string]*LocalIndex)}
+
+func NewLocalIndex(dir, collectionName string, dim int) (*LocalIndex, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create vector dir failed: %w", err)
+	}
+
+	path, err := resolveLocalPath(dir, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	points, err := loadLocalFile(path, dim)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &LocalIndex{
+		dir:    dir,
+		name:   collectionName,
+		path:   path,
+		dim:    dim,
+		points: points,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	liveLocal.Lock()
+	liveLocal.m[localKey(dir, collectionName)] = idx
+	liveLocal.Unlock()
+
+	go idx.flushLoop()
+	return idx, nil
+}
+
+func localKey(dir, name string) string {
+	return filepath.Join(dir, name)
+}
+
+func readLocalAliases(dir string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(dir, localAliasFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read vector aliases failed: %w", err)
+	}
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("decode vector aliases failed: %w", err)
+	}
+	return aliases, nil
+}
+
+// resolveLocalPath 别名优先，找不到别名就当作集合名
+func resolveLocalPath(dir, name string) (string, error) {
+	aliases, err := readLocalAliases(dir)
+	if err != nil {
+		return "", err
+	}
+	if target, ok := aliases[name]; ok {
+		name = target
+	}
+	return filepath.Join(dir, name+".gob"), nil
+}
+
+func loadLocalFile(path string, dim int) (map[uint]localPoint, error) {
+	points := make(map[uint]localPoint)
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return points, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open vector file failed: %w", err)
+	}
+	defer f.Close()
+
+	var data localFile
+	if err := gob.NewDecoder(f).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode vector file failed: %w", err)
+	}
+	if data.Dim != dim {
+		return nil, fmt.Errorf("%w: %s has size %d, embedding model produces %d",
+			ErrDimensionMismatch, path, data.Dim, dim)
+	}
+	if data.Points != nil {
+		points = data.Points
+	}
+	return points, nil
+}
+
+// reload 别名切换后重新解析文件路径并加载数据，旧数据先落盘到原来的文件
+func (l *LocalIndex) reload() error {
+	if err := l.flush(); err != nil {
+		return err
+	}
+	path, err := resolveLocalPath(l.dir, l.name)
+	if err != nil {
+		return err
+	}
+	points, err := loadLocalFile(path, l.dim)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.path = path
+	l.points = points
+	l.dirty = false
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *LocalIndex) flushLoop() {
+	defer close(l.done)
+	ticker := time.NewTicker(localFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.flush(); err != nil {
+				zap.L().Error("Flush local vector index failed", zap.Error(err))
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// flush 先写临时文件再 rename，避免进程崩溃时留下半个文件。
+// 拍快照时清除 dirty，写入期间的修改会重新置位；写入失败时恢复 dirty，下次重试
+func (l *LocalIndex) flush() error {
+	l.flushMu.Lock()
+	defer l.flushMu.Unlock()
+
+	l.mu.Lock()
+	if !l.dirty {
+		l.mu.Unlock()
+		return nil
+	}
+	snapshot := make(map[uint]localPoint, len(l.points))
+	for id, p := range l.points {
+		snapshot[id] = p
+	}
+	l.dirty = false
+	path := l.path
+	l.mu.Unlock()
+
+	if err := writeLocalFile(path, localFile{Dim: l.dim, Points: snapshot}); err != nil {
+		l.mu.Lock()
+		l.dirty = true
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func writeLocalFile(path string, data localFile) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (l *LocalIndex) Dimension() int {
+	return l.dim
+}
+
+func (l *LocalIndex) Close() error {
+	liveLocal.Lock()
+	if liveLocal.m[localKey(l.dir, l.name)] == l {
+		delete(liveLocal.m, localKey(l.dir, l.name))
+	}
+	liveLocal.Unlock()
+
+	close(l.stop)
+	<-l.done
+	return l.flush()
+}
+
+func (l *LocalIndex) Upsert(_ context.Context, id uint, vector []float32, payload Payload) error {
+	if err := checkDimension(l.dim, vector); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.points[id] = localPoint{Vector: normalize(vector), Payload: payload}
+	l.dirty = true
+	return nil
+}
+
+func (l *LocalIndex) SetPayload(_ context.Context, id uint, payload Payload) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.points[id]
+	if !ok {
+		return ErrPointNotFound
+	}
+	p.Payload = payload
+	l.points[id] = p
+	l.dirty = true
+	return nil
+}
+
+func (l *LocalIndex) Delete(_ context.Context, ids ...uint) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		delete(l.points, id)
+	}
+	l.dirty = true
+	return nil
+}
+
+func (l *LocalIndex) Search(_ context.Context, vector []float32, limit uint64, filter Filter) ([]Hit, error) {
+	if err := checkDimension(l.dim, vector); err != nil {
+		return nil, err
+	}
+	query := normalize(vector)
+	excluded := toSet(filter.ExcludeIDs)
+
+	l.mu.RLock()
+	hits := make([]Hit, 0, len(l.points))
+	for id, p := range l.points {
+		if !matches(id, p.Payload, filter, excluded) {
+			continue
+		}
+		hits = append(hits, Hit{ID: id, Score: dot(query, p.Vector)})
+	}
+	l.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].ID > hits[j].ID
+		}
+		return hits[i].Score > hits[j].Score
+	})
+	if uint64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func (l *LocalIndex) Recommend(ctx context.Context, id uint, limit uint64, filter Filter) ([]Hit, error) {
+	l.mu.RLock()
+	p, ok := l.points[id]
+	l.mu.RUnlock()
+	if !ok {
+		return nil, ErrPointNotFound
+	}
+
+	// Clip 保证追加时复制一份，不会写进调用方切片的底层数组
+	filter.ExcludeIDs = append(slices.Clip(filter.ExcludeIDs), id)
+	return l.Search(ctx, p.Vector, limit, filter)
+}
+
+func (l *LocalIndex) Count(_ context.Context, filter Filter) (uint64, error) {
+	excluded := toSet(filter.ExcludeIDs)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var n uint64
+	for id, p := range l.points {
+		if matches(id, p.Payload, filter, excluded) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (l *LocalIndex) Retrieve(_ context.Context, ids []uint, withVectors bool) ([]Point, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	points := make([]Point, 0, len(ids))
+	for _, id := range ids {
+		p, ok := l.points[id]
+		if !ok {
+			continue
+		}
+		point := Point{ID: id, Payload: p.Payload}
+		if withVectors {
+			point.Vector = append([]float32(nil), p.Vector...)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+func (l *LocalIndex) Scroll(_ context.Context, afterID uint, limit int, withVectors bool) ([]Point, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ids := make([]uint, 0, len(l.points))
+	for id := range l.points {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	points := make([]Point, 0, len(ids))
+	for _, id := range ids {
+		p := l.points[id]
+		point := Point{ID: id, Payload: p.Payload}
+		if withVectors {
+			point.Vector = append([]float32(nil), p.Vector...)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// localAdmin 本地索引的集合管理
+type localAdmin struct {
+	dir string
+}
+
+func (a *localAdmin) Open(_ context.Context, collection string, dim int) (VectorIndex, error) {
+	return NewLocalIndex(a.dir, collection, dim)
+}
+
+func (a *localAdmin) SwitchAlias(_ context.Context, alias, collection string) error {
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return err
+	}
+	aliases, err := readLocalAliases(a.dir)
+	if err != nil {
+		return err
+	}
+	aliases[alias] = collection
+
+	data, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 写临时文件再 rename，保证别名文件的替换是原子的
+	path := filepath.Join(a.dir, localAliasFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	// 进程内正在通过这个别名读写的索引切到新集合
+	liveLocal.Lock()
+	live := liveLocal.m[localKey(a.dir, alias)]
+	liveLocal.Unlock()
+	if live != nil {
+		return live.reload()
+	}
+	return nil
+}
+
+func matches(id uint, p Payload, f Filter, excluded map[uint]struct{}) bool {
+	if f.ViewerID != 0 && p.UserID != f.ViewerID && p.IsPrivate {
+		return false
+	}
+	if f.OwnerID != 0 && p.UserID != f.OwnerID {
+		return false
+	}
+	if _, ok := excluded[id]; ok {
+		return false
+	}
+	if f.PublicOnly && p.IsPrivate {
+		return false
+	}
+	for _, owner := range f.ExcludeOwnerIDs {
+		if p.UserID == owner {
+			return false
+		}
+	}
+	return true
+}
+
+func toSet(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,173 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLocalFlushRetriesAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	idx, err := NewLocalIndex(dir, "notes", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	if err := idx.Upsert(context.Background(), 1, []float32{1, 0}, Payload{UserID: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 临时文件的位置被目录占住，写入失败
+	path := filepath.Join(dir, "notes.gob")
+	if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.flush(); err == nil {
+		t.Fatal("flush succeeded with the temp file blocked")
+	}
+	idx.mu.RLock()
+	dirty := idx.dirty
+	idx.mu.RUnlock()
+	if !dirty {
+		t.Fatal("dirty cleared after a failed flush")
+	}
+
+	if err := os.Remove(path + ".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.flush(); err != nil {
+		t.Fatalf("retry flush: %v", err)
+	}
+	points, err := loadLocalFile(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := points[1]; !ok || p.Payload.UserID != 7 {
+		t.Fatalf("point not persisted: %+v", points)
+	}
+}
+
+func newTestLocalIndex(t *testing.T) *LocalIndex {
+	t.Helper()
+	idx, err := NewLocalIndex(t.TempDir(), "notes", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = idx.Close() })
+	return idx
+}
+
+func hitIDs(hits []Hit) []uint {
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestLocalSearch(t *testing.T) {
+	idx := newTestLocalIndex(t)
+	ctx := context.Background()
+	// 和查询向量 (1, 0) 的夹角依次变大；向量不需要是单位长度
+	_ = idx.Upsert(ctx, 1, []float32{0, 3}, Payload{})
+	_ = idx.Upsert(ctx, 2, []float32{2, 0}, Payload{})
+	_ = idx.Upsert(ctx, 3, []float32{1, 1}, Payload{})
+	_ = idx.Upsert(ctx, 4, []float32{5, 5}, Payload{})
+
+	hits, err := idx.Search(ctx, []float32{1, 0}, 3, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分数相同时 ID 大的在前
+	if got, want := hitIDs(hits), []uint{2, 4, 3}; !slices.Equal(got, want) {
+		t.Fatalf("Search() = %v, want %v", got, want)
+	}
+	if hits[0].Score < 0.999 {
+		t.Fatalf("score of an identical direction = %v, want 1", hits[0].Score)
+	}
+
+	if _, err := idx.Search(ctx, []float32{1, 0, 0}, 3, Filter{}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("Search() with a wrong dimension: %v", err)
+	}
+	if err := idx.Upsert(ctx, 5, []float32{1}, Payload{}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("Upsert() with a wrong dimension: %v", err)
+	}
+}
+
+func TestLocalFilter(t *testing.T) {
+	idx := newTestLocalIndex(t)
+	ctx := context.Background()
+	points := map[uint]Payload{
+		1: {UserID: 7},
+		2: {UserID: 7, IsPrivate: true},
+		3: {UserID: 8},
+		4: {UserID: 8, IsPrivate: true},
+		5: {UserID: 9},
+	}
+	for id, p := range points {
+		_ = idx.Upsert(ctx, id, []float32{1, float32(id)}, p)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint
+	}{
+		{"no filter", Filter{}, []uint{1, 2, 3, 4, 5}},
+		{"viewer sees own private and others' public", Filter{ViewerID: 7}, []uint{1, 2, 3, 5}},
+		{"owner only", Filter{OwnerID: 8}, []uint{3, 4}},
+		{"exclude ids", Filter{ExcludeIDs: []uint{1, 5}}, []uint{2, 3, 4}},
+		{"public only hides the viewer's private notes too", Filter{ViewerID: 7, PublicOnly: true}, []uint{1, 3, 5}},
+		{"exclude owners", Filter{ExcludeOwnerIDs: []uint{7, 9}}, []uint{3, 4}},
+		{"combined", Filter{PublicOnly: true, ExcludeOwnerIDs: []uint{9}, ExcludeIDs: []uint{1}}, []uint{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := idx.Search(ctx, []float32{1, 0}, 10, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := hitIDs(hits)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Search() = %v, want %v", got, tt.want)
+			}
+			n, err := idx.Count(ctx, tt.filter)
+			if err != nil || n != uint64(len(tt.want)) {
+				t.Fatalf("Count() = %d, %v; want %d", n, err, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestLocalRecommend(t *testing.T) {
+	idx := newTestLocalIndex(t)
+	ctx := context.Background()
+	_ = idx.Upsert(ctx, 1, []float32{1, 0}, Payload{UserID: 7})
+	_ = idx.Upsert(ctx, 2, []float32{1, 0.1}, Payload{UserID: 7})
+	_ = idx.Upsert(ctx, 3, []float32{1, 1}, Payload{UserID: 7})
+	_ = idx.Upsert(ctx, 4, []float32{0, 1}, Payload{UserID: 7})
+
+	// 调用方的切片还有空余容量，Recommend 追加自身 ID 时不能写进去
+	exclude := make([]uint, 1, 4)
+	exclude[0] = 3
+	backing := exclude[:cap(exclude)]
+	hits, err := idx.Recommend(ctx, 1, 10, Filter{ExcludeIDs: exclude})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hitIDs(hits), []uint{2, 4}; !slices.Equal(got, want) {
+		t.Fatalf("Recommend() = %v, want %v", got, want)
+	}
+	if backing[1] != 0 {
+		t.Fatalf("Recommend() wrote %d into the caller's ExcludeIDs", backing[1])
+	}
+
+	if _, err := idx.Recommend(ctx, 99, 10, Filter{}); !errors.Is(err, ErrPointNotFound) {
+		t.Fatalf("Recommend() for a missing point: %v", err)
+	}
+}
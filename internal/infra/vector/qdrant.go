@@ -2,6 +2,8 @@ package vector
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
 	"github.com/qdrant/go-client/qdrant"
 	"go.uber.org/zap"
@@ -12,9 +14,24 @@ import (
 type QdrantService struct {
 	client *qdrant.Client
 	col    string
+	dim    int
 }
 
-func NewQdrantService(host string, port int, collectionName string, apiKey string) *QdrantService {
+func NewQdrantService(host string, port int, collectionName string, apiKey string, dim int) (*QdrantService, error) {
+	client, err := newQdrantClient(host, port, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &QdrantService{client: client, col: collectionName, dim: dim}
+	if err := svc.ensureCollection(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return svc, nil
+}
+
+func newQdrantClient(host string, port int, apiKey string) (*qdrant.Client, error) {
 	config := &qdrant.Config{
 		Host: host,
 		Port: port,
@@ -33,50 +50,66 @@ func NewQdrantService(host string, port int, collectionName string, apiKey strin
 
 	client, err := qdrant.NewClient(config)
 	if err != nil {
-		panic("无法连接 Qdrant 数据库: " + err.Error())
+		return nil, fmt.Errorf("无法连接 Qdrant 数据库: %w", err)
 	}
-
-	svc := &QdrantService{client: client, col: collectionName}
-	svc.ensureCollection()
-	return svc
+	return client, nil
 }
 
-// ensureCollection 不存在就创建
-func (s *QdrantService) ensureCollection() {
+// ensureCollection 不存在就创建，已存在则校验维度
+func (s *QdrantService) ensureCollection() error {
 	ctx := context.Background()
 	exists, err := s.client.CollectionExists(ctx, s.col)
 	if err != nil {
-		zap.L().Error("Check qdrant collection failed", zap.Error(err))
-		return
+		return fmt.Errorf("check qdrant collection failed: %w", err)
 	}
+
 	if !exists {
 		err := s.client.CreateCollection(ctx, &qdrant.CreateCollection{
 			CollectionName: s.col,
 			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-				Size:     2048,
+				Size:     uint64(s.dim),
 				Distance: qdrant.Distance_Cosine,
 			}),
 		})
 		if err != nil {
-			zap.L().Error("Create collection failed", zap.Error(err))
+			return fmt.Errorf("create collection failed: %w", err)
 		}
+		return nil
 	}
+
+	info, err := s.client.GetCollectionInfo(ctx, s.col)
+	if err != nil {
+		return fmt.Errorf("get collection info failed: %w", err)
+	}
+	size := info.GetConfig().GetParams().GetVectorsConfig().GetParams().GetSize()
+	if size != uint64(s.dim) {
+		return fmt.Errorf("%w: collection %s has size %d, embedding model produces %d",
+			ErrDimensionMismatch, s.col, size, s.dim)
+	}
+	return nil
+}
+
+func (s *QdrantService) Dimension() int {
+	return s.dim
+}
+
+func (s *QdrantService) Close() error {
+	return s.client.Close()
 }
 
 // Upsert 将向量存入 Qdrant
 // id: MySQL 中的 Note ID
 // vector: AI 生成的向量
-func (s *QdrantService) Upsert(ctx context.Context, id uint, vector []float32, userID uint, isPrivate bool) error {
-	payload := map[string]*qdrant.Value{
-		"user_id":    {Kind: &qdrant.Value_IntegerValue{IntegerValue: int64(userID)}},
-		"is_private": {Kind: &qdrant.Value_BoolValue{BoolValue: isPrivate}},
+func (s *QdrantService) Upsert(ctx context.Context, id uint, vector []float32, payload Payload) error {
+	if err := checkDimension(s.dim, vector); err != nil {
+		return err
 	}
 
 	points := []*qdrant.PointStruct{
 		{
 			Id:      qdrant.NewIDNum(uint64(id)),
 			Vectors: qdrant.NewVectors(vector...),
-			Payload: payload,
+			Payload: toQdrantPayload(payload),
 		},
 	}
 
@@ -87,56 +120,266 @@ func (s *QdrantService) Upsert(ctx context.Context, id uint, vector []float32, u
 	return err
 }
 
-func (s *QdrantService) Search(ctx context.Context, vector []float32, limit uint64, userID uint) ([]uint, error) {
-	// 构造 Filter: (user_id == current_user) OR (is_public == true)
-	filter := &qdrant.Filter{
-		Should: []*qdrant.Condition{
-			{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: "user_id",
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Integer{
-								Integer: int64(userID),
-							},
-						},
-					},
-				},
-			},
-			{
-				ConditionOneOf: &qdrant.Condition_Field{
-					Field: &qdrant.FieldCondition{
-						Key: "is_private",
-						Match: &qdrant.Match{
-							MatchValue: &qdrant.Match_Boolean{
-								Boolean: false,
-							},
-						},
-					},
-				},
-			},
-		},
+// SetPayload 覆盖已有点的 payload。Qdrant 对不存在的点不会报错，所以先查一次
+func (s *QdrantService) SetPayload(ctx context.Context, id uint, payload Payload) error {
+	points, err := s.Retrieve(ctx, []uint{id}, false)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return ErrPointNotFound
+	}
+
+	_, err = s.client.OverwritePayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: s.col,
+		Payload:        toQdrantPayload(payload),
+		PointsSelector: qdrant.NewPointsSelector(qdrant.NewIDNum(uint64(id))),
+	})
+	return err
+}
+
+func (s *QdrantService) Delete(ctx context.Context, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
 	}
+	_, err := s.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: s.col,
+		Points:         qdrant.NewPointsSelector(toPointIDs(ids)...),
+	})
+	return err
+}
 
+func (s *QdrantService) Search(ctx context.Context, vector []float32, limit uint64, filter Filter) ([]Hit, error) {
+	return s.query(ctx, qdrant.NewQuery(vector...), limit, filter)
+}
+
+func (s *QdrantService) query(ctx context.Context, query *qdrant.Query, limit uint64, filter Filter) ([]Hit, error) {
 	res, err := s.client.Query(ctx, &qdrant.QueryPoints{
 		CollectionName: s.col,
-		Query:          qdrant.NewQuery(vector...),
-		Filter:         filter, // 传入这个混合过滤器
+		Query:          query,
+		Filter:         toQdrantFilter(filter),
 		Limit:          &limit,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	var ids []uint
+	hits := make([]Hit, 0, len(res))
 	for _, point := range res {
-		if point.Id == nil {
+		if id, ok := fromPointID(point.Id); ok {
+			hits = append(hits, Hit{ID: id, Score: point.Score})
+		}
+	}
+	return hits, nil
+}
+
+func (s *QdrantService) Count(ctx context.Context, filter Filter) (uint64, error) {
+	exact := true
+	return s.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: s.col,
+		Filter:         toQdrantFilter(filter),
+		Exact:          &exact,
+	})
+}
+
+func (s *QdrantService) Recommend(ctx context.Context, id uint, limit uint64, filter Filter) ([]Hit, error) {
+	points, err := s.Retrieve(ctx, []uint{id}, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, ErrPointNotFound
+	}
+
+	// 直接用库里已有的向量做查询，不需要重新生成
+	// Clip 保证追加时复制一份，不会写进调用方切片的底层数组
+	filter.ExcludeIDs = append(slices.Clip(filter.ExcludeIDs), id)
+	return s.query(ctx, qdrant.NewQueryID(qdrant.NewIDNum(uint64(id))), limit, filter)
+}
+
+func (s *QdrantService) Retrieve(ctx context.Context, ids []uint, withVectors bool) ([]Point, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	res, err := s.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: s.col,
+		Ids:            toPointIDs(ids),
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(withVectors),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, 0, len(res))
+	for _, p := range res {
+		id, ok := fromPointID(p.GetId())
+		if !ok {
 			continue
 		}
-		// 提取 ID
-		if numID, ok := point.Id.PointIdOptions.(*qdrant.PointId_Num); ok {
-			ids = append(ids, uint(numID.Num))
+		point := Point{ID: id, Payload: fromQdrantPayload(p.GetPayload())}
+		if withVectors {
+			point.Vector = denseVector(p.GetVectors().GetVector())
 		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+func (s *QdrantService) Scroll(ctx context.Context, afterID uint, limit int, withVectors bool) ([]Point, error) {
+	n := uint32(limit)
+	// 整数 ID 的点按 ID 升序返回，offset 包含自身，所以从 afterID+1 开始
+	res, err := s.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: s.col,
+		Offset:         qdrant.NewIDNum(uint64(afterID) + 1),
+		Limit:          &n,
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(withVectors),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, 0, len(res))
+	for _, p := range res {
+		id, ok := fromPointID(p.GetId())
+		if !ok {
+			continue
+		}
+		point := Point{ID: id, Payload: fromQdrantPayload(p.GetPayload())}
+		if withVectors {
+			point.Vector = denseVector(p.GetVectors().GetVector())
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// qdrantAdmin Qdrant 集合管理
+type qdrantAdmin struct {
+	client *qdrant.Client
+	host   string
+	port   int
+	apiKey string
+}
+
+func newQdrantAdmin(host string, port int, apiKey string) (*qdrantAdmin, error) {
+	client, err := newQdrantClient(host, port, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &qdrantAdmin{client: client, host: host, port: port, apiKey: apiKey}, nil
+}
+
+func (a *qdrantAdmin) Open(_ context.Context, collection string, dim int) (VectorIndex, error) {
+	return NewQdrantService(a.host, a.port, collection, a.apiKey, dim)
+}
+
+func (a *qdrantAdmin) SwitchAlias(ctx context.Context, alias, collection string) error {
+	aliases, err := a.client.ListAliases(ctx)
+	if err != nil {
+		return fmt.Errorf("list aliases failed: %w", err)
+	}
+	aliasExists := false
+	for _, desc := range aliases {
+		if desc.GetAliasName() == alias {
+			aliasExists = true
+			break
+		}
+	}
+
+	if !aliasExists {
+		// 老部署里 alias 这个名字是一个真实集合，别名不能和集合重名，只能先删掉它。
+		// 这一步不是原子的，但只会在第一次迁移时发生，新集合此时已经完整构建好了。
+		exists, err := a.client.CollectionExists(ctx, alias)
+		if err != nil {
+			return fmt.Errorf("check collection failed: %w", err)
+		}
+		if exists {
+			zap.L().Warn("Dropping legacy collection to replace it with an alias",
+				zap.String("collection", alias), zap.String("target", collection))
+			if err := a.client.DeleteCollection(ctx, alias); err != nil {
+				return fmt.Errorf("drop legacy collection failed: %w", err)
+			}
+		}
+	}
+
+	// 删除旧别名和创建新别名放在同一个请求里，Qdrant 保证原子生效
+	var actions []*qdrant.AliasOperations
+	if aliasExists {
+		actions = append(actions, qdrant.NewAliasDelete(alias))
+	}
+	actions = append(actions, qdrant.NewAliasCreate(alias, collection))
+	return a.client.UpdateAliases(ctx, actions)
+}
+
+func toQdrantPayload(p Payload) map[string]*qdrant.Value {
+	return map[string]*qdrant.Value{
+		"user_id":    qdrant.NewValueInt(int64(p.UserID)),
+		"is_private": qdrant.NewValueBool(p.IsPrivate),
+	}
+}
+
+func fromQdrantPayload(p map[string]*qdrant.Value) Payload {
+	return Payload{
+		UserID:    uint(p["user_id"].GetIntegerValue()),
+		IsPrivate: p["is_private"].GetBoolValue(),
+	}
+}
+
+func denseVector(v *qdrant.VectorOutput) []float32 {
+	if dense := v.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	return v.GetData()
+}
+
+func toQdrantFilter(f Filter) *qdrant.Filter {
+	filter := &qdrant.Filter{}
+
+	if f.ViewerID != 0 {
+		// (user_id == viewer) OR (is_private == false)
+		filter.Must = append(filter.Must, qdrant.NewFilterAsCondition(&qdrant.Filter{
+			Should: []*qdrant.Condition{
+				qdrant.NewMatchInt("user_id", int64(f.ViewerID)),
+				qdrant.NewMatchBool("is_private", false),
+			},
+		}))
+	}
+	if f.OwnerID != 0 {
+		filter.Must = append(filter.Must, qdrant.NewMatchInt("user_id", int64(f.OwnerID)))
+	}
+	if len(f.ExcludeIDs) > 0 {
+		filter.MustNot = append(filter.MustNot, qdrant.NewHasID(toPointIDs(f.ExcludeIDs)...))
+	}
+	if f.PublicOnly {
+		filter.Must = append(filter.Must, qdrant.NewMatchBool("is_private", false))
+	}
+	if len(f.ExcludeOwnerIDs) > 0 {
+		owners := make([]int64, len(f.ExcludeOwnerIDs))
+		for i, id := range f.ExcludeOwnerIDs {
+			owners[i] = int64(id)
+		}
+		filter.MustNot = append(filter.MustNot, qdrant.NewMatchInts("user_id", owners...))
+	}
+	return filter
+}
+
+func toPointIDs(ids []uint) []*qdrant.PointId {
+	pointIDs := make([]*qdrant.PointId, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = qdrant.NewIDNum(uint64(id))
+	}
+	return pointIDs
+}
+
+func fromPointID(id *qdrant.PointId) (uint, bool) {
+	if id == nil {
+		return 0, false
+	}
+	numID, ok := id.PointIdOptions.(*qdrant.PointId_Num)
+	if !ok {
+		return 0, false
 	}
-	return ids, nil
+	return uint(numID.Num), true
 }
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"note/internal/history"
+	"note/internal/indexer"
+	"note/internal/svc"
+	"time"
+)
+
+// 每次最多为多少个用户重新聚类，聚类和命名都比较重
+const topicUsersPerRun = 50
+
+// 每次最多为多少个到期用户生成摘要
+const digestUsersPerRun = 100
+
+// Register 注册所有后台定时任务
+func Register(s *Scheduler, svcCtx *svc.ServiceContext) {
+	cfg := svcCtx.Config
+
+	// 对账：补齐缺失的笔记，清理已删除笔记的向量，修正不一致的 payload
+	s.Every("vector_reconcile", cfg.ReconcileInterval, func(ctx context.Context) error {
+		_, err := svcCtx.Indexer.Reconcile(ctx, indexer.ReconcileOptions{MaxRepair: cfg.ReconcileMaxRepair})
+		return err
+	})
+
+	// 主题聚类：只处理笔记有变化、新笔记无法归入已有主题的用户
+	s.Every("topic_cluster", cfg.TopicClusterInterval, func(ctx context.Context) error {
+		return svcCtx.Topics.RunDirty(ctx, topicUsersPerRun)
+	})
+
+	// 热门榜：重新计算近期笔记的互动分 (纳入浏览数)，整体替换榜单
+	s.Every("hot_rank", cfg.RankRebuildInterval, func(ctx context.Context) error {
+		return svcCtx.Ranking.Rebuild(ctx)
+	})
+
+	// 笔记统计：把 Redis 中缓冲的浏览、收藏、表情回应计数写入数据库
+	s.Every("analytics_flush", cfg.AnalyticsFlushInterval, func(ctx context.Context) error {
+		return svcCtx.Analytics.Flush(ctx)
+	})
+
+	// 阅读历史：清理超过保留期的记录
+	if cfg.HistoryRetention > 0 {
+		s.Every("history_prune", cfg.HistoryPruneInterval, func(ctx context.Context) error {
+			return history.Prune(ctx, svcCtx.DB, time.Now().Add(-cfg.HistoryRetention))
+		})
+	}
+
+	// AI 摘要：按用户设置的频率生成
+	s.Every("ai_digest", cfg.DigestCheckInterval, func(ctx context.Context) error {
+		return svcCtx.Digest.RunDue(ctx, digestUsersPerRun)
+	})
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"note/internal/infra/cache"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// Scheduler 简单的定时任务调度器。
+// 多实例部署时每个实例都会跑调度，执行前用 Redis 锁保证同一周期内只有一个实例真正执行。
+type Scheduler struct {
+	cache  *cache.RedisCache
+	jobs   []job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(cache *cache.RedisCache) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{cache: cache, ctx: ctx, cancel: cancel}
+}
+
+// Every 注册一个每隔 interval 执行一次的任务，interval <= 0 表示不启用
+func (s *Scheduler) Every(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		zap.L().Info("Job disabled", zap.String("job", name))
+		return
+	}
+	s.jobs = append(s.jobs, job{name: name, interval: interval, fn: fn})
+}
+
+// Start 启动所有任务
+func (s *Scheduler) Start() {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Stop 停止调度并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(j job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(j)
+		}
+	}
+}
+
+func (s *Scheduler) run(j job) {
+	if s.cache != nil {
+		// 锁的过期时间略短于周期，保证下个周期能重新抢到
+		locked, err := s.cache.SetNX(s.ctx, "job:lock:"+j.name, "1", j.interval*9/10)
+		if err != nil {
+			zap.L().Warn("Acquire job lock failed", zap.String("job", j.name), zap.Error(err))
+			return
+		}
+		if !locked {
+			return
+		}
+	}
+
+	start := time.Now()
+	if err := j.fn(s.ctx); err != nil {
+		zap.L().Error("Job failed", zap.String("job", j.name), zap.Duration("cost", time.Since(start)), zap.Error(err))
+		return
+	}
+	zap.L().Debug("Job finished", zap.String("job", j.name), zap.Duration("cost", time.Since(start)))
+}
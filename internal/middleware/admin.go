@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"note/config"
+	"note/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware 只允许配置在 ADMIN_USER_IDS 中的用户访问
+func AdminMiddleware(cfg *config.Config) gin.HandlerFunc {
+	admins := make(map[uint]bool, len(cfg.AdminUserIDs))
+	for _, id := range cfg.AdminUserIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserID(c)
+		if err != nil {
+			utils.Error(c, http.StatusUnauthorized, err.Error())
+			c.Abort()
+			return
+		}
+
+		if !admins[userID] {
+			utils.Error(c, http.StatusForbidden, "需要管理员权限")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
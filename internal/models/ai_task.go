@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// AI 任务类型
+const (
+	AITaskGenerateTitle   = "generate_title"
+	AITaskGenerateSummary = "generate_summary"
+	AITaskSuggestTags     = "suggest_tags"
+	AITaskFlashcards      = "generate_flashcards"
+)
+
+// AI 任务状态
+const (
+	AITaskQueued    = "queued"
+	AITaskRunning   = "running"
+	AITaskSucceeded = "succeeded"
+	AITaskFailed    = "failed"
+)
+
+// AITask 一次 AI 任务的执行记录，用户可以查看结果并重试失败的任务
+type AITask struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	NoteID   uint   `json:"note_id" gorm:"not null;index"`
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	Task     string `json:"task" gorm:"size:32"`
+	Status   string `json:"status" gorm:"size:16;index"`
+	Error    string `json:"error,omitempty" gorm:"type:text"`
+	Attempts int    `json:"attempts" gorm:"default:0"`
+	// PromptVersion 产生结果的提示词版本，例如 "title@v1"
+	PromptVersion string `json:"prompt_version,omitempty" gorm:"size:64;index"`
+
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
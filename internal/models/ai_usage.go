@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// AIUsage 按 (用户, 日期, 任务类型) 汇总的 AI 用量
+type AIUsage struct {
+	ID               uint   `json:"-" gorm:"primaryKey"`
+	UserID           uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_usage_user_day_task"`
+	Day              string `json:"day" gorm:"size:10;uniqueIndex:idx_usage_user_day_task"` // 2006-01-02
+	Task             string `json:"task" gorm:"size:32;uniqueIndex:idx_usage_user_day_task"`
+	Calls            int64  `json:"calls" gorm:"default:0"`
+	PromptTokens     int64  `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int64  `json:"completion_tokens" gorm:"default:0"`
+
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (AIUsage) TableName() string {
+	return "ai_usages"
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Collection 用户整理的笔记合集，可以收录自己的笔记和别人的公开笔记
+type Collection struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	UserID      uint   `json:"user_id" gorm:"index"`
+	Name        string `json:"name" gorm:"size:64"`
+	Description string `json:"description" gorm:"size:500"`
+	// IsPrivate 私密合集只有自己能看到，关注者也看不到
+	IsPrivate     bool `json:"is_private" gorm:"default:false"`
+	ItemCount     int  `json:"item_count" gorm:"default:0"`
+	FollowerCount int  `json:"follower_count" gorm:"default:0"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CollectionItem 合集中的一篇笔记，按 Position 从小到大排列。
+// 笔记删除或改为私密后条目保留，展示为不可用
+type CollectionItem struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	CollectionID uint      `json:"-" gorm:"uniqueIndex:idx_collection_note"`
+	NoteID       uint      `json:"note_id" gorm:"uniqueIndex:idx_collection_note;index"`
+	Position     int       `json:"position"`
+	Annotation   string    `json:"annotation" gorm:"size:500"`
+	CreatedAt    time.Time `json:"added_at"`
+}
+
+// CollectionFollow 关注别人的公开合集
+type CollectionFollow struct {
+	ID           uint `json:"-" gorm:"primaryKey"`
+	UserID       uint `gorm:"uniqueIndex:idx_collection_follow"`
+	CollectionID uint `gorm:"uniqueIndex:idx_collection_follow;index"`
+	CreatedAt    time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Digest 一次生成的 AI 摘要，内容保存为用户的一篇私密笔记
+type Digest struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"not null;index"`
+	NoteID        uint      `json:"note_id" gorm:"index"`
+	Frequency     string    `json:"frequency" gorm:"size:8"`
+	PeriodStart   time.Time `json:"period_start"`
+	PeriodEnd     time.Time `json:"period_end"`
+	PromptVersion string    `json:"prompt_version,omitempty" gorm:"size:64"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
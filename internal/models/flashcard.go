@@ -0,0 +1,109 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SM-2 算法参数
+const (
+	initialEaseFactor = 2.5
+	minEaseFactor     = 1.3
+)
+
+// Flashcard 从笔记生成的问答卡片，同时记录作者对这张卡片的复习进度 (SM-2)
+type Flashcard struct {
+	ID     uint `json:"id" gorm:"primaryKey"`
+	NoteID uint `json:"note_id" gorm:"not null;uniqueIndex:idx_note_card"`
+	UserID uint `json:"user_id" gorm:"not null;index:idx_card_due"`
+	// Hash 问题和答案的摘要，重新生成时用来识别没有变化的卡片
+	Hash     string `json:"-" gorm:"size:64;uniqueIndex:idx_note_card"`
+	Question string `json:"question" gorm:"type:text"`
+	Answer   string `json:"answer" gorm:"type:text"`
+
+	EaseFactor  float64   `json:"ease_factor" gorm:"default:2.5"`
+	Interval    int       `json:"interval"` // 距下次复习的天数
+	Repetitions int       `json:"repetitions"`
+	DueAt       time.Time `json:"due_at" gorm:"index:idx_card_due"`
+
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+	PromptVersion  string     `json:"prompt_version,omitempty" gorm:"size:64"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// FlashcardReview 一次复习记录
+type FlashcardReview struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	FlashcardID uint      `json:"flashcard_id" gorm:"not null;index"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	Grade       int       `json:"grade"`
+	Interval    int       `json:"interval"`
+	EaseFactor  float64   `json:"ease_factor"`
+	ReviewedAt  time.Time `json:"reviewed_at" gorm:"autoCreateTime"`
+}
+
+// NewFlashcard 新卡片当天就可以复习
+func NewFlashcard(noteID, userID uint, question, answer string) Flashcard {
+	return Flashcard{
+		NoteID:     noteID,
+		UserID:     userID,
+		Hash:       FlashcardHash(question, answer),
+		Question:   question,
+		Answer:     answer,
+		EaseFactor: initialEaseFactor,
+		DueAt:      time.Now(),
+	}
+}
+
+// FlashcardHash 忽略大小写和空白差异
+func FlashcardHash(question, answer string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	sum := sha256.Sum256([]byte(normalize(question) + "\x00" + normalize(answer)))
+	return hex.EncodeToString(sum[:])
+}
+
+// FlashcardQuestionKey 归一化后的问题：只保留小写的文字和数字，重新生成时问题只改了标点、空白、大小写也算同一张卡片
+func FlashcardQuestionKey(question string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(question) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Review 按 SM-2 根据回忆质量 grade (0-5) 更新复习间隔和下次复习时间。
+// grade < 3 视为没记住，从头开始；记住时间隔依次为 1 天、6 天，之后每次乘以难度系数
+func (f *Flashcard) Review(grade int, now time.Time) {
+	if grade >= 3 {
+		switch f.Repetitions {
+		case 0:
+			f.Interval = 1
+		case 1:
+			f.Interval = 6
+		default:
+			f.Interval = int(math.Round(float64(f.Interval) * f.EaseFactor))
+		}
+		f.Repetitions++
+	} else {
+		f.Repetitions = 0
+		f.Interval = 1
+	}
+
+	q := float64(5 - grade)
+	f.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if f.EaseFactor < minEaseFactor {
+		f.EaseFactor = minEaseFactor
+	}
+
+	f.DueAt = now.AddDate(0, 0, f.Interval)
+	f.LastReviewedAt = &now
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFlashcardReview(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		card         Flashcard
+		grade        int
+		wantInterval int
+		wantReps     int
+		wantEase     float64
+	}{
+		{"first recall", Flashcard{EaseFactor: 2.5}, 4, 1, 1, 2.5},
+		{"second recall", Flashcard{EaseFactor: 2.5, Repetitions: 1, Interval: 1}, 4, 6, 2, 2.5},
+		{"later recall multiplies by ease", Flashcard{EaseFactor: 2.5, Repetitions: 2, Interval: 6}, 5, 15, 3, 2.6},
+		{"hesitant recall lowers ease", Flashcard{EaseFactor: 2.5, Repetitions: 2, Interval: 6}, 3, 15, 3, 2.36},
+		{"forgotten restarts", Flashcard{EaseFactor: 2.5, Repetitions: 5, Interval: 40}, 2, 1, 0, 2.18},
+		{"ease never below minimum", Flashcard{EaseFactor: 1.4, Repetitions: 3, Interval: 10}, 0, 1, 0, minEaseFactor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := tt.card
+			card.Review(tt.grade, now)
+			if card.Interval != tt.wantInterval || card.Repetitions != tt.wantReps {
+				t.Fatalf("interval %d reps %d, want %d %d", card.Interval, card.Repetitions, tt.wantInterval, tt.wantReps)
+			}
+			if math.Abs(card.EaseFactor-tt.wantEase) > 1e-9 {
+				t.Fatalf("ease %v, want %v", card.EaseFactor, tt.wantEase)
+			}
+			if !card.DueAt.Equal(now.AddDate(0, 0, tt.wantInterval)) || card.LastReviewedAt == nil || !card.LastReviewedAt.Equal(now) {
+				t.Fatalf("due %v last reviewed %v", card.DueAt, card.LastReviewedAt)
+			}
+		})
+	}
+}
+
+func TestFlashcardQuestionKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"What is a goroutine?", "what is a  goroutine", true},
+		{"什么是协程？", "什么是协程……?", true},
+		{"What is a goroutine?", "What is a channel?", false},
+	}
+	for _, tt := range tests {
+		if got := FlashcardQuestionKey(tt.a) == FlashcardQuestionKey(tt.b); got != tt.same {
+			t.Fatalf("%q vs %q: same = %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+	if FlashcardHash("Q", "A") != FlashcardHash(" q ", "a") || FlashcardHash("Q", "A") == FlashcardHash("Q", "B") {
+		t.Fatal("FlashcardHash should ignore case and spacing but not content")
+	}
+}
@@ -4,11 +4,12 @@ import "time"
 
 type History struct {
 	ID     uint `gorm:"primaryKey"`
-	UserID uint `gorm:"uniqueIndex:idx_user_note"`
-	NoteID uint `gorm:"uniqueIndex:idx_user_note"`
+	UserID uint `gorm:"uniqueIndex:idx_user_note;index:idx_user_viewed,priority:1"`
+	NoteID uint `gorm:"uniqueIndex:idx_user_note;index"`
 
 	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	// UpdatedAt 最后一次浏览时间：(user_id, updated_at) 用于按时间翻页，单独的索引用于清理过期历史
+	UpdatedAt time.Time `gorm:"autoUpdateTime;index:idx_user_viewed,priority:2;index"`
 }
 
 type HistoryMsg struct {
@@ -1,12 +1,13 @@
 package models
 
 import (
+	"note/internal/dedup"
 	"time"
 )
 
 type Note struct {
 	ID      uint   `json:"id" gorm:"primaryKey"`
-	UserID  uint   `gorm:"index"`
+	UserID  uint   `gorm:"index;index:idx_note_sim_band0,priority:1;index:idx_note_sim_band1,priority:1;index:idx_note_sim_band2,priority:1;index:idx_note_sim_band3,priority:1"`
 	Title   string `json:"title" binding:"required"`
 	Content string `json:"content" binding:"required"`
 
@@ -15,21 +16,77 @@ type Note struct {
 	IsFavorite     bool           `gorm:"default:false;index"`
 	FavoriteCount  int            `gorm:"default:0"`
 	ReactionCounts map[string]int `gorm:"serializer:json;default:'{}'" json:"reaction_counts"`
+	// Points 互动分 (收藏、表情回应、浏览加权)，用于排行榜
+	Points float64 `gorm:"default:0;index" json:"points"`
+	// ViewCount 去重后的浏览数 (不含作者自己)，定时从 Redis 缓冲写入
+	ViewCount int64 `gorm:"default:0" json:"view_count"`
+	// MyReactions 当前用户点过的 emoji，不入库，返回前按读者填充
+	MyReactions []string `gorm:"-" json:"my_reactions,omitempty"`
 
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	Tags      []Tag     `gorm:"many2many:note_tags;"`
 	Summary   string    `json:"summary" gorm:"type:text"`
+	// SimHash 内容指纹，用于查找重复笔记，0 表示还没有计算
+	SimHash uint64 `json:"-" gorm:"index"`
+	// SimBand0-3 指纹切成的 4 段 (见 dedup.Split)，按 (作者, 段) 建索引查找相近的指纹
+	SimBand0 uint16 `json:"-" gorm:"index:idx_note_sim_band0,priority:2"`
+	SimBand1 uint16 `json:"-" gorm:"index:idx_note_sim_band1,priority:2"`
+	SimBand2 uint16 `json:"-" gorm:"index:idx_note_sim_band2,priority:2"`
+	SimBand3 uint16 `json:"-" gorm:"index:idx_note_sim_band3,priority:2"`
 }
 
+// SetSimHash 设置指纹及其分段
+func (n *Note) SetSimHash(hash uint64) {
+	bands := dedup.Split(hash)
+	n.SimHash = hash
+	n.SimBand0, n.SimBand1, n.SimBand2, n.SimBand3 = bands[0], bands[1], bands[2], bands[3]
+}
+
+// SimHashColumns 更新指纹时要写的列，用于 Updates(map)
+func SimHashColumns(hash uint64) map[string]interface{} {
+	bands := dedup.Split(hash)
+	return map[string]interface{}{
+		"sim_hash":  hash,
+		"sim_band0": bands[0],
+		"sim_band1": bands[1],
+		"sim_band2": bands[2],
+		"sim_band3": bands[3],
+	}
+}
+
+// 时间线事件
+const (
+	FeedPost     = "post"     // 发布 (或从私密改为公开)，推送给粉丝
+	FeedRemove   = "remove"   // 删除或改为私密，从粉丝时间线移除
+	FeedFollow   = "follow"   // 新关注，回填作者最近的笔记
+	FeedUnfollow = "unfollow" // 取消关注，移除作者的笔记
+)
+
 type FeedMsg struct {
-	AuthorID uint  `json:"author_id"`
-	NoteID   uint  `json:"note_id"`
-	PostTime int64 `json:"post_time"`
+	// Event 为空等同于 FeedPost (兼容旧消息)
+	Event      string `json:"event,omitempty"`
+	AuthorID   uint   `json:"author_id"`
+	NoteID     uint   `json:"note_id,omitempty"`
+	PostTime   int64  `json:"post_time,omitempty"`
+	FollowerID uint   `json:"follower_id,omitempty"` // 关注/取消关注事件的关注者
 }
 
 // 新增：AI 任务消息结构
 type AITaskMsg struct {
+	TaskID uint   `json:"task_id"` // 对应 models.AITask 记录
 	NoteID uint   `json:"note_id"`
-	Task   string `json:"task"` // "generate_title" 或 "generate_summary"
+	Task   string `json:"task"` // "generate_title"、"generate_summary"、"suggest_tags" 或 "generate_flashcards"
+}
+
+const (
+	VectorSyncDelete  = "delete"
+	VectorSyncPayload = "payload"
+)
+
+// VectorSyncMsg 向量索引同步消息：删除笔记，或者私密/作者变化后同步 payload
+type VectorSyncMsg struct {
+	NoteID  uint   `json:"note_id"`
+	Action  string `json:"action"`  // "delete" 或 "payload"
+	Attempt int    `json:"attempt"` // 已重试次数
 }
@@ -0,0 +1,14 @@
+package models
+
+// NoteDailyStat 笔记每天的浏览数和收藏、表情回应的净增量 (取消收藏或回应会抵扣当天的数)
+type NoteDailyStat struct {
+	ID     uint `json:"-" gorm:"primaryKey"`
+	NoteID uint `json:"note_id" gorm:"uniqueIndex:idx_note_day"`
+	// UserID 笔记作者，用于统计作者的所有笔记
+	UserID uint `json:"-" gorm:"index:idx_author_day"`
+	// Day 服务器本地日期，格式 2006-01-02
+	Day       string `json:"day" gorm:"type:char(10);uniqueIndex:idx_note_day;index:idx_author_day"`
+	Views     int64  `json:"views"`
+	Favorites int64  `json:"favorites"`
+	Reactions int64  `json:"reactions"`
+}
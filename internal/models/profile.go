@@ -13,7 +13,18 @@ type PersonalPage struct {
 
 	CreatedAt string `json:"created_at"`
 
-	Documents []NoteBrief `json:"documents"`
+	Documents   []NoteBrief       `json:"documents"`
+	Collections []CollectionBrief `json:"collections"`
+}
+
+type CollectionBrief struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	IsPrivate     bool   `json:"is_private"`
+	ItemCount     int    `json:"item_count"`
+	FollowerCount int    `json:"follower_count"`
+	UpdatedAt     string `json:"updated_at"`
 }
 
 type NoteBrief struct {
@@ -25,6 +36,10 @@ type NoteBrief struct {
 	IsPinned      bool     `json:"is_pinned"`
 	Tags          []string `json:"tags,omitempty"`
 	UpdatedAt     string   `json:"updated_at"`
+
+	ReactionCounts map[string]int `json:"reaction_counts,omitempty"`
+	// MyReactions 当前读者点过的 emoji
+	MyReactions []string `json:"my_reactions,omitempty"`
 }
 
 type UserBrief struct {
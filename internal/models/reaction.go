@@ -2,11 +2,12 @@ package models
 
 import "time"
 
+// Reaction 同一用户对同一笔记的同一个 emoji 只有一条 (idx_user_note_emoji)
 type Reaction struct {
 	ID     uint   `gorm:"primaryKey"`
-	UserID uint   `gorm:"index"`
-	NoteID uint   `gorm:"index"`
-	Emoji  string `gorm:"size:10"` // 存 "❤️", "👍", "🔥" 等
+	UserID uint   `gorm:"uniqueIndex:idx_user_note_emoji"`
+	NoteID uint   `gorm:"uniqueIndex:idx_user_note_emoji;index:idx_note_emoji"`
+	Emoji  string `gorm:"size:10;uniqueIndex:idx_user_note_emoji;index:idx_note_emoji"` // 存 "❤️", "👍", "🔥" 等
 
 	CreatedAt time.Time
 }
@@ -19,8 +20,6 @@ type ReactionMsg struct {
 	Action string `json:"action"` // "add" or "remove"
 }
 
-// 防止同一用户对同一笔记重复点同一个 emoji
-
 func (Reaction) TableName() string {
 	return "reactions"
 }
@@ -1,14 +1,42 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
 
 type Tag struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_user_tag_name"`
-	Name      string    `json:"name" gorm:"size:64;uniqueIndex:idx_user_tag_name"`
-	Color     string    `json:"color" binding:"required"`
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_user_tag_name"`
+	Name   string `json:"name" gorm:"size:64;uniqueIndex:idx_user_tag_name"`
+	Color  string `json:"color" binding:"required"`
+	// Topic 归一化后的标签名，不同用户同名的标签属于同一个公开话题
+	Topic     string    `json:"topic" gorm:"size:64;index"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 
 	Notes []Note `gorm:"many2many:note_tags;"`
 }
+
+// TopicName 标签名归一化为话题名：全角转半角、转小写、去掉开头的 #，空白和下划线统一为 "-"
+func TopicName(name string) string {
+	name = strings.ToLower(width.Fold.String(strings.TrimSpace(name)))
+	name = strings.TrimLeft(name, "#")
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_' || r == '-'
+	})
+	return strings.Join(fields, "-")
+}
+
+// NoteTag 笔记和标签的关联表 (由 Note.Tags 的 many2many 建表)，批量打标签时直接写这张表
+type NoteTag struct {
+	NoteID uint `gorm:"primaryKey"`
+	TagID  uint `gorm:"primaryKey"`
+}
+
+func (NoteTag) TableName() string {
+	return "note_tags"
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	SuggestionPending  = "pending"
+	SuggestionAccepted = "accepted"
+	SuggestionRejected = "rejected"
+)
+
+// TagSuggestion AI 为笔记建议的标签，等待用户接受或拒绝
+type TagSuggestion struct {
+	ID     uint `json:"id" gorm:"primaryKey"`
+	NoteID uint `json:"note_id" gorm:"not null;uniqueIndex:idx_note_suggestion"`
+	UserID uint `json:"user_id" gorm:"not null;index"`
+	// TagID 建议的是已有标签时指向它，AI 新提出的标签为空，接受时才创建
+	TagID  *uint  `json:"tag_id"`
+	Name   string `json:"name" gorm:"size:64;uniqueIndex:idx_note_suggestion"`
+	Status string `json:"status" gorm:"size:16;default:pending;index"`
+	// PromptVersion 产生这条建议的提示词版本，用于比较不同版本的接受率
+	PromptVersion string `json:"prompt_version,omitempty" gorm:"size:64"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
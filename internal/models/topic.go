@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Topic 根据笔记向量自动聚类出来的主题
+type Topic struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	Name   string `json:"name" gorm:"size:64"`
+	Size   int    `json:"size"`
+	// Centroid 簇中心 (已归一化)，新笔记按它就近归入主题
+	Centroid []float32 `json:"-" gorm:"serializer:json;type:mediumtext"`
+	// TagID 用户把主题转成标签后指向该标签
+	TagID         *uint  `json:"tag_id"`
+	PromptVersion string `json:"prompt_version,omitempty" gorm:"size:64"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// NoteTopic 笔记所属的主题，一篇笔记最多属于一个主题
+type NoteTopic struct {
+	NoteID     uint    `json:"note_id" gorm:"primaryKey"`
+	TopicID    uint    `json:"topic_id" gorm:"not null;index"`
+	UserID     uint    `json:"user_id" gorm:"not null;index"`
+	Similarity float32 `json:"similarity"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// TopicFollow 用户关注的公开话题，话题下的新笔记会出现在关注动态里
+type TopicFollow struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"uniqueIndex:idx_topic_follow"`
+	Topic     string    `json:"topic" gorm:"size:64;uniqueIndex:idx_topic_follow;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserBlock 屏蔽关系：屏蔽者不会再在推荐中看到被屏蔽者的笔记
+type UserBlock struct {
+	ID        uint `gorm:"primaryKey"`
+	BlockerID uint `gorm:"uniqueIndex:idx_block_relation"`
+	BlockedID uint `gorm:"uniqueIndex:idx_block_relation"`
+	CreatedAt time.Time
+}
+
+func (UserBlock) TableName() string {
+	return "user_blocks"
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// 摘要频率
+const (
+	DigestOff    = ""
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// 摘要可以包含的内容
+const (
+	DigestSectionNotes    = "notes"    // 这段时间新建/修改的笔记
+	DigestSectionActivity = "activity" // 公开笔记收到的收藏和表情
+)
+
+// UserSetting 用户偏好设置，没有记录时使用默认值
+type UserSetting struct {
+	UserID uint `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	// AutoApplyTags AI 建议的已有标签直接打到笔记上，不需要手动确认
+	AutoApplyTags bool `json:"auto_apply_tags" gorm:"default:false"`
+	// AILanguage AI 生成标题/摘要/标签使用的语言 (zh/en/ja)，空表示中文
+	AILanguage string `json:"ai_language" gorm:"size:8"`
+	// AIStyle AI 生成内容的风格，例如 "简洁"、"正式"
+	AIStyle string `json:"ai_style" gorm:"size:32"`
+
+	// PauseHistory 暂停记录阅读历史，已有的历史保留
+	PauseHistory bool `json:"pause_history" gorm:"default:false"`
+
+	// DigestFrequency AI 摘要的频率，空表示不生成
+	DigestFrequency string `json:"digest_frequency" gorm:"size:8;index"`
+	// DigestSections 摘要包含的内容，空表示全部
+	DigestSections []string   `json:"digest_sections" gorm:"serializer:json;type:varchar(128)"`
+	LastDigestAt   *time.Time `json:"last_digest_at,omitempty"`
+	// DigestFailures 连续生成失败的次数，DigestRetryAt 之前不再尝试
+	DigestFailures int        `json:"-" gorm:"default:0"`
+	DigestRetryAt  *time.Time `json:"-"`
+
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,179 @@
+package note
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"note/internal/infra/ai"
+	"note/internal/models"
+	"note/internal/utils"
+	"note/internal/validators"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var errAIQueueUnavailable = errors.New("AI 服务暂不可用")
+
+const aiTaskStaleAfter = 10 * time.Minute
+
+// ListAITasks 查看笔记最近的 AI 任务
+func (h *NoteHandler) ListAITasks(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var tasks []models.AITask
+	err = h.svc.DB.Where("note_id = ? AND user_id = ?", c.Param("id"), userID).
+		Order("id DESC").
+		Limit(50).
+		Find(&tasks).Error
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	utils.Success(c, tasks)
+}
+
+// CreateAITask 为已有笔记触发一个 AI 任务
+func (h *NoteHandler) CreateAITask(c *gin.Context) {
+	var req validators.CreateAITaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "不支持的任务类型")
+		return
+	}
+
+	h.triggerAITask(c, req.Task)
+}
+
+// RetryAITask 重新执行一个失败的任务
+func (h *NoteHandler) RetryAITask(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if h.svc.Rabbit == nil {
+		utils.Error(c, http.StatusServiceUnavailable, errAIQueueUnavailable.Error())
+		return
+	}
+
+	var task models.AITask
+	err = h.svc.DB.Where("id = ? AND note_id = ? AND user_id = ?", c.Param("tid"), c.Param("id"), userID).
+		First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "任务不存在")
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+
+	// 条件更新，避免并发重试把同一个任务投递两次
+	result := h.svc.DB.Model(&task).
+		Where("status = ?", models.AITaskFailed).
+		Updates(map[string]interface{}{"status": models.AITaskQueued, "error": ""})
+	if result.Error != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.Error(c, http.StatusConflict, "只能重试失败的任务")
+		return
+	}
+
+	if err := h.publishAITask(&task); err != nil {
+		utils.Error(c, http.StatusInternalServerError, "提交任务失败")
+		return
+	}
+
+	task.Status = models.AITaskQueued
+	task.Error = ""
+	utils.Success(c, task)
+}
+
+// triggerAITask 同一篇笔记同类任务还在排队或执行时不重复提交
+func (h *NoteHandler) triggerAITask(c *gin.Context, taskType string) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	noteID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+	if err := h.svc.Usage.Allow(c, userID); errors.Is(err, ai.ErrQuotaExceeded) {
+		utils.Error(c, http.StatusTooManyRequests, "AI 额度已用完")
+		return
+	}
+
+	// 超过 aiTaskStaleAfter 还没结束的任务视为丢失 (例如消费者重启)，允许重新提交
+	var pending int64
+	h.svc.DB.Model(&models.AITask{}).
+		Where("note_id = ? AND task = ? AND status IN ?", noteID, taskType, []string{models.AITaskQueued, models.AITaskRunning}).
+		Where("updated_at > ?", time.Now().Add(-aiTaskStaleAfter)).
+		Count(&pending)
+	if pending > 0 {
+		utils.Error(c, http.StatusConflict, "任务正在进行中")
+		return
+	}
+
+	task, err := h.sendAITask(userID, uint(noteID), taskType)
+	if err != nil {
+		if errors.Is(err, errAIQueueUnavailable) {
+			utils.Error(c, http.StatusServiceUnavailable, err.Error())
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "提交任务失败")
+		}
+		return
+	}
+
+	utils.Success(c, task)
+}
+
+// sendAITask 记录任务并投递到 ai_queue
+func (h *NoteHandler) sendAITask(userID, noteID uint, taskType string) (*models.AITask, error) {
+	if h.svc.Rabbit == nil {
+		return nil, errAIQueueUnavailable
+	}
+
+	task := models.AITask{
+		NoteID: noteID,
+		UserID: userID,
+		Task:   taskType,
+		Status: models.AITaskQueued,
+	}
+	if err := h.svc.DB.Create(&task).Error; err != nil {
+		zap.L().Error("Create AI task failed", zap.Uint("note_id", noteID), zap.Error(err))
+		return nil, err
+	}
+
+	if err := h.publishAITask(&task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (h *NoteHandler) publishAITask(task *models.AITask) error {
+	msg := models.AITaskMsg{
+		TaskID: task.ID,
+		NoteID: task.NoteID,
+		Task:   task.Task,
+	}
+	body, _ := json.Marshal(msg)
+	if err := h.svc.Rabbit.Publish("ai_queue", body); err != nil {
+		// 没投递出去就直接标记失败，用户可以重试
+		h.svc.DB.Model(task).Updates(map[string]interface{}{
+			"status": models.AITaskFailed,
+			"error":  "publish failed: " + err.Error(),
+		})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package note
+
+import (
+	"net/http"
+	"note/internal/analytics"
+	"note/internal/models"
+	"note/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+	// 作者统计中列出的热门笔记数
+	topNotesLimit = 10
+)
+
+// DailyStat 一天的统计，收藏和表情回应为当天的净增量
+type DailyStat struct {
+	Day       string `json:"day"`
+	Views     int64  `json:"views"`
+	Favorites int64  `json:"favorites"`
+	Reactions int64  `json:"reactions"`
+}
+
+// TopNote 统计区间内浏览最多的笔记
+type TopNote struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	Views     int64  `json:"views"`
+	Favorites int64  `json:"favorites"`
+	Reactions int64  `json:"reactions"`
+}
+
+// analyticsDays 读取统计的天数 (含今天)，返回起始日期
+func analyticsDays(c *gin.Context) (int, string, bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultAnalyticsDays)))
+	if err != nil || days < 1 || days > maxAnalyticsDays {
+		utils.Error(c, http.StatusBadRequest, "无效的天数")
+		return 0, "", false
+	}
+	return days, time.Now().AddDate(0, 0, 1-days).Format(analytics.DayLayout), true
+}
+
+// dailySeries 按天汇总，没有数据的日期补 0
+func dailySeries(query *gorm.DB, days int) ([]DailyStat, error) {
+	var rows []DailyStat
+	err := query.Select("day, SUM(views) AS views, SUM(favorites) AS favorites, SUM(reactions) AS reactions").
+		Group("day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]DailyStat, len(rows))
+	for _, r := range rows {
+		byDay[r.Day] = r
+	}
+
+	series := make([]DailyStat, days)
+	start := time.Now().AddDate(0, 0, 1-days)
+	for i := range series {
+		day := start.AddDate(0, 0, i).Format(analytics.DayLayout)
+		series[i] = DailyStat{Day: day}
+		if r, ok := byDay[day]; ok {
+			series[i] = r
+		}
+	}
+	return series, nil
+}
+
+// GetMyAnalytics 作者所有笔记最近 days 天的流量：每天的浏览、收藏、表情回应，以及浏览最多的笔记。
+// 计数定时从缓冲写入，最近一两分钟的数据可能还没有计入
+func (h *NoteHandler) GetMyAnalytics(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	days, since, ok := analyticsDays(c)
+	if !ok {
+		return
+	}
+
+	stats := func() *gorm.DB {
+		return h.svc.DB.Model(&models.NoteDailyStat{}).
+			Where("note_daily_stats.user_id = ? AND note_daily_stats.day >= ?", userID, since)
+	}
+
+	series, err := dailySeries(stats(), days)
+	if err != nil {
+		zap.L().Error("Query author analytics failed", zap.Uint("uid", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取统计失败")
+		return
+	}
+	var total DailyStat
+	for _, d := range series {
+		total.Views += d.Views
+		total.Favorites += d.Favorites
+		total.Reactions += d.Reactions
+	}
+
+	topNotes := []TopNote{}
+	err = stats().
+		Select("notes.id, notes.title, SUM(note_daily_stats.views) AS views, SUM(note_daily_stats.favorites) AS favorites, SUM(note_daily_stats.reactions) AS reactions").
+		Joins("JOIN notes ON notes.id = note_daily_stats.note_id").
+		Group("notes.id, notes.title").
+		Order("views DESC, favorites DESC, notes.id DESC").
+		Limit(topNotesLimit).
+		Scan(&topNotes).Error
+	if err != nil {
+		zap.L().Error("Query top notes failed", zap.Uint("uid", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取统计失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"days":      days,
+		"views":     total.Views,
+		"favorites": total.Favorites,
+		"reactions": total.Reactions,
+		"series":    series,
+		"top_notes": topNotes,
+	})
+}
+
+// GetNoteAnalytics 单篇笔记的累计数据和最近 days 天的每日统计 (仅作者)
+func (h *NoteHandler) GetNoteAnalytics(c *gin.Context) {
+	noteID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	days, since, ok := analyticsDays(c)
+	if !ok {
+		return
+	}
+
+	var note models.Note
+	if err := h.svc.DB.Select("id, favorite_count, reaction_counts, view_count").First(&note, noteID).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+	reactions := 0
+	for _, n := range note.ReactionCounts {
+		reactions += n
+	}
+
+	series, err := dailySeries(h.svc.DB.Model(&models.NoteDailyStat{}).Where("note_id = ? AND day >= ?", noteID, since), days)
+	if err != nil {
+		zap.L().Error("Query note analytics failed", zap.Uint64("nid", noteID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取统计失败")
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"note_id":   note.ID,
+		"views":     note.ViewCount,
+		"favorites": note.FavoriteCount,
+		"reactions": reactions,
+		"series":    series,
+	})
+}
@@ -0,0 +1,144 @@
+package note
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"note/internal/infra/ai"
+	"note/internal/models"
+	"note/internal/utils"
+	"note/internal/validators"
+	"unicode/utf16"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AssistPatch 写作助手给出的修改建议，客户端确认后自行替换 [Start, End) 区间的文本 (续写时 Start == End，即插入)。
+// Start/End 和请求一样是 UTF-16 下标；选区超过 AI_MAX_INPUT_RUNES 时只处理前面一段，Truncated 为 true，End 为实际处理到的位置
+type AssistPatch struct {
+	Action        string `json:"action"`
+	Start         int    `json:"start"`
+	End           int    `json:"end"`
+	Truncated     bool   `json:"truncated"`
+	Original      string `json:"original"`
+	Replacement   string `json:"replacement"`
+	PromptVersion string `json:"prompt_version"`
+}
+
+// runeIndex 把 UTF-16 下标换算成 rune 下标，下标落在代理对中间或超出文本长度时返回 false
+func runeIndex(runes []rune, offset int) (int, bool) {
+	units := 0
+	for i, r := range runes {
+		if units == offset {
+			return i, true
+		}
+		if units > offset {
+			return 0, false
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(runes), units == offset
+}
+
+// utf16Len rune 切片按 UTF-16 编码的长度
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// Assist 对笔记全文或选中的片段执行写作助手操作，只返回建议，不修改笔记
+func (h *NoteHandler) Assist(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req validators.AssistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+	if (req.Action == ai.AssistTone && req.Tone == "") || (req.Action == ai.AssistTranslate && req.TargetLanguage == "") {
+		utils.Error(c, http.StatusBadRequest, "请指定目标语气或语言")
+		return
+	}
+
+	var note models.Note
+	if err := h.svc.DB.Select("id, content").First(&note, c.Param("id")).Error; err != nil {
+		utils.Error(c, http.StatusNotFound, "note not found")
+		return
+	}
+
+	runes := []rune(note.Content)
+	start, end := 0, len(runes)
+	if req.Start != nil || req.End != nil {
+		var okStart, okEnd bool
+		if req.Start != nil && req.End != nil {
+			start, okStart = runeIndex(runes, *req.Start)
+			end, okEnd = runeIndex(runes, *req.End)
+		}
+		if !okStart || !okEnd || start > end {
+			utils.Error(c, http.StatusBadRequest, "选区无效")
+			return
+		}
+	}
+	truncated := false
+	if req.Action == ai.AssistContinue {
+		// 续写以选区 (默认全文) 之前的内容为上下文，在选区末尾插入
+		start = 0
+	} else if start == end {
+		utils.Error(c, http.StatusBadRequest, "选中的内容为空")
+		return
+	} else if limit := h.svc.Config.AIMaxInputRunes; limit > 0 && end-start > limit {
+		// 选区过长时只处理前 limit 个字符，客户端按返回的区间替换
+		end, truncated = start+limit, true
+	}
+	text := string(runes[start:end])
+
+	var setting models.UserSetting
+	if err := h.svc.DB.Where("user_id = ?", userID).First(&setting).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		zap.L().Warn("Load user setting failed", zap.Uint("uid", userID), zap.Error(err))
+	}
+
+	gen, err := h.svc.AI.Assist(userID, ai.AssistRequest{
+		Action:         req.Action,
+		Text:           text,
+		Tone:           req.Tone,
+		TargetLanguage: req.TargetLanguage,
+	}, ai.Preferences{Language: setting.AILanguage, Style: setting.AIStyle})
+	if err != nil {
+		switch {
+		case errors.Is(err, ai.ErrQuotaExceeded):
+			utils.Error(c, http.StatusTooManyRequests, "AI 额度已用完")
+		case errors.Is(err, ai.ErrInputTooLong):
+			utils.Error(c, http.StatusRequestEntityTooLarge, "选中的内容过长，请分段处理")
+		case errors.Is(err, context.DeadlineExceeded):
+			utils.Error(c, http.StatusGatewayTimeout, "AI 服务超时")
+		default:
+			zap.L().Error("AI assist failed", zap.String("action", req.Action), zap.Error(err))
+			utils.Error(c, http.StatusInternalServerError, "AI 服务繁忙")
+		}
+		return
+	}
+
+	patch := AssistPatch{
+		Action:        req.Action,
+		Start:         utf16Len(runes[:start]),
+		End:           utf16Len(runes[:end]),
+		Truncated:     truncated,
+		Original:      text,
+		Replacement:   gen.Content,
+		PromptVersion: gen.PromptVersion,
+	}
+	if req.Action == ai.AssistContinue {
+		patch.Start = patch.End
+		patch.Original = ""
+	}
+	utils.Success(c, patch)
+}
@@ -0,0 +1,109 @@
+package note
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"note/config"
+	"note/internal/infra/ai"
+	"note/internal/models"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRuneIndex(t *testing.T) {
+	// "a😀中b"：😀 在 UTF-16 中占两个单元
+	runes := []rune("a😀中b")
+	tests := []struct {
+		offset int
+		want   int
+		ok     bool
+	}{
+		{0, 0, true},
+		{1, 1, true},
+		{2, 0, false}, // 代理对中间
+		{3, 2, true},
+		{4, 3, true},
+		{5, 4, true},
+		{6, 0, false}, // 超出长度
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.offset), func(t *testing.T) {
+			got, ok := runeIndex(runes, tt.offset)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Fatalf("runeIndex(%d) = %d, %v, want %d, %v", tt.offset, got, ok, tt.want, tt.ok)
+			}
+			if ok && utf16Len(runes[:got]) != tt.offset {
+				t.Fatalf("utf16Len(runes[:%d]) = %d, want %d", got, utf16Len(runes[:got]), tt.offset)
+			}
+		})
+	}
+}
+
+func TestAssistRejectsInvalidSelection(t *testing.T) {
+	db := testutil.DB(t, &models.Note{})
+	h := NewNoteHandler(&svc.ServiceContext{Config: &config.Config{AIMaxInputRunes: 100}, DB: db})
+	note := models.Note{UserID: 1, Title: "t", Content: "a😀中b"}
+	db.Create(&note)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"inside a surrogate pair", `{"action":"rewrite","start":0,"end":2}`},
+		{"past the end", `{"action":"rewrite","start":0,"end":6}`},
+		{"only start", `{"action":"rewrite","start":1}`},
+		{"reversed", `{"action":"rewrite","start":4,"end":3}`},
+		{"empty", `{"action":"rewrite","start":3,"end":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := testutil.JSONContext(http.MethodPost, "/notes/1/assist", tt.body, 1)
+			c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(note.ID)}}
+			h.Assist(c)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want 400: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestAssistTruncatesLongSelection(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.UserSetting{})
+	cfg := &config.Config{
+		AIChatProvider:  ai.ProviderLocal,
+		AIEmbedProvider: ai.ProviderLocal,
+		AIEmbedDim:      8,
+		AIChatTimeout:   time.Second,
+		AIMaxInputRunes: 3,
+	}
+	aiService, err := ai.NewAIService(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewNoteHandler(&svc.ServiceContext{Config: cfg, DB: db, AI: aiService})
+	note := models.Note{UserID: 1, Title: "t", Content: "x😀中文字y"}
+	db.Create(&note)
+
+	// 选中 "😀中文字"，UTF-16 下标 [1, 6)，只处理前 3 个字符 "😀中文"
+	c, w := testutil.JSONContext(http.MethodPost, "/notes/1/assist", `{"action":"rewrite","start":1,"end":6}`, 1)
+	c.Params = gin.Params{{Key: "id", Value: fmt.Sprint(note.ID)}}
+	h.Assist(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data AssistPatch `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	got := resp.Data
+	if got.Start != 1 || got.End != 5 || !got.Truncated || got.Original != "😀中文" {
+		t.Fatalf("patch = %+v", got)
+	}
+}
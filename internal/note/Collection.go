@@ -0,0 +1,593 @@
+package note
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/utils"
+	"note/internal/validators"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 每个合集最多收录的笔记数
+const maxCollectionItems = 500
+
+// 合集相关列表的游标排序标识，合集内容的游标还会带上合集 ID
+const (
+	collectionItemsSort     = "collection_items:%d"
+	followedCollectionsSort = "followed_collections"
+)
+
+// CollectionItemView 合集中的一条，笔记已删除或对当前用户不可见时 Available 为 false、Note 为空
+type CollectionItemView struct {
+	models.CollectionItem
+	Available bool              `json:"available"`
+	Note      *models.NoteBrief `json:"note,omitempty"`
+}
+
+// loadCollection 读取路径中的合集；别人的私密合集视为不存在，ownerOnly 时只有创建者可以操作
+func (h *NoteHandler) loadCollection(c *gin.Context, userID uint, ownerOnly bool) (*models.Collection, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的合集ID")
+		return nil, false
+	}
+
+	var collection models.Collection
+	if err := h.svc.DB.First(&collection, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "合集不存在")
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "database error")
+		}
+		return nil, false
+	}
+	isOwner := collection.UserID == userID
+	if collection.IsPrivate && !isOwner {
+		utils.Error(c, http.StatusNotFound, "合集不存在")
+		return nil, false
+	}
+	if ownerOnly && !isOwner {
+		utils.Error(c, http.StatusForbidden, "你没有权限操作这个合集")
+		return nil, false
+	}
+	return &collection, true
+}
+
+// CreateCollection 新建合集
+func (h *NoteHandler) CreateCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	var req validators.CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.Error(c, http.StatusBadRequest, "合集名称不能为空")
+		return
+	}
+
+	collection := models.Collection{
+		UserID:      userID,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		IsPrivate:   req.IsPrivate,
+	}
+	if err := h.svc.DB.Create(&collection).Error; err != nil {
+		zap.L().Error("Create collection failed", zap.Uint("uid", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "创建合集失败")
+		return
+	}
+	utils.Success(c, collection)
+}
+
+// GetCollection 合集详情，包括创建者和当前用户是否关注
+func (h *NoteHandler) GetCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, false)
+	if !ok {
+		return
+	}
+
+	var owner models.UserBrief
+	h.svc.DB.Model(&models.User{}).
+		Select("id, username, avatar, bio").
+		Where("id = ?", collection.UserID).
+		Scan(&owner)
+	var following int64
+	h.svc.DB.Model(&models.CollectionFollow{}).
+		Where("user_id = ? AND collection_id = ?", userID, collection.ID).
+		Count(&following)
+
+	utils.Success(c, gin.H{
+		"collection":   collection,
+		"owner":        owner,
+		"is_following": following > 0,
+	})
+}
+
+// UpdateCollection 修改合集名称、简介或可见性
+func (h *NoteHandler) UpdateCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, true)
+	if !ok {
+		return
+	}
+	var req validators.UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			utils.Error(c, http.StatusBadRequest, "合集名称不能为空")
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.IsPrivate != nil {
+		updates["is_private"] = *req.IsPrivate
+	}
+	if len(updates) > 0 {
+		if err := h.svc.DB.Model(collection).Updates(updates).Error; err != nil {
+			zap.L().Error("Update collection failed", zap.Uint("cid", collection.ID), zap.Error(err))
+			utils.Error(c, http.StatusInternalServerError, "更新合集失败")
+			return
+		}
+		h.svc.DB.First(collection, collection.ID)
+	}
+	utils.Success(c, collection)
+}
+
+// DeleteCollection 删除合集及其条目和关注
+func (h *NoteHandler) DeleteCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, true)
+	if !ok {
+		return
+	}
+
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionFollow{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(collection).Error
+	})
+	if err != nil {
+		zap.L().Error("Delete collection failed", zap.Uint("cid", collection.ID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "删除合集失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListCollectionItems 合集中的笔记，按合集中的顺序分页
+func (h *NoteHandler) ListCollectionItems(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, false)
+	if !ok {
+		return
+	}
+	sort := fmt.Sprintf(collectionItemsSort, collection.ID)
+	cursor, limit, err := h.svc.Pager.Parse(c, sort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	query := h.svc.DB.Where("collection_id = ?", collection.ID)
+	if cursor != nil {
+		query = query.Where("position > ? OR (position = ? AND id > ?)", cursor.Score, cursor.Score, cursor.ID)
+	}
+	var items []models.CollectionItem
+	if err := query.Order("position ASC, id ASC").Limit(limit + 1).Find(&items).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "获取合集内容失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		next = &pagination.Cursor{Sort: sort, Score: int64(last.Position), ID: last.ID}
+	}
+
+	noteIDs := make([]uint, len(items))
+	for i, item := range items {
+		noteIDs[i] = item.NoteID
+	}
+	var notes []models.Note
+	if len(noteIDs) > 0 {
+		err := h.svc.DB.Preload("Tags").
+			Select("id, user_id, title, summary, is_private, is_pinned, favorite_count, reaction_counts, updated_at").
+			Where("id IN ?", noteIDs).
+			Where("is_private = ? OR user_id = ?", false, userID).
+			Find(&notes).Error
+		if err != nil {
+			utils.Error(c, http.StatusInternalServerError, "获取合集内容失败")
+			return
+		}
+	}
+	h.attachMyReactions(userID, notes)
+	noteMap := make(map[uint]models.Note, len(notes))
+	for _, n := range notes {
+		noteMap[n.ID] = n
+	}
+
+	views := make([]CollectionItemView, len(items))
+	for i, item := range items {
+		views[i] = CollectionItemView{CollectionItem: item}
+		n, ok := noteMap[item.NoteID]
+		if !ok {
+			continue
+		}
+		var tagNames []string
+		for _, t := range n.Tags {
+			tagNames = append(tagNames, t.Name)
+		}
+		views[i].Available = true
+		views[i].Note = &models.NoteBrief{
+			ID:            n.ID,
+			Title:         n.Title,
+			Summary:       n.Summary,
+			FavoriteCount: n.FavoriteCount,
+			IsPrivate:     n.IsPrivate,
+			IsPinned:      n.IsPinned,
+			Tags:          tagNames,
+			UpdatedAt:     n.UpdatedAt.Format("2006-01-02"),
+
+			ReactionCounts: n.ReactionCounts,
+			MyReactions:    n.MyReactions,
+		}
+	}
+	utils.Success(c, h.svc.Pager.Page(views, next))
+}
+
+// AddCollectionItem 把自己的笔记或别人的公开笔记加到合集末尾
+func (h *NoteHandler) AddCollectionItem(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, true)
+	if !ok {
+		return
+	}
+	var req validators.AddCollectionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	var visible int64
+	h.svc.DB.Model(&models.Note{}).
+		Where("id = ? AND (is_private = ? OR user_id = ?)", req.NoteID, false, userID).
+		Count(&visible)
+	if visible == 0 {
+		utils.Error(c, http.StatusNotFound, "笔记不存在")
+		return
+	}
+
+	item := models.CollectionItem{
+		CollectionID: collection.ID,
+		NoteID:       req.NoteID,
+		Annotation:   strings.TrimSpace(req.Annotation),
+	}
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁住合集，并发添加时位置和计数不会错乱
+		var locked models.Collection
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, collection.ID).Error; err != nil {
+			return err
+		}
+		// 合集已加锁，这里查到的就是最新状态；唯一索引只作兜底
+		var exists int64
+		if err := tx.Model(&models.CollectionItem{}).
+			Where("collection_id = ? AND note_id = ?", collection.ID, req.NoteID).
+			Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists > 0 {
+			return errAlreadyInCollection
+		}
+		if locked.ItemCount >= maxCollectionItems {
+			return errCollectionFull
+		}
+		var last struct{ Position int }
+		if err := tx.Model(&models.CollectionItem{}).
+			Select("COALESCE(MAX(position), 0) AS position").
+			Where("collection_id = ?", collection.ID).
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		item.Position = last.Position + 1
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		return tx.Model(&locked).Updates(map[string]interface{}{
+			"item_count": gorm.Expr("item_count + 1"),
+			"updated_at": time.Now(),
+		}).Error
+	})
+	switch {
+	case errors.Is(err, errCollectionFull):
+		utils.Error(c, http.StatusBadRequest, "合集中的笔记已达上限")
+		return
+	case errors.Is(err, errAlreadyInCollection), errors.Is(err, gorm.ErrDuplicatedKey):
+		utils.Error(c, http.StatusConflict, "笔记已在合集中")
+		return
+	case err != nil:
+		zap.L().Error("Add collection item failed", zap.Uint("cid", collection.ID), zap.Uint("nid", req.NoteID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "添加失败")
+		return
+	}
+	utils.Success(c, item)
+}
+
+var (
+	errCollectionFull      = errors.New("collection is full")
+	errAlreadyInCollection = errors.New("note already in collection")
+)
+
+// UpdateCollectionItem 修改条目的注释
+func (h *NoteHandler) UpdateCollectionItem(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, true)
+	if !ok {
+		return
+	}
+	var req validators.UpdateCollectionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	result := h.svc.DB.Model(&models.CollectionItem{}).
+		Where("collection_id = ? AND note_id = ?", collection.ID, c.Param("nid")).
+		Update("annotation", strings.TrimSpace(*req.Annotation))
+	if result.Error != nil {
+		utils.Error(c, http.StatusInternalServerError, "更新失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.Error(c, http.StatusNotFound, "笔记不在合集中")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// RemoveCollectionItem 从合集中移除笔记
+func (h *NoteHandler) RemoveCollectionItem(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, true)
+	if !ok {
+		return
+	}
+
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("collection_id = ? AND note_id = ?", collection.ID, c.Param("nid")).Delete(&models.CollectionItem{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(collection).Updates(map[string]interface{}{
+			"item_count": gorm.Expr("GREATEST(item_count - 1, 0)"),
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		zap.L().Error("Remove collection item failed", zap.Uint("cid", collection.ID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "移除失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ReorderCollection 按给出的笔记顺序重排合集，需要包含合集中的全部笔记
+func (h *NoteHandler) ReorderCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, true)
+	if !ok {
+		return
+	}
+	var req validators.ReorderCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	var current []uint
+	if err := h.svc.DB.Model(&models.CollectionItem{}).
+		Where("collection_id = ?", collection.ID).
+		Pluck("note_id", &current).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+	inCollection := make(map[uint]bool, len(current))
+	for _, id := range current {
+		inCollection[id] = true
+	}
+	seen := make(map[uint]bool, len(req.NoteIDs))
+	for _, id := range req.NoteIDs {
+		if !inCollection[id] || seen[id] {
+			utils.Error(c, http.StatusBadRequest, "笔记列表和合集内容不一致")
+			return
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(current) {
+		utils.Error(c, http.StatusBadRequest, "笔记列表和合集内容不一致")
+		return
+	}
+
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.NoteIDs {
+			if err := tx.Model(&models.CollectionItem{}).
+				Where("collection_id = ? AND note_id = ?", collection.ID, id).
+				Update("position", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(collection).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		zap.L().Error("Reorder collection failed", zap.Uint("cid", collection.ID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "排序失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// FollowCollection 关注别人的公开合集
+func (h *NoteHandler) FollowCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collection, ok := h.loadCollection(c, userID, false)
+	if !ok {
+		return
+	}
+	if collection.UserID == userID {
+		utils.Error(c, http.StatusBadRequest, "不能关注自己的合集")
+		return
+	}
+
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.CollectionFollow{UserID: userID, CollectionID: collection.ID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(collection).UpdateColumn("follower_count", gorm.Expr("follower_count + 1")).Error
+	})
+	if err != nil {
+		zap.L().Error("Follow collection failed", zap.Uint("uid", userID), zap.Uint("cid", collection.ID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "关注失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// UnfollowCollection 取消关注合集；合集已改为私密时也可以取消
+func (h *NoteHandler) UnfollowCollection(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	collectionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的合集ID")
+		return
+	}
+
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND collection_id = ?", userID, collectionID).Delete(&models.CollectionFollow{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Collection{}).Where("id = ?", collectionID).
+			UpdateColumn("follower_count", gorm.Expr("GREATEST(follower_count - 1, 0)")).Error
+	})
+	if err != nil {
+		zap.L().Error("Unfollow collection failed", zap.Uint("uid", userID), zap.Uint64("cid", collectionID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "取消关注失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListFollowedCollections 我关注的合集，按关注时间倒序；已改为私密的合集不显示
+func (h *NoteHandler) ListFollowedCollections(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	cursor, limit, err := h.svc.Pager.Parse(c, followedCollectionsSort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	query := h.svc.DB.Table("collections").
+		Select("collections.*, collection_follows.id AS follow_id").
+		Joins("JOIN collection_follows ON collection_follows.collection_id = collections.id").
+		Where("collection_follows.user_id = ? AND collections.is_private = ?", userID, false)
+	if cursor != nil {
+		query = query.Where("collection_follows.id < ?", cursor.ID)
+	}
+
+	var rows []struct {
+		models.Collection
+		FollowID uint
+	}
+	if err := query.Order("collection_follows.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		zap.L().Error("List followed collections failed", zap.Uint("uid", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取列表失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &pagination.Cursor{Sort: followedCollectionsSort, ID: rows[limit-1].FollowID}
+	}
+	collections := make([]models.Collection, len(rows))
+	for i, r := range rows {
+		collections[i] = r.Collection
+	}
+	utils.Success(c, h.svc.Pager.Page(collections, next))
+}
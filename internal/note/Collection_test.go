@@ -0,0 +1,57 @@
+package note
+
+import (
+	"net/http"
+	"note/config"
+	"note/internal/models"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCollectionItems(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.Collection{}, &models.CollectionItem{})
+	h := NewNoteHandler(&svc.ServiceContext{Config: &config.Config{}, DB: db})
+
+	const owner uint = 3
+	collection := models.Collection{UserID: owner, Name: "reading"}
+	db.Create(&collection)
+	note := models.Note{UserID: owner, Title: "t", Content: "c"}
+	db.Create(&note)
+
+	call := func(handler gin.HandlerFunc, method, body string) int {
+		c, w := testutil.JSONContext(method, "/collections/x", body, owner)
+		c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(collection.ID))}}
+		handler(c)
+		return w.Code
+	}
+
+	add := `{"note_id": ` + strconv.Itoa(int(note.ID)) + `}`
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		method  string
+		body    string
+		want    int
+	}{
+		{"add note", h.AddCollectionItem, http.MethodPost, add, http.StatusOK},
+		{"add the same note again", h.AddCollectionItem, http.MethodPost, add, http.StatusConflict},
+		{"add a missing note", h.AddCollectionItem, http.MethodPost, `{"note_id": 999}`, http.StatusNotFound},
+		{"rename to empty", h.UpdateCollection, http.MethodPut, `{"name": ""}`, http.StatusBadRequest},
+		{"rename to blank", h.UpdateCollection, http.MethodPut, `{"name": "  "}`, http.StatusBadRequest},
+		{"rename", h.UpdateCollection, http.MethodPut, `{"name": "later"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := call(tt.handler, tt.method, tt.body); got != tt.want {
+			t.Fatalf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	db.First(&collection, collection.ID)
+	if collection.ItemCount != 1 {
+		t.Fatalf("item_count = %d, want 1", collection.ItemCount)
+	}
+}
@@ -1,10 +1,9 @@
 package note
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"note/internal/dedup"
 	"note/internal/models"
 	"note/internal/utils"
 	"note/internal/validators"
@@ -24,6 +23,8 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 
 	needSummary := c.DefaultQuery("gen_summary", "false") == "true"
 	needGenTitle := c.DefaultQuery("gen_title", "false") == "true"
+	needSuggestTags := c.DefaultQuery("suggest_tags", "false") == "true"
+	checkDuplicates := c.DefaultQuery("check_duplicates", "false") == "true"
 
 	var req validators.CreateNoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -56,6 +57,7 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 		Tags:      tags,
 		IsPrivate: req.IsPrivate,
 	}
+	note.SetSimHash(dedup.SimHash(req.Content))
 
 	if err := h.svc.DB.Create(&note).Error; err != nil {
 		zap.L().Error("Create note db error", zap.Error(err))
@@ -66,59 +68,46 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 	cacheKeyAllNotes := fmt.Sprintf("notes:user:%d*", userID)
 	_ = h.svc.Cache.ClearCacheByPattern(c, h.svc.Cache, cacheKeyAllNotes)
 
-	go func(n models.Note) {
-		// 拼接标题和内容，让搜索更准
-		textToEmbed := fmt.Sprintf("%s\n%s", n.Title, n.Content)
-
-		vec, err := h.svc.AI.GetEmbedding(textToEmbed)
-		if err != nil {
-			zap.L().Error("AI embedding failed", zap.Error(err))
-			return
-		}
+	// 查重要在建索引之前同步做完：两边用同一段文本生成向量，
+	// 开启向量缓存时建索引直接命中缓存，不会再调一次模型、再计一次费
+	var duplicates []Duplicate
+	if checkDuplicates {
+		duplicates = h.findDuplicates(c, &note)
+	}
 
-		err = h.svc.Qdrant.Upsert(context.Background(), n.ID, vec, n.UserID, n.IsPrivate)
-		if err != nil {
-			zap.L().Error("Qdrant upsert failed", zap.Error(err))
-		}
-	}(note)
+	// 失败了也没关系，定期对账任务会补上
+	h.indexNote(note)
 
 	go func() {
 		if usingDefaultTitle && needGenTitle {
-			h.sendAITask(note.ID, "generate_title")
+			_, _ = h.sendAITask(userID, note.ID, models.AITaskGenerateTitle)
 		}
 
 		if needSummary {
-			h.sendAITask(note.ID, "generate_summary")
+			_, _ = h.sendAITask(userID, note.ID, models.AITaskGenerateSummary)
+		}
+
+		if needSuggestTags {
+			_, _ = h.sendAITask(userID, note.ID, models.AITaskSuggestTags)
 		}
 	}()
 
 	if !note.IsPrivate {
-		go func() {
-			msg := models.FeedMsg{
-				AuthorID: note.UserID,
-				NoteID:   note.ID,
-				PostTime: note.CreatedAt.Unix(),
-			}
-			body, _ := json.Marshal(msg)
-			if h.svc.Rabbit != nil {
-				// 只需要发这一条消息，剩下的交给消费者去扩散
-				_ = h.svc.Rabbit.Publish("feed_queue", body)
-			}
-		}()
+		// 只需要发这一条消息，剩下的交给消费者去扩散
+		go h.svc.Rabbit.PublishFeed(models.FeedMsg{
+			Event:    models.FeedPost,
+			AuthorID: note.UserID,
+			NoteID:   note.ID,
+			PostTime: note.CreatedAt.Unix(),
+		})
 	}
-	utils.Success(c, note)
-}
 
-func (h *NoteHandler) sendAITask(noteID uint, taskType string) {
-	if h.svc.Rabbit == nil {
+	if checkDuplicates {
+		// 只提示，不阻止创建，用户可以再调用合并接口
+		utils.Success(c, gin.H{"note": note, "duplicates": duplicates})
 		return
 	}
-	msg := models.AITaskMsg{
-		NoteID: noteID,
-		Task:   taskType,
-	}
-	body, _ := json.Marshal(msg)
-	_ = h.svc.Rabbit.Publish("ai_queue", body)
+	utils.Success(c, note)
 }
 
 func (h *NoteHandler) generateDefaultTitle(userID uint) (string, error) {
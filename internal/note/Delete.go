@@ -1,6 +1,7 @@
 package note
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"note/internal/models"
@@ -37,5 +38,21 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 	_ = h.svc.Cache.ClearCacheByPattern(c, h.svc.Cache, cacheKeyAllNotes)
 
 	zap.L().Info("Cache cleared for deleted note", zap.Int("note_id", id))
+
+	h.cleanupDeletedNote(uint(id), userID)
+
 	utils.Success(c, gin.H{"message": "deleted"})
 }
+
+// cleanupDeletedNote 清理笔记删除后残留的卡片、主题归属、时间线和向量记录
+func (h *NoteHandler) cleanupDeletedNote(noteID, authorID uint) {
+	h.deleteFlashcards(noteID)
+	if err := h.svc.Topics.NoteDeleted(context.Background(), noteID, authorID); err != nil {
+		zap.L().Warn("Delete note topic failed", zap.Uint("note_id", noteID), zap.Error(err))
+	}
+
+	h.svc.Rabbit.PublishFeed(models.FeedMsg{Event: models.FeedRemove, AuthorID: authorID, NoteID: noteID})
+
+	// 删除向量记录，避免已删除的笔记还出现在别人的智能搜索里
+	h.sendVectorSync(noteID, models.VectorSyncDelete)
+}
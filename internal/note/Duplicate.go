@@ -0,0 +1,348 @@
+package note
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"note/internal/dedup"
+	"note/internal/indexer"
+	"note/internal/infra/ai"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"note/internal/utils"
+	"note/internal/validators"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 创建时最多提示几篇疑似重复的笔记
+	duplicateWarnLimit = 5
+	// 按向量找重复需要对每篇笔记查一次索引，笔记太多时只用 simhash
+	semanticDedupMaxNotes = 300
+)
+
+// Duplicate 一篇疑似重复的笔记，Distance/Similarity 分别是 simhash 和向量给出的依据
+type Duplicate struct {
+	NoteID     uint     `json:"note_id"`
+	Title      string   `json:"title"`
+	Distance   *int     `json:"simhash_distance,omitempty"`
+	Similarity *float32 `json:"similarity,omitempty"`
+}
+
+// DuplicateCluster 一组互相重复的笔记
+type DuplicateCluster struct {
+	Notes []DuplicateNote `json:"notes"`
+}
+
+type DuplicateNote struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// findDuplicates 在作者自己的笔记里找和 note 疑似重复的笔记。
+// 向量检索失败 (包括额度用完) 时只用 simhash 的结果，不影响创建
+func (h *NoteHandler) findDuplicates(ctx context.Context, note *models.Note) []Duplicate {
+	found := make(map[uint]*Duplicate)
+
+	// 只取至少有一段指纹相同的笔记，走 (user_id, sim_bandN) 索引，不用加载作者的所有笔记
+	if note.SimHash != 0 {
+		var candidates []models.Note
+		err := h.svc.DB.WithContext(ctx).Select("id, title, sim_hash").
+			Where("user_id = ? AND id <> ? AND sim_hash <> 0", note.UserID, note.ID).
+			Where("sim_band0 = ? OR sim_band1 = ? OR sim_band2 = ? OR sim_band3 = ?",
+				note.SimBand0, note.SimBand1, note.SimBand2, note.SimBand3).
+			Find(&candidates).Error
+		if err != nil {
+			zap.L().Warn("Load simhash candidates failed", zap.Error(err))
+		}
+		for _, n := range candidates {
+			if d := dedup.Distance(note.SimHash, n.SimHash); d <= h.svc.Config.DedupSimHashDistance {
+				found[n.ID] = &Duplicate{NoteID: n.ID, Title: n.Title, Distance: &d}
+			}
+		}
+	}
+
+	// 和索引用的是同一段文本；开启向量缓存时结果会被缓存，调用方在这之后再建索引就不用重复调模型
+	vec, err := h.svc.AI.GetEmbedding(note.UserID, indexer.NoteText(note))
+	if err != nil {
+		if !errors.Is(err, ai.ErrQuotaExceeded) {
+			zap.L().Warn("Embedding for duplicate check failed", zap.Error(err))
+		}
+	} else {
+		hits, err := h.svc.Indexer.Index().Search(ctx, vec, duplicateWarnLimit, vector.Filter{
+			OwnerID:    note.UserID,
+			ExcludeIDs: []uint{note.ID},
+		})
+		if err != nil {
+			zap.L().Warn("Vector search for duplicate check failed", zap.Error(err))
+		}
+		var missing []uint
+		for _, hit := range hits {
+			if hit.Score < h.svc.Config.DedupVectorThreshold {
+				continue
+			}
+			score := hit.Score
+			if d, ok := found[hit.ID]; ok {
+				d.Similarity = &score
+			} else {
+				found[hit.ID] = &Duplicate{NoteID: hit.ID, Similarity: &score}
+				missing = append(missing, hit.ID)
+			}
+		}
+		if len(missing) > 0 {
+			var notes []models.Note
+			h.svc.DB.WithContext(ctx).Select("id, title").Where("id IN ?", missing).Find(&notes)
+			for _, n := range notes {
+				found[n.ID].Title = n.Title
+			}
+		}
+	}
+
+	duplicates := make([]Duplicate, 0, len(found))
+	for _, d := range found {
+		duplicates = append(duplicates, *d)
+	}
+	// 两种依据都命中的排前面，其次按 simhash 距离
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicateRank(duplicates[i]) < duplicateRank(duplicates[j])
+	})
+	if len(duplicates) > duplicateWarnLimit {
+		duplicates = duplicates[:duplicateWarnLimit]
+	}
+	return duplicates
+}
+
+func duplicateRank(d Duplicate) int {
+	rank := 100
+	if d.Distance != nil {
+		rank = *d.Distance
+	}
+	if d.Similarity == nil {
+		rank += 100
+	}
+	return rank
+}
+
+// ListDuplicates 把当前用户的笔记按内容相似度分组，只返回有重复的组。
+// semantic=true 时额外用向量相似度判断 (笔记数不超过 semanticDedupMaxNotes)
+func (h *NoteHandler) ListDuplicates(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	semantic := c.DefaultQuery("semantic", "false") == "true"
+
+	var notes []models.Note
+	if err := h.svc.DB.Select("id, title, sim_hash, updated_at").Where("user_id = ?", userID).Order("id ASC").Find(&notes).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// 按指纹分段分桶，只比较同桶的笔记
+	clusters := dedup.NewClusters()
+	buckets := dedup.NewBuckets()
+	for _, n := range notes {
+		if n.SimHash == 0 {
+			continue
+		}
+		for _, id := range buckets.Near(n.SimHash, h.svc.Config.DedupSimHashDistance) {
+			clusters.Link(id, n.ID)
+		}
+		buckets.Add(n.ID, n.SimHash)
+	}
+
+	if semantic && len(notes) <= semanticDedupMaxNotes {
+		index := h.svc.Indexer.Index()
+		for _, n := range notes {
+			hits, err := index.Recommend(c, n.ID, duplicateWarnLimit, vector.Filter{OwnerID: userID})
+			if err != nil {
+				if !errors.Is(err, vector.ErrPointNotFound) {
+					zap.L().Warn("Recommend for duplicates failed", zap.Uint("note_id", n.ID), zap.Error(err))
+				}
+				continue
+			}
+			for _, hit := range hits {
+				if hit.Score >= h.svc.Config.DedupVectorThreshold {
+					clusters.Link(n.ID, hit.ID)
+				}
+			}
+		}
+	}
+
+	byID := make(map[uint]models.Note, len(notes))
+	for _, n := range notes {
+		byID[n.ID] = n
+	}
+	result := make([]DuplicateCluster, 0)
+	for _, ids := range clusters.Groups() {
+		cluster := DuplicateCluster{Notes: make([]DuplicateNote, 0, len(ids))}
+		for _, id := range ids {
+			n := byID[id]
+			cluster.Notes = append(cluster.Notes, DuplicateNote{ID: n.ID, Title: n.Title, UpdatedAt: n.UpdatedAt})
+		}
+		result = append(result, cluster)
+	}
+
+	utils.Success(c, gin.H{
+		"clusters": result,
+		"semantic": semantic && len(notes) <= semanticDedupMaxNotes,
+	})
+}
+
+// BackfillSimHash 为还没有指纹或还没有指纹分段的老笔记补算，启动时执行，补齐之后查询为空直接跳过
+func BackfillSimHash(db *gorm.DB) error {
+	var notes []models.Note
+	return db.Select("id, content").
+		Where("(sim_hash = 0 AND content <> '') OR (sim_hash <> 0 AND sim_band0 = 0 AND sim_band1 = 0 AND sim_band2 = 0 AND sim_band3 = 0)").
+		FindInBatches(&notes, 200, func(tx *gorm.DB, _ int) error {
+			for _, n := range notes {
+				if err := db.Model(&models.Note{}).Where("id = ?", n.ID).
+					UpdateColumns(models.SimHashColumns(dedup.SimHash(n.Content))).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// MergeNotes 把若干篇重复笔记合并到 :id：内容追加到末尾 (完全相同的内容不重复追加)，标签取并集，
+// 收藏转移到目标笔记，之后删除被合并的笔记
+func (h *NoteHandler) MergeNotes(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	targetID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+
+	var req validators.MergeNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+	// 重复的 ID 只合并一次，否则查出的笔记数和请求的对不上
+	slices.Sort(req.SourceIDs)
+	req.SourceIDs = slices.Compact(req.SourceIDs)
+	for _, id := range req.SourceIDs {
+		if uint64(id) == targetID {
+			utils.Error(c, http.StatusBadRequest, "不能把笔记合并到自身")
+			return
+		}
+	}
+
+	var target models.Note
+	var sources []models.Note
+	var favoriters []uint
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Tags").
+			Where("id = ? AND user_id = ?", targetID, userID).First(&target).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Tags").Where("id IN ? AND user_id = ?", req.SourceIDs, userID).
+			Order("created_at ASC").Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) != len(req.SourceIDs) {
+			return gorm.ErrRecordNotFound
+		}
+		sourceIDs := make([]uint, len(sources))
+		for i, s := range sources {
+			sourceIDs[i] = s.ID
+		}
+
+		content := target.Content
+		var tags []models.Tag
+		for _, s := range sources {
+			if !strings.Contains(normalizeForMerge(content), normalizeForMerge(s.Content)) {
+				content = strings.TrimRight(content, "\n") + "\n\n" + s.Content
+			}
+			tags = append(tags, s.Tags...)
+		}
+		update := models.SimHashColumns(dedup.SimHash(content))
+		update["content"] = content
+		if err := tx.Model(&target).Updates(update).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tx.Model(&target).Association("Tags").Append(tags); err != nil {
+				return err
+			}
+		}
+
+		// 收藏转移：已经收藏过目标笔记的用户不重复计数
+		var favorites []models.Favorite
+		if err := tx.Where("note_id IN ?", sourceIDs).Find(&favorites).Error; err != nil {
+			return err
+		}
+		for _, f := range favorites {
+			favoriters = append(favoriters, f.UserID)
+			moved := models.Favorite{UserID: f.UserID, NoteID: target.ID, CreatedAt: f.CreatedAt}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&moved).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("note_id IN ?", sourceIDs).Delete(&models.Favorite{}).Error; err != nil {
+			return err
+		}
+		var favoriteCount int64
+		if err := tx.Model(&models.Favorite{}).Where("note_id = ?", target.ID).Count(&favoriteCount).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&target).Update("favorite_count", favoriteCount).Error; err != nil {
+			return err
+		}
+
+		for i := range sources {
+			if err := tx.Model(&sources[i]).Association("Tags").Clear(); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&models.Note{}, sourceIDs).Error; err != nil {
+			return err
+		}
+		return tx.Preload("Tags").First(&target, target.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "笔记不存在或无权操作")
+		} else {
+			zap.L().Error("Merge notes failed", zap.Uint64("target", targetID), zap.Error(err))
+			utils.Error(c, http.StatusInternalServerError, "合并失败")
+		}
+		return
+	}
+
+	for _, s := range sources {
+		_ = h.svc.Cache.Del(c, fmt.Sprintf("note:%d", s.ID))
+		h.cleanupDeletedNote(s.ID, userID)
+	}
+	for _, uid := range favoriters {
+		_ = h.svc.Cache.Del(c, fmt.Sprintf("notes:favorites:%d", uid))
+	}
+	h.svc.Cache.ClearNoteCache(c, target.ID, userID)
+
+	h.indexNote(target)
+	h.regenerateFlashcards(userID, target.ID)
+
+	zap.L().Info("Notes merged",
+		zap.Uint("target", target.ID),
+		zap.Int("sources", len(sources)),
+		zap.Int("favorites_moved", len(favoriters)))
+	utils.Success(c, target)
+}
+
+func normalizeForMerge(content string) string {
+	return strings.Join(strings.Fields(content), " ")
+}
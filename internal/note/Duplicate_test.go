@@ -0,0 +1,64 @@
+package note
+
+import (
+	"encoding/json"
+	"net/http"
+	"note/config"
+	"note/internal/dedup"
+	"note/internal/models"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"reflect"
+	"testing"
+)
+
+func TestListDuplicates(t *testing.T) {
+	db := testutil.DB(t, &models.Note{})
+	h := NewNoteHandler(&svc.ServiceContext{Config: &config.Config{DedupSimHashDistance: 3}, DB: db})
+
+	const userID uint = 1
+	text := "Go 的并发模型基于 goroutine 和 channel，调度器把 goroutine 复用到少量系统线程上。"
+	notes := []models.Note{
+		{UserID: userID, Title: "a", Content: text},
+		{UserID: userID, Title: "b", Content: "做菜的关键在于火候，先大火爆香再转小火慢炖。"},
+		{UserID: userID, Title: "c", Content: text + "  "},
+		{UserID: 2, Title: "other user", Content: text},
+	}
+	for i := range notes {
+		db.Create(&notes[i])
+	}
+	// 老数据没有指纹，启动时补算
+	if err := BackfillSimHash(db); err != nil {
+		t.Fatal(err)
+	}
+	var first models.Note
+	db.First(&first, notes[0].ID)
+	if first.SimHash != dedup.SimHash(text) || first.SimBand0 != uint16(first.SimHash) {
+		t.Fatalf("fingerprint not backfilled: %+v", first)
+	}
+
+	c, w := testutil.Context(http.MethodGet, "/notes/duplicates", userID)
+	h.ListDuplicates(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data struct {
+			Clusters []DuplicateCluster `json:"clusters"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]uint
+	for _, cluster := range resp.Data.Clusters {
+		var ids []uint
+		for _, n := range cluster.Notes {
+			ids = append(ids, n.ID)
+		}
+		got = append(got, ids)
+	}
+	if want := [][]uint{{notes[0].ID, notes[2].ID}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("clusters = %v, want %v", got, want)
+	}
+}
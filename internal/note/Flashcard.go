@@ -0,0 +1,144 @@
+package note
+
+import (
+	"errors"
+	"net/http"
+	"note/internal/models"
+	"note/internal/utils"
+	"note/internal/validators"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDueLimit = 50
+	maxDueLimit     = 200
+)
+
+// ListFlashcards 笔记的全部问答卡片
+func (h *NoteHandler) ListFlashcards(c *gin.Context) {
+	var cards []models.Flashcard
+	if err := h.svc.DB.Where("note_id = ?", c.Param("id")).Order("id ASC").Find(&cards).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	utils.Success(c, cards)
+}
+
+// ListDueFlashcards 今天 (含之前逾期) 需要复习的卡片，最早到期的在前
+func (h *NoteHandler) ListDueFlashcards(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDueLimit)))
+	if limit <= 0 || limit > maxDueLimit {
+		limit = defaultDueLimit
+	}
+
+	now := time.Now()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+
+	var cards []models.Flashcard
+	var total int64
+	query := h.svc.DB.Model(&models.Flashcard{}).Where("user_id = ? AND due_at < ?", userID, endOfDay)
+	if err := query.Count(&total).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := query.Order("due_at ASC").Limit(limit).Find(&cards).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	utils.Success(c, gin.H{"total": total, "cards": cards})
+}
+
+// ReviewFlashcard 提交一次复习结果，按 SM-2 安排下次复习时间
+func (h *NoteHandler) ReviewFlashcard(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var req validators.ReviewFlashcardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "评分必须在 0-5 之间")
+		return
+	}
+
+	var card models.Flashcard
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", c.Param("cid"), userID).First(&card).Error; err != nil {
+			return err
+		}
+
+		card.Review(*req.Grade, time.Now())
+		err := tx.Model(&card).Updates(map[string]interface{}{
+			"ease_factor":      card.EaseFactor,
+			"interval":         card.Interval,
+			"repetitions":      card.Repetitions,
+			"due_at":           card.DueAt,
+			"last_reviewed_at": card.LastReviewedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&models.FlashcardReview{
+			FlashcardID: card.ID,
+			UserID:      userID,
+			Grade:       *req.Grade,
+			Interval:    card.Interval,
+			EaseFactor:  card.EaseFactor,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "卡片不存在")
+		} else {
+			zap.L().Error("Review flashcard failed", zap.Error(err))
+			utils.Error(c, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+
+	utils.Success(c, card)
+}
+
+// regenerateFlashcards 内容修改后，已经生成过卡片的笔记重新生成 (没变的卡片保留复习进度)
+func (h *NoteHandler) regenerateFlashcards(userID, noteID uint) {
+	if h.svc.Rabbit == nil {
+		return
+	}
+	var count int64
+	h.svc.DB.Model(&models.Flashcard{}).Where("note_id = ?", noteID).Count(&count)
+	if count == 0 {
+		return
+	}
+	if _, err := h.sendAITask(userID, noteID, models.AITaskFlashcards); err != nil {
+		zap.L().Warn("Regenerate flashcards failed", zap.Uint("note_id", noteID), zap.Error(err))
+	}
+}
+
+// deleteFlashcards 笔记删除时清理卡片和复习记录
+func (h *NoteHandler) deleteFlashcards(noteID uint) {
+	err := h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		cardIDs := tx.Model(&models.Flashcard{}).Select("id").Where("note_id = ?", noteID)
+		if err := tx.Where("flashcard_id IN (?)", cardIDs).Delete(&models.FlashcardReview{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("note_id = ?", noteID).Delete(&models.Flashcard{}).Error
+	})
+	if err != nil {
+		zap.L().Warn("Delete flashcards failed", zap.Uint("note_id", noteID), zap.Error(err))
+	}
+}
@@ -0,0 +1,82 @@
+package note
+
+import (
+	"net/http"
+	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const feedSortForYou = "foryou"
+
+// GetForYouFeed 为你推荐：按兴趣相似度推荐没看过的公开笔记，并混入最新和热门内容
+func (h *NoteHandler) GetForYouFeed(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	cursor, limit, err := h.svc.Pager.Parse(c, feedSortForYou)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+	// 游标记录推荐会话和已经读到的位置
+	var session int64
+	var offset int
+	if cursor != nil {
+		session, offset = cursor.Time, int(cursor.Score)
+	}
+
+	page, err := h.svc.Recommend.Page(c, userID, session, offset, limit)
+	if err != nil {
+		zap.L().Error("Build recommend feed failed", zap.Uint("uid", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取推荐失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if page.HasMore {
+		next = &pagination.Cursor{Sort: feedSortForYou, Time: page.Session, Score: int64(page.Offset + len(page.NoteIDs))}
+	}
+	if len(page.NoteIDs) == 0 {
+		utils.Success(c, h.svc.Pager.Page([]models.Note{}, next))
+		return
+	}
+
+	// 会话生成之后才删除、改为私密或屏蔽了作者的笔记在这里过滤掉
+	blocked, err := h.svc.Recommend.BlockedAuthors(c, userID)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+	query := h.svc.DB.Preload("Tags").
+		Where("id IN ?", page.NoteIDs).
+		Where("is_private = ?", false)
+	if len(blocked) > 0 {
+		query = query.Where("user_id NOT IN ?", blocked)
+	}
+	var found []models.Note
+	if err := query.Find(&found).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	noteMap := make(map[uint]models.Note, len(found))
+	for _, n := range found {
+		noteMap[n.ID] = n
+	}
+	notes := make([]models.Note, 0, len(page.NoteIDs))
+	for _, id := range page.NoteIDs {
+		if n, ok := noteMap[id]; ok {
+			notes = append(notes, n)
+		}
+	}
+
+	h.attachMyReactions(userID, notes)
+	utils.Success(c, h.svc.Pager.Page(notes, next))
+}
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"note/internal/infra/cache"
 	"note/internal/models"
+	"note/internal/pagination"
 	"note/internal/utils"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -34,6 +37,7 @@ func (h *NoteHandler) GetNotes(c *gin.Context) {
 		var notes []models.Note
 		if err := json.Unmarshal([]byte(cachedNotes), &notes); err == nil {
 			zap.L().Debug("Notes retrieved from cache", zap.String("key", cacheKey))
+			h.attachMyReactions(userID, notes)
 			utils.Success(c, notes)
 			return
 		}
@@ -61,6 +65,8 @@ func (h *NoteHandler) GetNotes(c *gin.Context) {
 	notesJSON, _ := json.Marshal(notes)
 	_ = h.svc.Cache.SetWithRandomTTL(c, cacheKey, string(notesJSON), 10*time.Minute)
 
+	// 表情回应变化时不会清理列表缓存，MyReactions 在缓存之后再填
+	h.attachMyReactions(userID, notes)
 	utils.Success(c, notes)
 }
 
@@ -71,29 +77,35 @@ func (h *NoteHandler) GetNote(c *gin.Context) {
 		return
 	}
 
-	id := c.Param("id")
-	noteID, _ := strconv.ParseUint(id, 10, 64)
-	cacheKey := "note:" + id
+	// 缓存键用解析后的 ID，"007" 这样的写法不能绕过按 note:<id> 清理的缓存
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的笔记ID")
+		return
+	}
+	cacheKey := fmt.Sprintf("note:%d", noteID)
 
+	// 自己的笔记和别人的公开笔记可以读；缓存不区分读者，别人的私密笔记走下面的查询返回 404
 	cachedNote, err := h.svc.Cache.Get(c, cacheKey)
 	if err == nil {
 		var note models.Note
-		if err := json.Unmarshal([]byte(cachedNote), &note); err == nil {
+		if err := json.Unmarshal([]byte(cachedNote), &note); err == nil && (note.UserID == userID || !note.IsPrivate) {
 			zap.L().Debug("Note retrieved from cache", zap.String("key", cacheKey))
 
-			h.recordNoteView(c, userID, uint(noteID))
+			h.recordNoteView(c, userID, note.UserID, uint(noteID))
 
+			note.MyReactions = h.myReactions(userID, []uint{note.ID})[note.ID]
 			utils.Success(c, note)
 			return
 		}
 	}
 
 	var note models.Note
-	if err := h.svc.DB.Preload("Tags").Where("id = ? AND user_id = ?", id, userID).First(&note).Error; err != nil {
+	if err := h.svc.DB.Preload("Tags").Where("id = ? AND (user_id = ? OR is_private = ?)", noteID, userID, false).First(&note).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.Error(c, http.StatusNotFound, "note not found")
 		} else {
-			zap.L().Error("db query note failed", zap.String("note_id", id), zap.Error(err))
+			zap.L().Error("db query note failed", zap.Uint64("note_id", noteID), zap.Error(err))
 			utils.Error(c, http.StatusInternalServerError, "database error")
 		}
 		return
@@ -102,11 +114,22 @@ func (h *NoteHandler) GetNote(c *gin.Context) {
 	noteJSON, _ := json.Marshal(note)
 	_ = h.svc.Cache.SetWithRandomTTL(c, cacheKey, string(noteJSON), 10*time.Minute)
 
-	h.recordNoteView(c, userID, uint(noteID))
+	h.recordNoteView(c, userID, note.UserID, uint(noteID))
 
+	// 缓存不区分读者，MyReactions 在缓存之后再填
+	note.MyReactions = h.myReactions(userID, []uint{note.ID})[note.ID]
 	utils.Success(c, note)
 }
 
+// maxFeedRepairs 读时间线时最多清理重取几次
+const maxFeedRepairs = 2
+
+// 关注动态游标的 Sort：timeline 从推送时间线 (合并大 V 拉取) 翻页，following 为冷启动时直接查库翻页
+const (
+	feedSortTimeline  = "timeline"
+	feedSortFollowing = "following"
+)
+
 func (h *NoteHandler) GetFollowingFeed(c *gin.Context) {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
@@ -114,60 +137,107 @@ func (h *NoteHandler) GetFollowingFeed(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
+	cursor, limit, err := h.svc.Pager.Parse(c, feedSortTimeline, feedSortFollowing)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+	// 游标记录上一页最后一条在时间线中的位置 (发布时间 + 笔记 ID)
+	var after *feedEntry
+	if cursor != nil {
+		after = &feedEntry{NoteID: cursor.ID, PostTime: cursor.Score}
 	}
-	pageSize := 20
-	start := int64((page - 1) * pageSize)
-	stop := start + int64(pageSize) - 1
-
-	key := fmt.Sprintf("timeline:user:%d", userID)
-
-	noteIDStrs, err := h.svc.Cache.LRange(c, key, start, stop)
 
-	if len(noteIDStrs) == 0 {
-		if page > 1 {
-			utils.Success(c, []models.Note{})
+	// 时间线不存在时先从数据库重建；重建失败或时间线为空时直接从数据库拉取所有关注的人，
+	// 后续翻页沿用同一方式，避免中途时间线被回填后顺序错乱
+	sort := feedSortTimeline
+	var pushed []feedEntry
+	if cursor != nil && cursor.Sort == feedSortFollowing {
+		sort = feedSortFollowing
+	} else {
+		if err := h.seedTimeline(c, userID); err != nil {
+			zap.L().Warn("Seed timeline failed", zap.Uint("uid", userID), zap.Error(err))
+		}
+		pushed, err = h.timelineAfter(c, userID, after, limit+1)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			zap.L().Warn("Read timeline failed", zap.Uint("uid", userID), zap.Error(err))
+		}
+		if cursor == nil && len(pushed) == 0 {
+			sort = feedSortFollowing
+		}
+	}
+	if sort == feedSortFollowing {
+		if pushed, err = h.pullFollowedNotes(userID, after, limit+1); err != nil {
+			utils.Error(c, http.StatusInternalServerError, "database error")
 			return
 		}
+	}
 
-		var followedIDs []uint
-		err = h.svc.DB.Model(&models.UserFollow{}).
-			Where("follower_id = ?", userID).
-			Pluck("followed_id", &followedIDs).Error
-
+	// 已删除或改为私密的笔记一般会被时间线事件清理掉，这里兜底：发现后从时间线删除并重新取一页，保证页面是满的
+	for attempt := 0; ; attempt++ {
+		notes, next, stale, err := h.followingFeedPage(userID, sort, pushed, after, limit)
 		if err != nil {
 			utils.Error(c, http.StatusInternalServerError, "database error")
+			return
+		}
+		if len(stale) == 0 || attempt >= maxFeedRepairs || sort == feedSortFollowing {
+			h.attachMyReactions(userID, notes)
+			utils.Success(c, h.svc.Pager.Page(notes, next))
+			return
 		}
 
-		if len(followedIDs) == 0 {
-			utils.Success(c, []models.Note{})
+		members := make([]interface{}, len(stale))
+		for i, id := range stale {
+			members[i] = id
+		}
+		_, _ = h.svc.Cache.ZRem(c, cache.TimelineKey(userID), members...)
+		if pushed, err = h.timelineAfter(c, userID, after, limit+1); err != nil {
+			h.attachMyReactions(userID, notes)
+			utils.Success(c, h.svc.Pager.Page(notes, next))
 			return
 		}
+	}
+}
 
-		var notes []models.Note
-		h.svc.DB.Preload("Tags").
-			Where("user_id IN ?", followedIDs).
-			Where("is_private = ?", false).
-			Order("created_at DESC").
-			Offset(int(start)).
-			Limit(pageSize).
-			Find(&notes)
-
-		utils.Success(c, notes)
-		return
+// followingFeedPage 合并推送 (或冷启动时直接查库) 和拉取的条目并加载一页笔记，stale 为时间线中已经不可见的笔记
+func (h *NoteHandler) followingFeedPage(userID uint, sort string, pushed []feedEntry, after *feedEntry, limit int) ([]models.Note, *pagination.Cursor, []uint, error) {
+	var pulled []feedEntry
+	if sort == feedSortTimeline {
+		// 大 V 的笔记不推送，读的时候拉取
+		var err error
+		if pulled, err = h.pullBigAuthorNotes(userID, after, limit+1); err != nil {
+			zap.L().Warn("Pull big author notes failed", zap.Uint("uid", userID), zap.Error(err))
+		}
+	}
+	// 关注的话题下的新笔记也在读的时候拉取
+	topicNotes, err := h.pullTopicNotes(userID, after, limit+1)
+	if err != nil {
+		zap.L().Warn("Pull topic notes failed", zap.Uint("uid", userID), zap.Error(err))
+	}
+	entries := mergeFeed(pushed, pulled, topicNotes)
+
+	var next *pagination.Cursor
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		next = &pagination.Cursor{Sort: sort, Score: last.PostTime, ID: last.NoteID}
+	}
+	if len(entries) == 0 {
+		return []models.Note{}, nil, nil, nil
+	}
+
+	ids := make([]uint, len(entries))
+	for i, e := range entries {
+		ids[i] = e.NoteID
 	}
 
 	var notes []models.Note
 	err = h.svc.DB.Preload("Tags").
-		Where("id IN ?", noteIDStrs).
+		Where("id IN ?", ids).
 		Where("is_private = ?", false).
 		Find(&notes).Error
-
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, "database error")
-		return
+		return nil, nil, nil, err
 	}
 
 	noteMap := make(map[uint]models.Note)
@@ -175,13 +245,14 @@ func (h *NoteHandler) GetFollowingFeed(c *gin.Context) {
 		noteMap[n.ID] = n
 	}
 
-	var sortedNotes []models.Note
-	for _, idStr := range noteIDStrs {
-		id, _ := strconv.Atoi(idStr)
-		if n, ok := noteMap[uint(id)]; ok {
+	sortedNotes := make([]models.Note, 0, len(ids))
+	var stale []uint
+	for _, id := range ids {
+		if n, ok := noteMap[id]; ok {
 			sortedNotes = append(sortedNotes, n)
+		} else {
+			stale = append(stale, id)
 		}
 	}
-
-	utils.Success(c, sortedNotes)
+	return sortedNotes, next, stale, nil
 }
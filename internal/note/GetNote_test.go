@@ -0,0 +1,69 @@
+package note
+
+import (
+	"fmt"
+	"net/http"
+	"note/config"
+	"note/internal/analytics"
+	"note/internal/models"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetNoteAccess(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.Tag{}, &models.Reaction{}, &models.UserSetting{})
+	rdb, _ := testutil.Cache(t)
+	cfg := &config.Config{AnalyticsViewWindow: time.Hour}
+	h := NewNoteHandler(&svc.ServiceContext{Config: cfg, DB: db, Cache: rdb, Analytics: analytics.New(db, rdb, cfg)})
+
+	const author, reader uint = 1, 2
+	public := models.Note{UserID: author, Title: "public", Content: "c"}
+	private := models.Note{UserID: author, Title: "private", Content: "c", IsPrivate: true}
+	db.Create(&public)
+	db.Create(&private)
+
+	get := func(id string, userID uint) int {
+		c, w := testutil.Context(http.MethodGet, "/notes/"+id, userID)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		h.GetNote(c)
+		return w.Code
+	}
+	pub, priv := fmt.Sprint(public.ID), fmt.Sprint(private.ID)
+
+	tests := []struct {
+		name   string
+		id     string
+		userID uint
+		want   int
+	}{
+		// 作者先读一遍，两篇笔记都进入共享缓存
+		{"author reads public", pub, author, http.StatusOK},
+		{"author reads private", priv, author, http.StatusOK},
+		{"others read public from cache", pub, reader, http.StatusOK},
+		{"others can't read private from cache", priv, reader, http.StatusNotFound},
+		{"zero-padded id", "0" + priv, reader, http.StatusNotFound},
+		{"invalid id", "abc", reader, http.StatusBadRequest},
+		{"missing note", "999", reader, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := get(tt.id, tt.userID); got != tt.want {
+				t.Fatalf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// 公开笔记改为私密并清理缓存后，带前导 0 的 ID 不能读到旧缓存
+	if code := get("0"+pub, reader); code != http.StatusOK {
+		t.Fatalf("public note via padded id: status %d", code)
+	}
+	db.Model(&public).Update("is_private", true)
+	_ = rdb.Del(t.Context(), "note:"+pub)
+	if code := get("0"+pub, reader); code != http.StatusNotFound {
+		t.Fatalf("stale cache served a note that became private: status %d", code)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"note/internal/infra/cache"
 	"note/internal/models"
+	"note/internal/pagination"
 	"note/internal/utils"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +18,14 @@ import (
 	"go.uber.org/zap"
 )
 
+// historySort 阅读历史的游标排序标识
+const historySort = "history"
+
+// recentHistoryKey 最近浏览的 5 篇笔记 (ZSET，score 为浏览时间)
+func recentHistoryKey(userID uint) string {
+	return fmt.Sprintf("user:history:%d", userID)
+}
+
 // GetRecentNotes 返回最近访问的笔记ID列表（最多5个，按时间倒序）
 func (h *NoteHandler) GetRecentNotes(c *gin.Context) {
 	userID, err := utils.GetUserID(c)
@@ -23,7 +34,7 @@ func (h *NoteHandler) GetRecentNotes(c *gin.Context) {
 		return
 	}
 
-	key := fmt.Sprintf("user:history:%d", userID)
+	key := recentHistoryKey(userID)
 
 	noteIDs, err := h.svc.Cache.ZRevRange(c, key, 0, 4)
 	if err != nil {
@@ -110,8 +121,16 @@ func (h *NoteHandler) GetRecentNotes(c *gin.Context) {
 	utils.Success(c, result)
 }
 
-func (h *NoteHandler) recordNoteView(ctx context.Context, userID, noteID uint) {
-	key := fmt.Sprintf("user:history:%d", userID)
+func (h *NoteHandler) recordNoteView(ctx context.Context, userID, authorID, noteID uint) {
+	// 浏览数照常统计，暂停的只是个人的阅读历史
+	if err := h.svc.Analytics.RecordView(ctx, userID, authorID, noteID); err != nil {
+		zap.L().Warn("failed to record note view", zap.Uint("user_id", userID), zap.Uint("note_id", noteID), zap.Error(err))
+	}
+	if h.historyPaused(ctx, userID) {
+		return
+	}
+
+	key := recentHistoryKey(userID)
 	now := float64(time.Now().Unix())
 
 	noteIDStr := strconv.Itoa(int(noteID))
@@ -142,3 +161,154 @@ func (h *NoteHandler) recordNoteView(ctx context.Context, userID, noteID uint) {
 		zap.L().Warn("rabbitmq is nil, skipping history publish", zap.Uint("user_id", userID))
 	}
 }
+
+// historyPaused 用户是否暂停了阅读历史。每次打开笔记都要判断，设置缓存在 Redis 中
+func (h *NoteHandler) historyPaused(ctx context.Context, userID uint) bool {
+	key := cache.HistoryPausedKey(userID)
+	if cached, err := h.svc.Cache.Get(ctx, key); err == nil {
+		return cached == "1"
+	}
+
+	var setting models.UserSetting
+	if err := h.svc.DB.Select("pause_history").Where("user_id = ?", userID).Limit(1).Find(&setting).Error; err != nil {
+		zap.L().Warn("failed to load history setting", zap.Uint("user_id", userID), zap.Error(err))
+		return false
+	}
+	value := "0"
+	if setting.PauseHistory {
+		value = "1"
+	}
+	_ = h.svc.Cache.SetWithRandomTTL(ctx, key, value, time.Hour)
+	return setting.PauseHistory
+}
+
+// HistoryEntry 阅读历史中的一条
+type HistoryEntry struct {
+	Note     HistoryNote `json:"note"`
+	ViewedAt time.Time   `json:"viewed_at"`
+}
+
+type HistoryNote struct {
+	ID            uint      `json:"id"`
+	Title         string    `json:"title"`
+	Summary       string    `json:"summary"`
+	FavoriteCount int       `json:"favorite_count"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	ReactionCounts map[string]int `json:"reaction_counts" gorm:"serializer:json"`
+	MyReactions    []string       `json:"my_reactions,omitempty" gorm:"-"`
+}
+
+// HistoryDay 同一天的阅读历史，按浏览时间倒序。
+// 同一天的记录可能跨页，前端需要把相邻页中日期相同的分组合并
+type HistoryDay struct {
+	Date    string         `json:"date"`
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// ListHistory 完整的阅读历史，按最后一次浏览时间倒序、按天分组；q 按标题和内容过滤。
+// 已删除或已改为私密的笔记不显示
+func (h *NoteHandler) ListHistory(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	cursor, limit, err := h.svc.Pager.Parse(c, historySort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	query := h.svc.DB.Table("histories").
+		Select("histories.id AS history_id, histories.updated_at AS viewed_at, notes.id, notes.title, notes.summary, notes.favorite_count, notes.reaction_counts, notes.created_at").
+		Joins("JOIN notes ON notes.id = histories.note_id").
+		Where("histories.user_id = ?", userID).
+		Where("notes.is_private = ? OR notes.user_id = ?", false, userID)
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("notes.title LIKE ? OR notes.content LIKE ?", like, like)
+	}
+	if cursor != nil {
+		query = query.Where("histories.updated_at < ? OR (histories.updated_at = ? AND histories.id < ?)", cursor.At(), cursor.At(), cursor.ID)
+	}
+
+	var rows []struct {
+		HistoryNote
+		HistoryID uint
+		ViewedAt  time.Time
+	}
+	if err := query.Order("histories.updated_at DESC, histories.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		zap.L().Error("List reading history failed", zap.Uint("user_id", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取阅读历史失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		next = &pagination.Cursor{Sort: historySort, Time: last.ViewedAt.UnixMicro(), ID: last.HistoryID}
+	}
+
+	noteIDs := make([]uint, len(rows))
+	for i, r := range rows {
+		noteIDs[i] = r.ID
+	}
+	mine := h.myReactions(userID, noteIDs)
+
+	days := []HistoryDay{}
+	for _, r := range rows {
+		r.MyReactions = mine[r.ID]
+		date := r.ViewedAt.Local().Format("2006-01-02")
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, HistoryDay{Date: date})
+		}
+		day := &days[len(days)-1]
+		day.Entries = append(day.Entries, HistoryEntry{Note: r.HistoryNote, ViewedAt: r.ViewedAt})
+	}
+	utils.Success(c, h.svc.Pager.Page(days, next))
+}
+
+// DeleteHistoryEntry 从阅读历史中删除一篇笔记
+func (h *NoteHandler) DeleteHistoryEntry(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的笔记ID")
+		return
+	}
+
+	if err := h.svc.DB.Where("user_id = ? AND note_id = ?", userID, noteID).Delete(&models.History{}).Error; err != nil {
+		zap.L().Error("Delete history entry failed", zap.Uint("user_id", userID), zap.Uint64("note_id", noteID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "删除失败")
+		return
+	}
+	if _, err := h.svc.Cache.ZRem(c, recentHistoryKey(userID), strconv.FormatUint(noteID, 10)); err != nil {
+		zap.L().Warn("failed to remove note from recent history", zap.Uint("user_id", userID), zap.Error(err))
+	}
+	utils.Success(c, nil)
+}
+
+// ClearHistory 清空阅读历史
+func (h *NoteHandler) ClearHistory(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.svc.DB.Where("user_id = ?", userID).Delete(&models.History{}).Error; err != nil {
+		zap.L().Error("Clear history failed", zap.Uint("user_id", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "清空失败")
+		return
+	}
+	if err := h.svc.Cache.Del(c, recentHistoryKey(userID)); err != nil {
+		zap.L().Warn("failed to clear recent history", zap.Uint("user_id", userID), zap.Error(err))
+	}
+	utils.Success(c, nil)
+}
@@ -0,0 +1,78 @@
+package note
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"note/config"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"testing"
+)
+
+func TestListHistoryReactions(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.History{}, &models.Reaction{})
+	cfg := &config.Config{CursorSecret: "test", PageDefaultLimit: 20, PageMaxLimit: 100}
+	h := NewNoteHandler(&svc.ServiceContext{Config: cfg, DB: db, Pager: pagination.New(cfg)})
+
+	const viewer uint = 5
+	note := models.Note{UserID: 9, Title: "t", Content: "c", ReactionCounts: map[string]int{"👍": 3, "🎉": 1}}
+	db.Create(&note)
+	db.Create(&models.History{UserID: viewer, NoteID: note.ID})
+	db.Create(&models.Reaction{UserID: viewer, NoteID: note.ID, Emoji: "👍"})
+
+	c, w := testutil.Context(http.MethodGet, "/notes/history", viewer)
+	h.ListHistory(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Data struct {
+			Items []HistoryDay `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data.Items) != 1 || len(resp.Data.Items[0].Entries) != 1 {
+		t.Fatalf("unexpected history: %s", w.Body)
+	}
+	got := resp.Data.Items[0].Entries[0].Note
+	if got.ReactionCounts["👍"] != 3 || len(got.MyReactions) != 1 || got.MyReactions[0] != "👍" {
+		t.Fatalf("reactions not attached: %+v", got)
+	}
+}
+
+func TestHistoryPausedCached(t *testing.T) {
+	db := testutil.DB(t, &models.UserSetting{})
+	rdb, mr := testutil.Cache(t)
+	h := NewNoteHandler(&svc.ServiceContext{Config: &config.Config{}, DB: db, Cache: rdb})
+	ctx := context.Background()
+
+	const userID uint = 8
+	if h.historyPaused(ctx, userID) {
+		t.Fatal("history is recorded by default")
+	}
+	// 设置改了但缓存还在：修改设置的接口负责删除缓存
+	db.Create(&models.UserSetting{UserID: userID, PauseHistory: true})
+	if h.historyPaused(ctx, userID) {
+		t.Fatal("expected the cached setting to be used")
+	}
+	mr.Del(cache.HistoryPausedKey(userID))
+	if !h.historyPaused(ctx, userID) {
+		t.Fatal("expected the setting to be reloaded after the cache is cleared")
+	}
+}
+
+func TestHistoryIndexes(t *testing.T) {
+	db := testutil.DB(t, &models.History{})
+	for _, name := range []string{"idx_user_note", "idx_user_viewed", "idx_histories_updated_at"} {
+		if !db.Migrator().HasIndex(&models.History{}, name) {
+			t.Errorf("missing index %s", name)
+		}
+	}
+}
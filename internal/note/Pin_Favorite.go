@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"note/internal/models"
+	"note/internal/pagination"
 	"note/internal/utils"
 	"strconv"
 	"time"
@@ -119,6 +120,9 @@ func (h *NoteHandler) UnfavoriteNote(c *gin.Context) {
 	utils.Success(c, gin.H{"message": "已取消收藏"})
 }
 
+// favoritesSort 收藏列表的游标排序标识
+const favoritesSort = "favorites"
+
 func (h *NoteHandler) ListMyFavorites(c *gin.Context) {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
@@ -126,18 +130,32 @@ func (h *NoteHandler) ListMyFavorites(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit := 20
+	cursor, limit, err := h.svc.Pager.Parse(c, favoritesSort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
 
+	query := h.svc.DB.Where("user_id = ?", userID)
+	if cursor != nil {
+		query = query.Where("created_at < ? OR (created_at = ? AND note_id < ?)", cursor.At(), cursor.At(), cursor.ID)
+	}
 	var favorites []models.Favorite
-	h.svc.DB.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Limit(limit).
-		Offset((page - 1) * limit).
-		Find(&favorites)
+	if err := query.Order("created_at DESC, note_id DESC").Limit(limit + 1).Find(&favorites).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "获取收藏失败")
+		return
+	}
+
+	// 游标按收藏记录计算，收藏的笔记后来变为私密时这一页会少于 limit 条
+	var next *pagination.Cursor
+	if len(favorites) > limit {
+		favorites = favorites[:limit]
+		last := favorites[limit-1]
+		next = &pagination.Cursor{Sort: favoritesSort, Time: last.CreatedAt.UnixMicro(), ID: last.NoteID}
+	}
 
 	if len(favorites) == 0 {
-		utils.Success(c, []interface{}{})
+		utils.Success(c, h.svc.Pager.Page([]interface{}{}, nil))
 		return
 	}
 
@@ -151,6 +169,7 @@ func (h *NoteHandler) ListMyFavorites(c *gin.Context) {
 		Where("id IN ?", noteIDs).
 		Where("is_private = ?", false).
 		Find(&notes)
+	h.attachMyReactions(userID, notes)
 
 	noteMap := make(map[uint]models.Note)
 	for _, n := range notes {
@@ -169,5 +188,5 @@ func (h *NoteHandler) ListMyFavorites(c *gin.Context) {
 		}
 	}
 
-	utils.Success(c, result)
+	utils.Success(c, h.svc.Pager.Page(result, next))
 }
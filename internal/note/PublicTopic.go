@@ -0,0 +1,253 @@
+package note
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/ranking"
+	"note/internal/utils"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 热门话题的缓存时间，统计需要扫描窗口内的所有公开笔记
+const trendingCacheTTL = 5 * time.Minute
+
+// 话题相关列表的游标排序标识，话题笔记的游标还会带上话题名
+const (
+	topicNotesSort     = "topic_notes:%s"
+	followedTopicsSort = "followed_topics"
+)
+
+// TrendingTopic 一个热门话题及其在时间窗口内的活跃度
+type TrendingTopic struct {
+	Name    string  `json:"name"`
+	Notes   int64   `json:"notes"`
+	Authors int64   `json:"authors"`
+	Points  float64 `json:"points"`
+	Score   float64 `json:"score"`
+}
+
+// topicParam 从路径中读取话题名并归一化
+func topicParam(c *gin.Context) (string, bool) {
+	topic := models.TopicName(c.Param("name"))
+	if topic == "" || utf8.RuneCountInString(topic) > 64 {
+		utils.Error(c, http.StatusBadRequest, "无效的话题")
+		return "", false
+	}
+	return topic, true
+}
+
+// topicNoteIDs 带有该话题标签的笔记 ID (子查询)
+func (h *NoteHandler) topicNoteIDs(topic string) *gorm.DB {
+	return h.svc.DB.Table("note_tags").
+		Select("note_tags.note_id").
+		Joins("JOIN tags ON tags.id = note_tags.tag_id").
+		Where("tags.topic = ?", topic)
+}
+
+// TrendingTopics 时间窗口内公开笔记最活跃的话题，热度 = 笔记数 + 作者数 + 互动分 × 0.1
+func (h *NoteHandler) TrendingTopics(c *gin.Context) {
+	window := c.DefaultQuery("window", ranking.WindowWeek)
+	since, ok := ranking.Since(window, time.Now())
+	if !ok {
+		utils.Error(c, http.StatusBadRequest, "无效的时间窗口")
+		return
+	}
+	// 热门话题只有一页，不接受任何游标
+	_, limit, err := h.svc.Pager.Parse(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	cacheKey := fmt.Sprintf("topics:trending:%s:%d", window, limit)
+	if cached, err := h.svc.Cache.Get(c, cacheKey); err == nil {
+		var topics []TrendingTopic
+		if json.Unmarshal([]byte(cached), &topics) == nil {
+			utils.Success(c, h.svc.Pager.Page(topics, nil))
+			return
+		}
+	}
+
+	// 同一篇笔记可能有多个归一化后同名的标签，先去重再统计
+	sub := h.svc.DB.Table("notes").
+		Select("DISTINCT tags.topic, notes.id, notes.user_id, notes.points").
+		Joins("JOIN note_tags ON note_tags.note_id = notes.id").
+		Joins("JOIN tags ON tags.id = note_tags.tag_id").
+		Where("notes.is_private = ? AND tags.topic <> ?", false, "")
+	if !since.IsZero() {
+		sub = sub.Where("notes.created_at >= ?", since)
+	}
+
+	topics := []TrendingTopic{}
+	err = h.svc.DB.Table("(?) AS t", sub).
+		Select("topic AS name, COUNT(*) AS notes, COUNT(DISTINCT user_id) AS authors, COALESCE(SUM(points), 0) AS points").
+		Group("topic").
+		Order("COUNT(*) + COUNT(DISTINCT user_id) + COALESCE(SUM(points), 0) * 0.1 DESC").
+		Limit(limit).
+		Scan(&topics).Error
+	if err != nil {
+		zap.L().Error("Query trending topics failed", zap.String("window", window), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取热门话题失败")
+		return
+	}
+	for i := range topics {
+		topics[i].Score = float64(topics[i].Notes+topics[i].Authors) + topics[i].Points*0.1
+	}
+
+	if data, err := json.Marshal(topics); err == nil {
+		_ = h.svc.Cache.SetWithRandomTTL(c, cacheKey, data, trendingCacheTTL)
+	}
+	utils.Success(c, h.svc.Pager.Page(topics, nil))
+}
+
+// GetPublicTopic 话题概况：公开笔记数、关注人数、当前用户是否关注
+func (h *NoteHandler) GetPublicTopic(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	topic, ok := topicParam(c)
+	if !ok {
+		return
+	}
+
+	var notes, followers, following int64
+	if err := h.svc.DB.Model(&models.Note{}).
+		Where("is_private = ? AND id IN (?)", false, h.topicNoteIDs(topic)).
+		Count(&notes).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+	h.svc.DB.Model(&models.TopicFollow{}).Where("topic = ?", topic).Count(&followers)
+	h.svc.DB.Model(&models.TopicFollow{}).Where("topic = ? AND user_id = ?", topic, userID).Count(&following)
+
+	utils.Success(c, gin.H{
+		"name":         topic,
+		"notes":        notes,
+		"followers":    followers,
+		"is_following": following > 0,
+	})
+}
+
+// ListTopicNotes 话题下的公开笔记，按发布时间倒序
+func (h *NoteHandler) ListTopicNotes(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	topic, ok := topicParam(c)
+	if !ok {
+		return
+	}
+	sort := fmt.Sprintf(topicNotesSort, topic)
+	cursor, limit, err := h.svc.Pager.Parse(c, sort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	query := h.svc.DB.Preload("Tags").
+		Where("is_private = ? AND id IN (?)", false, h.topicNoteIDs(topic))
+	if cursor != nil {
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.At(), cursor.At(), cursor.ID)
+	}
+
+	var notes []models.Note
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&notes).Error; err != nil {
+		zap.L().Error("List topic notes failed", zap.String("topic", topic), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取笔记失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(notes) > limit {
+		notes = notes[:limit]
+		last := notes[limit-1]
+		next = &pagination.Cursor{Sort: sort, Time: last.CreatedAt.UnixMicro(), ID: last.ID}
+	}
+	h.attachMyReactions(userID, notes)
+	utils.Success(c, h.svc.Pager.Page(notes, next))
+}
+
+// FollowTopic 关注话题，之后话题下的新笔记出现在关注动态里
+func (h *NoteHandler) FollowTopic(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	topic, ok := topicParam(c)
+	if !ok {
+		return
+	}
+
+	follow := models.TopicFollow{UserID: userID, Topic: topic}
+	if err := h.svc.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		zap.L().Error("Follow topic failed", zap.Uint("uid", userID), zap.String("topic", topic), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "关注失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// UnfollowTopic 取消关注话题
+func (h *NoteHandler) UnfollowTopic(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	topic, ok := topicParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.DB.Where("user_id = ? AND topic = ?", userID, topic).Delete(&models.TopicFollow{}).Error; err != nil {
+		zap.L().Error("Unfollow topic failed", zap.Uint("uid", userID), zap.String("topic", topic), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "取消关注失败")
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListFollowedTopics 我关注的话题，按关注时间倒序
+func (h *NoteHandler) ListFollowedTopics(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	cursor, limit, err := h.svc.Pager.Parse(c, followedTopicsSort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	query := h.svc.DB.Where("user_id = ?", userID)
+	if cursor != nil {
+		query = query.Where("id < ?", cursor.ID)
+	}
+	var follows []models.TopicFollow
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&follows).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "获取列表失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(follows) > limit {
+		follows = follows[:limit]
+		next = &pagination.Cursor{Sort: followedTopicsSort, ID: follows[limit-1].ID}
+	}
+	utils.Success(c, h.svc.Pager.Page(follows, next))
+}
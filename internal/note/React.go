@@ -2,12 +2,20 @@ package note
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/reaction"
 	"note/internal/utils"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func (h *NoteHandler) ReactToNote(c *gin.Context) {
@@ -28,11 +36,7 @@ func (h *NoteHandler) ReactToNote(c *gin.Context) {
 		return
 	}
 
-	// 校验 emoji（简单白名单）
-	validEmojis := map[string]bool{
-		"❤️": true, "👍": true, "🔥": true, "👏": true, "😂": true, "😮": true, "😢": true, "🎉": true, "👀": true,
-	}
-	if !validEmojis[input.Emoji] {
+	if !h.reactionAllowed(userID, uint(noteIDUint64), input.Emoji) {
 		utils.Error(c, http.StatusBadRequest, "不支持的 emoji")
 		return
 	}
@@ -56,3 +60,197 @@ func (h *NoteHandler) ReactToNote(c *gin.Context) {
 
 	utils.Success(c, gin.H{"message": "操作已接收"})
 }
+
+// reactionAllowed 新的表情回应只能用部署时配置的白名单里的 emoji；
+// 已经点过的表情即使后来从白名单里移除了，也要允许再点一次取消
+func (h *NoteHandler) reactionAllowed(userID, noteID uint, emoji string) bool {
+	if slices.Contains(h.svc.Config.ReactionEmojis, emoji) {
+		return true
+	}
+	var count int64
+	h.svc.DB.Model(&models.Reaction{}).
+		Where("user_id = ? AND note_id = ? AND emoji = ?", userID, noteID, emoji).
+		Count(&count)
+	return count > 0
+}
+
+// ListReactionEmojis 可以使用的表情
+func (h *NoteHandler) ListReactionEmojis(c *gin.Context) {
+	utils.Success(c, h.svc.Config.ReactionEmojis)
+}
+
+// ReactionUser 对笔记点过表情的一位用户
+type ReactionUser struct {
+	User      models.UserBrief `json:"user"`
+	Emoji     string           `json:"emoji"`
+	ReactedAt time.Time        `json:"reacted_at"`
+}
+
+// ListNoteReactions 谁对笔记点了表情，按时间倒序；emoji 为空时列出所有表情
+func (h *NoteHandler) ListNoteReactions(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	noteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的笔记ID")
+		return
+	}
+	// 游标只能用在同一篇笔记的同一个 emoji 筛选下
+	emoji := c.Query("emoji")
+	sort := fmt.Sprintf("reactions:%d:%s", noteID, emoji)
+	cursor, limit, err := h.svc.Pager.Parse(c, sort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	var note models.Note
+	if err := h.svc.DB.Select("id").
+		Where("id = ? AND (is_private = ? OR user_id = ?)", noteID, false, userID).
+		First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "note not found")
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+
+	query := h.svc.DB.Table("reactions").
+		Select("users.id, users.username, users.avatar, users.bio, reactions.id AS reaction_id, reactions.emoji, reactions.created_at AS reacted_at").
+		Joins("JOIN users ON users.id = reactions.user_id").
+		Where("reactions.note_id = ?", noteID)
+	if emoji != "" {
+		query = query.Where("reactions.emoji = ?", emoji)
+	}
+	if cursor != nil {
+		query = query.Where("reactions.id < ?", cursor.ID)
+	}
+
+	var rows []struct {
+		models.UserBrief
+		ReactionID uint
+		Emoji      string
+		ReactedAt  time.Time
+	}
+	if err := query.Order("reactions.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		zap.L().Error("List note reactions failed", zap.Uint64("nid", noteID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取列表失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &pagination.Cursor{Sort: sort, ID: rows[limit-1].ReactionID}
+	}
+
+	// 标记当前用户关注了哪些人
+	userIDs := make([]uint, len(rows))
+	for i, r := range rows {
+		userIDs[i] = r.ID
+	}
+	following := make(map[uint]bool)
+	if len(userIDs) > 0 {
+		var followed []uint
+		err := h.svc.DB.Model(&models.UserFollow{}).
+			Where("follower_id = ? AND followed_id IN ?", userID, userIDs).
+			Pluck("followed_id", &followed).Error
+		if err != nil {
+			zap.L().Error("Load follow status failed", zap.Uint("uid", userID), zap.Error(err))
+			utils.Error(c, http.StatusInternalServerError, "获取列表失败")
+			return
+		}
+		for _, id := range followed {
+			following[id] = true
+		}
+	}
+
+	users := make([]ReactionUser, len(rows))
+	for i, r := range rows {
+		r.UserBrief.IsFollowing = following[r.ID]
+		users[i] = ReactionUser{User: r.UserBrief, Emoji: r.Emoji, ReactedAt: r.ReactedAt}
+	}
+	utils.Success(c, h.svc.Pager.Page(users, next))
+}
+
+// myReactions 当前用户对这些笔记点过的 emoji
+func (h *NoteHandler) myReactions(userID uint, noteIDs []uint) map[uint][]string {
+	mine, err := reaction.Mine(h.svc.DB, userID, noteIDs)
+	if err != nil {
+		zap.L().Warn("Load my reactions failed", zap.Uint("uid", userID), zap.Error(err))
+	}
+	return mine
+}
+
+// attachMyReactions 填充笔记的 MyReactions
+func (h *NoteHandler) attachMyReactions(userID uint, notes []models.Note) {
+	ids := make([]uint, len(notes))
+	for i, n := range notes {
+		ids[i] = n.ID
+	}
+	mine := h.myReactions(userID, ids)
+	for i := range notes {
+		notes[i].MyReactions = mine[notes[i].ID]
+	}
+}
+
+// DedupReactions 创建 (user_id, note_id, emoji) 唯一索引前删除重复的表情回应，并重新统计受影响笔记的表情数。
+// 启动时在 AutoMigrate 之前执行，索引建好之后直接跳过
+func DedupReactions(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Reaction{}) || db.Migrator().HasIndex(&models.Reaction{}, "idx_user_note_emoji") {
+		return nil
+	}
+
+	var noteIDs []uint
+	if err := db.Model(&models.Reaction{}).
+		Distinct("note_id").
+		Where("(user_id, note_id, emoji) IN (?)", db.Model(&models.Reaction{}).
+			Select("user_id, note_id, emoji").
+			Group("user_id, note_id, emoji").
+			Having("COUNT(*) > 1")).
+		Pluck("note_id", &noteIDs).Error; err != nil {
+		return err
+	}
+	if len(noteIDs) == 0 {
+		return nil
+	}
+
+	// 每组保留最早的一条；保留的 ID 先放进派生表，MySQL 不允许在 DELETE 的子查询里直接读同一张表
+	keep := db.Table("(?) AS keep", db.Model(&models.Reaction{}).
+		Select("MIN(id) AS id").
+		Where("note_id IN ?", noteIDs).
+		Group("user_id, note_id, emoji")).
+		Select("id")
+	if err := db.Where("note_id IN ? AND id NOT IN (?)", noteIDs, keep).Delete(&models.Reaction{}).Error; err != nil {
+		return err
+	}
+
+	for _, noteID := range noteIDs {
+		var rows []struct {
+			Emoji string
+			Count int
+		}
+		if err := db.Model(&models.Reaction{}).
+			Select("emoji, COUNT(*) AS count").
+			Where("note_id = ?", noteID).
+			Group("emoji").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		counts := make(map[string]int, len(rows))
+		for _, r := range rows {
+			counts[r.Emoji] = r.Count
+		}
+		// 单列更新不会经过字段的 serializer，需要自己编码
+		data, _ := json.Marshal(counts)
+		if err := db.Model(&models.Note{}).Where("id = ?", noteID).UpdateColumn("reaction_counts", string(data)).Error; err != nil {
+			return err
+		}
+	}
+	zap.L().Info("Duplicate reactions removed", zap.Int("notes", len(noteIDs)))
+	return nil
+}
@@ -0,0 +1,152 @@
+package note
+
+import (
+	"encoding/json"
+	"net/http"
+	"note/config"
+	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListNoteReactionsCursor(t *testing.T) {
+	db := testutil.DB(t, &models.User{}, &models.Note{}, &models.Reaction{}, &models.UserFollow{})
+	cfg := &config.Config{CursorSecret: "test", PageDefaultLimit: 20, PageMaxLimit: 100}
+	h := NewNoteHandler(&svc.ServiceContext{Config: cfg, DB: db, Pager: pagination.New(cfg)})
+
+	note := models.Note{UserID: 1, Title: "t", Content: "c"}
+	db.Create(&note)
+	for i, emoji := range []string{"👍", "🎉", "👍", "🎉", "👍"} {
+		u := models.User{Username: "u" + strconv.Itoa(i)}
+		db.Create(&u)
+		db.Create(&models.Reaction{UserID: u.ID, NoteID: note.ID, Emoji: emoji})
+	}
+
+	type page struct {
+		Items      []ReactionUser `json:"items"`
+		NextCursor string         `json:"next_cursor"`
+	}
+	list := func(query string) (int, page) {
+		c, w := testutil.Context(http.MethodGet, "/notes/x/reactions?"+query, 1)
+		c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(note.ID))}}
+		h.ListNoteReactions(c)
+		var resp struct {
+			Data page `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	code, first := list("emoji=👍&limit=2")
+	if code != http.StatusOK || len(first.Items) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page: %d %+v", code, first)
+	}
+	code, second := list("emoji=👍&limit=2&cursor=" + first.NextCursor)
+	if code != http.StatusOK || len(second.Items) != 1 || second.Items[0].Emoji != "👍" {
+		t.Fatalf("second page: %d %+v", code, second)
+	}
+
+	// 换了 emoji 筛选后旧游标不能再用
+	if code, _ := list("emoji=🎉&limit=2&cursor=" + first.NextCursor); code != http.StatusBadRequest {
+		t.Fatalf("cursor reused with another emoji: status %d", code)
+	}
+	if code, _ := list("limit=2&cursor=" + first.NextCursor); code != http.StatusBadRequest {
+		t.Fatalf("cursor reused without emoji filter: status %d", code)
+	}
+}
+
+func TestDedupReactions(t *testing.T) {
+	db := testutil.DB(t, &models.Note{})
+	// 老的 reactions 表没有唯一索引
+	type legacyReaction struct {
+		ID        uint `gorm:"primaryKey"`
+		UserID    uint
+		NoteID    uint
+		Emoji     string `gorm:"size:10"`
+		CreatedAt time.Time
+	}
+	if err := db.Table("reactions").AutoMigrate(&legacyReaction{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dup := models.Note{UserID: 1, Title: "t", Content: "c", ReactionCounts: map[string]int{"👍": 3, "🎉": 1}}
+	clean := models.Note{UserID: 1, Title: "t", Content: "c", ReactionCounts: map[string]int{"👍": 1}}
+	db.Create(&dup)
+	db.Create(&clean)
+	for _, r := range []legacyReaction{
+		{UserID: 5, NoteID: dup.ID, Emoji: "👍"},
+		{UserID: 5, NoteID: dup.ID, Emoji: "👍"},
+		{UserID: 6, NoteID: dup.ID, Emoji: "👍"},
+		{UserID: 5, NoteID: dup.ID, Emoji: "🎉"},
+		{UserID: 5, NoteID: clean.ID, Emoji: "👍"},
+	} {
+		db.Table("reactions").Create(&r)
+	}
+
+	if err := DedupReactions(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint
+	db.Table("reactions").Order("id").Pluck("id", &ids)
+	if want := []uint{1, 3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("remaining reactions %v, want %v", ids, want)
+	}
+	var got models.Note
+	db.First(&got, dup.ID)
+	if want := map[string]int{"👍": 2, "🎉": 1}; !reflect.DeepEqual(got.ReactionCounts, want) {
+		t.Fatalf("reaction counts %v, want %v", got.ReactionCounts, want)
+	}
+
+	// 建好唯一索引之后不再处理
+	if err := db.AutoMigrate(&models.Reaction{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DedupReactions(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReactionAllowed(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.Reaction{})
+	h := NewNoteHandler(&svc.ServiceContext{Config: &config.Config{ReactionEmojis: []string{"👍"}}, DB: db})
+
+	note := models.Note{UserID: 1, Title: "t", Content: "c"}
+	db.Create(&note)
+	// 🎉 后来从白名单里移除了
+	db.Create(&models.Reaction{UserID: 5, NoteID: note.ID, Emoji: "🎉"})
+
+	tests := []struct {
+		name   string
+		userID uint
+		emoji  string
+		want   bool
+	}{
+		{"listed emoji", 6, "👍", true},
+		{"delisted emoji can be removed", 5, "🎉", true},
+		{"delisted emoji cannot be added", 6, "🎉", false},
+		{"unknown emoji", 5, "🙃", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.reactionAllowed(tt.userID, note.ID, tt.emoji); got != tt.want {
+				t.Fatalf("reactionAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 不允许的 emoji 在发消息之前就被拒绝
+	c, w := testutil.JSONContext(http.MethodPost, "/notes/x/react", `{"emoji":"🎉"}`, 6)
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(note.ID))}}
+	h.ReactToNote(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", w.Code)
+	}
+}
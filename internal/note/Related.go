@@ -0,0 +1,159 @@
+package note
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"note/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultRelatedLimit = 10
+	maxRelatedLimit     = 50
+	// 按标签过滤在数据库里做，需要多召回一些候选
+	relatedTagOverFetch = 5
+)
+
+// GetRelatedNotes 相关笔记推荐：直接用这篇笔记在向量索引里的向量找相似笔记，不再调用 AI
+func (h *NoteHandler) GetRelatedNotes(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	noteID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	if noteID == 0 {
+		utils.Error(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	sameAuthor := c.DefaultQuery("same_author", "false") == "true"
+	tagID, _ := strconv.ParseUint(c.Query("tag_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultRelatedLimit)))
+	if limit <= 0 || limit > maxRelatedLimit {
+		limit = defaultRelatedLimit
+	}
+
+	var note models.Note
+	err = h.svc.DB.Select("id, user_id, is_private").First(&note, noteID).Error
+	if err != nil || (note.IsPrivate && note.UserID != userID) {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "note not found")
+		} else {
+			utils.Error(c, http.StatusInternalServerError, "database error")
+		}
+		return
+	}
+
+	// 缓存的是 ID 列表，返回前再查一次数据库，保证删除或设为私密的笔记不会漏出去
+	cacheKey := fmt.Sprintf("note:related:%d:%d:%t:%d:%d", noteID, userID, sameAuthor, tagID, limit)
+	var ids []uint
+	if cached, err := h.svc.Cache.Get(c, cacheKey); err == nil && json.Unmarshal([]byte(cached), &ids) == nil {
+		zap.L().Debug("Related notes retrieved from cache", zap.String("key", cacheKey))
+	} else {
+		ids, err = h.findRelatedIDs(c, note, userID, sameAuthor, uint(tagID), limit)
+		if err != nil {
+			if errors.Is(err, vector.ErrPointNotFound) {
+				// 还没生成向量 (刚创建或 AI 服务不可用)，先返回空
+				utils.Success(c, []models.Note{})
+				return
+			}
+			zap.L().Error("Find related notes failed", zap.Uint64("note_id", noteID), zap.Error(err))
+			utils.Error(c, http.StatusInternalServerError, "推荐服务繁忙")
+			return
+		}
+		idsJSON, _ := json.Marshal(ids)
+		_ = h.svc.Cache.SetWithRandomTTL(c, cacheKey, string(idsJSON), 10*time.Minute)
+	}
+
+	notes, err := h.loadVisibleNotes(ids, userID)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "数据库查询失败")
+		return
+	}
+	h.attachMyReactions(userID, notes)
+	utils.Success(c, notes)
+}
+
+func (h *NoteHandler) findRelatedIDs(c *gin.Context, note models.Note, userID uint, sameAuthor bool, tagID uint, limit int) ([]uint, error) {
+	filter := vector.Filter{ViewerID: userID}
+	if sameAuthor {
+		filter.OwnerID = note.UserID
+	}
+
+	fetch := limit
+	if tagID != 0 {
+		fetch = limit * relatedTagOverFetch
+	}
+
+	hits, err := h.svc.Vector.Recommend(c, note.ID, uint64(fetch), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	if tagID == 0 || len(ids) == 0 {
+		return ids, nil
+	}
+
+	var tagged []uint
+	err = h.svc.DB.Table("note_tags").
+		Where("tag_id = ? AND note_id IN ?", tagID, ids).
+		Pluck("note_id", &tagged).Error
+	if err != nil {
+		return nil, err
+	}
+	inTag := make(map[uint]bool, len(tagged))
+	for _, id := range tagged {
+		inTag[id] = true
+	}
+
+	filtered := make([]uint, 0, limit)
+	for _, id := range ids {
+		if inTag[id] && len(filtered) < limit {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered, nil
+}
+
+// loadVisibleNotes 按 ids 的顺序返回当前用户可见的笔记
+func (h *NoteHandler) loadVisibleNotes(ids []uint, userID uint) ([]models.Note, error) {
+	if len(ids) == 0 {
+		return []models.Note{}, nil
+	}
+
+	var notes []models.Note
+	err := h.svc.DB.Preload("Tags").
+		Where("id IN ?", ids).
+		Where(h.svc.DB.Where("user_id = ?", userID).Or("is_private = ?", false)).
+		Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	noteMap := make(map[uint]models.Note, len(notes))
+	for _, n := range notes {
+		noteMap[n.ID] = n
+	}
+	sorted := make([]models.Note, 0, len(notes))
+	for _, id := range ids {
+		if n, ok := noteMap[id]; ok {
+			sorted = append(sorted, n)
+		}
+	}
+	return sorted, nil
+}
@@ -1,15 +1,22 @@
 package note
 
 import (
+	"errors"
 	"net/http"
+	"note/internal/infra/ai"
+	"note/internal/infra/vector"
 	"note/internal/models"
+	"note/internal/pagination"
 	"note/internal/utils"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// searchSort 关键词搜索的游标排序标识
+const searchSort = "search"
+
 func (h *NoteHandler) SearchNotes(c *gin.Context) {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
@@ -28,31 +35,21 @@ func (h *NoteHandler) SearchNotes(c *gin.Context) {
 		return
 	}
 
-	// 3. 分页（可选）
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
+	cursor, limit, err := h.svc.Pager.Parse(c, searchSort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
 	}
-	pageSize := 10
-	offset := (page - 1) * pageSize
-
-	keywordQuery := "%" + query + "%"
-	dbQuery := h.svc.DB.Model(&models.Note{}).
-		Where("title LIKE ? OR content LIKE ?", keywordQuery, keywordQuery).
-		Where(h.svc.DB.Where("user_id = ?", userID).Or("is_private = ?", false))
 
-	// 先查总数 (用于前端分页)
-	var total int64
-	if err := dbQuery.Count(&total).Error; err != nil {
-		utils.Error(c, http.StatusInternalServerError, "搜索失败")
-		return
+	dbQuery := h.keywordQuery(query, userID)
+	if cursor != nil {
+		dbQuery = dbQuery.Where("updated_at < ? OR (updated_at = ? AND id < ?)", cursor.At(), cursor.At(), cursor.ID)
 	}
 
 	var notes []models.Note
 	err = dbQuery.Preload("Tags").
-		Order("updated_at DESC").
-		Limit(pageSize).
-		Offset(offset).
+		Order("updated_at DESC, id DESC").
+		Limit(limit + 1).
 		Find(&notes).Error
 
 	if err != nil {
@@ -61,11 +58,23 @@ func (h *NoteHandler) SearchNotes(c *gin.Context) {
 		return
 	}
 
-	utils.Success(c, gin.H{
-		"notes": notes,
-		"page":  page,
-		"total": len(notes),
-	})
+	var next *pagination.Cursor
+	if len(notes) > limit {
+		notes = notes[:limit]
+		last := notes[limit-1]
+		next = &pagination.Cursor{Sort: searchSort, Time: last.UpdatedAt.UnixMicro(), ID: last.ID}
+	}
+
+	h.attachMyReactions(userID, notes)
+	utils.Success(c, h.svc.Pager.Page(notes, next))
+}
+
+// keywordQuery 标题或内容包含关键词、且当前用户可见的笔记
+func (h *NoteHandler) keywordQuery(query string, userID uint) *gorm.DB {
+	keywordQuery := "%" + query + "%"
+	return h.svc.DB.Model(&models.Note{}).
+		Where("title LIKE ? OR content LIKE ?", keywordQuery, keywordQuery).
+		Where(h.svc.DB.Where("user_id = ?", userID).Or("is_private = ?", false))
 }
 
 func (h *NoteHandler) SmartSearch(c *gin.Context) {
@@ -81,20 +90,38 @@ func (h *NoteHandler) SmartSearch(c *gin.Context) {
 		return
 	}
 
-	queryVec, err := h.svc.AI.GetEmbedding(query)
+	queryVec, err := h.svc.AI.GetEmbedding(userID, query)
+	if errors.Is(err, ai.ErrQuotaExceeded) {
+		// AI 额度用完，退化为关键词搜索
+		var notes []models.Note
+		if err := h.keywordQuery(query, userID).Preload("Tags").Order("updated_at DESC").Limit(20).Find(&notes).Error; err != nil {
+			utils.Error(c, http.StatusInternalServerError, "搜索失败")
+			return
+		}
+		c.Header("X-Search-Degraded", "quota_exceeded")
+		h.attachMyReactions(userID, notes)
+		utils.Success(c, notes)
+		return
+	}
 	if err != nil {
 		zap.L().Error("AI Embedding failed", zap.Error(err))
 		utils.Error(c, 500, "AI 服务繁忙")
 		return
 	}
 
-	// 2. 去 Qdrant 搜出最相似的 Top 20 个 Note ID
-	noteIDs, err := h.svc.Qdrant.Search(c, queryVec, 20, userID)
+	// 2. 去向量索引搜出最相似的 Top 20 个 Note ID
+	hits, err := h.svc.Vector.Search(c, queryVec, 20, vector.Filter{ViewerID: userID})
 	if err != nil {
+		zap.L().Error("Vector search failed", zap.Error(err))
 		utils.Error(c, 500, "搜索服务繁忙")
 		return
 	}
 
+	noteIDs := make([]uint, len(hits))
+	for i, hit := range hits {
+		noteIDs[i] = hit.ID
+	}
+
 	if len(noteIDs) == 0 {
 		utils.Success(c, []models.Note{})
 		return
@@ -125,5 +152,6 @@ func (h *NoteHandler) SmartSearch(c *gin.Context) {
 		}
 	}
 
+	h.attachMyReactions(userID, notes)
 	utils.Success(c, notes)
 }
@@ -0,0 +1,138 @@
+package note
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"note/internal/models"
+	"note/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errSuggestionNotFound = errors.New("suggestion not found")
+
+// RequestTagSuggestions 让 AI 为笔记建议标签 (异步)，返回的任务可以在 /notes/:id/ai-tasks 查看进度
+func (h *NoteHandler) RequestTagSuggestions(c *gin.Context) {
+	h.triggerAITask(c, models.AITaskSuggestTags)
+}
+
+// ListTagSuggestions 查看笔记的标签建议，默认只返回待确认的
+func (h *NoteHandler) ListTagSuggestions(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	status := c.DefaultQuery("status", models.SuggestionPending)
+
+	var suggestions []models.TagSuggestion
+	err = h.svc.DB.Where("note_id = ? AND user_id = ? AND status = ?", c.Param("id"), userID, status).
+		Order("id ASC").
+		Find(&suggestions).Error
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	utils.Success(c, suggestions)
+}
+
+// AcceptTagSuggestion 接受建议：新标签先创建，再和 UpdateNote 一样关联到笔记并清理缓存
+func (h *NoteHandler) AcceptTagSuggestion(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	noteID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	tagCreated := false
+
+	var note models.Note
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		var suggestion models.TagSuggestion
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND note_id = ? AND user_id = ? AND status = ?", c.Param("sid"), noteID, userID, models.SuggestionPending).
+			First(&suggestion).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errSuggestionNotFound
+			}
+			return err
+		}
+
+		var tag models.Tag
+		if suggestion.TagID != nil {
+			err = tx.Where("id = ? AND user_id = ?", *suggestion.TagID, userID).First(&tag).Error
+		} else {
+			err = tx.Where("user_id = ? AND name = ?", userID, suggestion.Name).First(&tag).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 建议的标签还不存在 (AI 新提出的，或者已经被用户删掉了)
+			tag = models.Tag{UserID: userID, Name: suggestion.Name, Topic: models.TopicName(suggestion.Name), Color: h.svc.Config.AISuggestTagColor}
+			if err := tx.Create(&tag).Error; err != nil {
+				return err
+			}
+			tagCreated = true
+		} else if err != nil {
+			return err
+		}
+
+		note.ID = uint(noteID)
+		if err := tx.Model(&note).Association("Tags").Append(&tag); err != nil {
+			return err
+		}
+		if err := tx.Model(&suggestion).Updates(map[string]interface{}{
+			"tag_id": tag.ID,
+			"status": models.SuggestionAccepted,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Preload("Tags").First(&note, noteID).Error
+	})
+	if err != nil {
+		if errors.Is(err, errSuggestionNotFound) {
+			utils.Error(c, http.StatusNotFound, "建议不存在或已处理")
+			return
+		}
+		zap.L().Error("Accept tag suggestion failed", zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "操作失败")
+		return
+	}
+
+	h.svc.Cache.ClearNoteCache(c, note.ID, userID)
+	if tagCreated {
+		_ = h.svc.Cache.Del(c, fmt.Sprintf("tags:user:%d", userID))
+	}
+
+	utils.Success(c, note)
+}
+
+// RejectTagSuggestion 拒绝建议，之后不会再为这篇笔记建议同名标签
+func (h *NoteHandler) RejectTagSuggestion(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	result := h.svc.DB.Model(&models.TagSuggestion{}).
+		Where("id = ? AND note_id = ? AND user_id = ? AND status = ?", c.Param("sid"), c.Param("id"), userID, models.SuggestionPending).
+		Update("status", models.SuggestionRejected)
+	if result.Error != nil {
+		utils.Error(c, http.StatusInternalServerError, "操作失败")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.Error(c, http.StatusNotFound, "建议不存在或已处理")
+		return
+	}
+
+	utils.Success(c, gin.H{"message": "rejected"})
+}
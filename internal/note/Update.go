@@ -1,10 +1,10 @@
 package note
 
 import (
-	"context"
 	"errors"
-	"fmt"
+	"maps"
 	"net/http"
+	"note/internal/dedup"
 	"note/internal/models"
 	"note/internal/utils"
 	"note/internal/validators"
@@ -38,6 +38,11 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 		return
 	}
 
+	// 标题或内容变了才需要重新生成向量，只改私密/标签时同步 payload 即可
+	contentChanged := (req.Title != nil && *req.Title != note.Title) ||
+		(req.Content != nil && *req.Content != note.Content)
+	privacyChanged := req.IsPrivate != nil && *req.IsPrivate != note.IsPrivate
+
 	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
 		update := make(map[string]interface{})
 		if req.Title != nil {
@@ -45,6 +50,7 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 		}
 		if req.Content != nil {
 			update["content"] = *req.Content
+			maps.Copy(update, models.SimHashColumns(dedup.SimHash(*req.Content)))
 		}
 		if req.IsPrivate != nil {
 			update["is_private"] = *req.IsPrivate
@@ -74,28 +80,25 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 		return
 	}
 
-	cacheKeyNote := "note:" + id
-	cacheKeyAllNotes := fmt.Sprintf("notes:user:%d*", userID)
-
-	_ = h.svc.Cache.Del(c, cacheKeyNote)
-	_ = h.svc.Cache.ClearCacheByPattern(c, h.svc.Cache, cacheKeyAllNotes)
+	h.svc.Cache.ClearNoteCache(c, note.ID, userID)
 
 	zap.L().Info("Cache cleared for updated note", zap.String("note_id", id))
 
-	go func(n models.Note) {
-		textToEmbed := fmt.Sprintf("%s\n%s", n.Title, n.Content)
-
-		vec, err := h.svc.AI.GetEmbedding(textToEmbed)
-		if err != nil {
-			zap.L().Error("Embedding generation failed", zap.Error(err))
-			return
-		}
+	if contentChanged {
+		h.indexNote(note)
+		h.regenerateFlashcards(userID, note.ID)
+	}
+	// 私密状态不能依赖重新生成向量成功与否，单独走队列保证同步
+	if privacyChanged {
+		h.sendVectorSync(note.ID, models.VectorSyncPayload)
 
-		err = h.svc.Qdrant.Upsert(context.Background(), n.ID, vec, n.UserID, n.IsPrivate)
-		if err != nil {
-			zap.L().Error("Qdrant upsert failed", zap.Error(err))
+		// 改为私密从粉丝时间线移除；改为公开按原发布时间推送
+		event := models.FeedMsg{Event: models.FeedPost, AuthorID: userID, NoteID: note.ID, PostTime: note.CreatedAt.Unix()}
+		if note.IsPrivate {
+			event = models.FeedMsg{Event: models.FeedRemove, AuthorID: userID, NoteID: note.ID}
 		}
-	}(note)
+		h.svc.Rabbit.PublishFeed(event)
+	}
 
 	utils.Success(c, note)
 }
@@ -1,10 +1,12 @@
 package note
 
 import (
+	"context"
 	"net/http"
 	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/ranking"
 	"note/internal/utils"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,36 +20,43 @@ func (h *NoteHandler) ListPublicNotes(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
-	}
-	limit := 20
-
-	sortBy := c.DefaultQuery("sort", "time") // time / popular
-
-	query := h.svc.DB.Model(&models.Note{}).Where("is_private = ?", false)
-
+	sortBy := c.DefaultQuery("sort", "time") // time / popular / hot / top
+	cursorSort := sortBy
+	var since time.Time
 	switch sortBy {
-	case "popular":
-		query = query.Order("favorite_count DESC, created_at DESC")
-	default: // time
-		query = query.Order("created_at DESC")
+	case "popular", "hot":
+	case "top":
+		// 时间窗口内按互动分排行：day / week / month / all
+		window := c.DefaultQuery("window", ranking.WindowWeek)
+		var ok bool
+		if since, ok = ranking.Since(window, time.Now()); !ok {
+			utils.Error(c, http.StatusBadRequest, "无效的时间窗口")
+			return
+		}
+		cursorSort = "top:" + window
+	default:
+		sortBy, cursorSort = "time", "time"
 	}
-
-	// 4. 获取总数，用于前端分页显示
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		zap.L().Error("count public notes failed", zap.Error(err))
-		utils.Error(c, http.StatusInternalServerError, "系统繁忙")
+	// 换了排序或时间窗口后旧游标失效
+	cursor, limit, err := h.svc.Pager.Parse(c, cursorSort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
 		return
 	}
 
 	var notes []models.Note
-	if err := query.Limit(limit).Offset((page - 1) * limit).Find(&notes).Error; err != nil {
+	var next *pagination.Cursor
+	if sortBy == "hot" {
+		notes, next, err = h.hotNotes(c, cursor, limit)
+	} else {
+		notes, next, err = h.sortedPublicNotes(sortBy, cursorSort, since, cursor, limit)
+	}
+	if err != nil {
+		zap.L().Error("list public notes failed", zap.String("sort", sortBy), zap.Error(err))
 		utils.Error(c, http.StatusInternalServerError, "获取笔记失败")
 		return
 	}
+
 	// 注入“当前用户是否已收藏”
 	noteIDs := make([]uint, len(notes))
 	for i, n := range notes {
@@ -66,163 +75,124 @@ func (h *NoteHandler) ListPublicNotes(c *gin.Context) {
 		}
 	}
 
+	myReactions := h.myReactions(userID, noteIDs)
+
 	type NoteDTO struct {
-		ID            uint      `json:"id"`
-		Title         string    `json:"title"`
-		Content       string    `json:"content"`
-		FavoriteCount int       `json:"favorite_count"`
-		IsFavorite    bool      `json:"is_favorite"`
-		CreatedAt     time.Time `json:"created_at"`
+		ID             uint           `json:"id"`
+		Title          string         `json:"title"`
+		Content        string         `json:"content"`
+		FavoriteCount  int            `json:"favorite_count"`
+		ReactionCounts map[string]int `json:"reaction_counts"`
+		MyReactions    []string       `json:"my_reactions,omitempty"`
+		Points         float64        `json:"points"`
+		IsFavorite     bool           `json:"is_favorite"`
+		CreatedAt      time.Time      `json:"created_at"`
 	}
 
 	result := make([]NoteDTO, len(notes))
 	for i, n := range notes {
 		result[i] = NoteDTO{
-			ID:            n.ID,
-			Title:         n.Title,
-			Content:       n.Content,
-			FavoriteCount: n.FavoriteCount,
-			IsFavorite:    favSet[n.ID],
-			CreatedAt:     n.CreatedAt,
+			ID:             n.ID,
+			Title:          n.Title,
+			Content:        n.Content,
+			FavoriteCount:  n.FavoriteCount,
+			ReactionCounts: n.ReactionCounts,
+			MyReactions:    myReactions[n.ID],
+			Points:         n.Points,
+			IsFavorite:     favSet[n.ID],
+			CreatedAt:      n.CreatedAt,
 		}
 	}
 
-	utils.Success(c, gin.H{"notes": result, "page": page, "total": total})
+	utils.Success(c, h.svc.Pager.Page(result, next))
 }
 
-/*非常非常高级的游标分页版，不过我还没搞懂，先封存在这
-func (h *NoteHandler) ListPublicNotes(c *gin.Context) {
-	userID, err := utils.GetUserID(c)
-	if err != nil {
-		utils.Error(c, http.StatusUnauthorized, err.Error())
-		return
-	}
-
-	sortBy := c.DefaultQuery("sort", "time") // time / popular
-	cursor := c.Query("cursor")              // 游标格式：时间戳_ID
-	limitStr := c.DefaultQuery("limit", "20")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 || limit > 100 {
-		limit = 20
-	}
-
-	query := h.db.Where("is_private = ?", false)
-
-	// 解析游标
-	var cursorTime time.Time
-	var cursorID uint
-	if cursor != "" {
-		// 游标格式：2006-01-02T15:04:05.999Z_123
-		parts := strings.Split(cursor, "_")
-		if len(parts) == 2 {
-			if t, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
-				cursorTime = t
-			}
-			if id, err := strconv.ParseUint(parts[1], 10, 64); err == nil {
-				cursorID = uint(id)
-			}
-		}
-	}
+// sortedPublicNotes 按数据库中的排序键分页；复合排序键保证顺序唯一，新发布的笔记不会让翻页重复或漏掉
+func (h *NoteHandler) sortedPublicNotes(sortBy, cursorSort string, since time.Time, cursor *pagination.Cursor, limit int) ([]models.Note, *pagination.Cursor, error) {
+	query := h.svc.DB.Model(&models.Note{}).Where("is_private = ?", false)
 
-	// 根据排序方式和游标构建查询
 	switch sortBy {
 	case "popular":
-		// 按热度排序（收藏数）
 		query = query.Order("favorite_count DESC, created_at DESC, id DESC")
-		if !cursorTime.IsZero() && cursorID > 0 {
-			// 我们需要知道游标记录的favorite_count
-			var cursorNote models.Note
-			if err := h.db.Select("favorite_count").First(&cursorNote, cursorID).Error; err == nil {
-				// 复杂查询：需要获取游标笔记的收藏数
-				query = query.Where(
-					"(favorite_count < ?) OR "+
-						"(favorite_count = ? AND created_at < ?) OR "+
-						"(favorite_count = ? AND created_at = ? AND id < ?)",
-					cursorNote.FavoriteCount,
-					cursorNote.FavoriteCount, cursorTime,
-					cursorNote.FavoriteCount, cursorTime, cursorID,
-				)
-			}
+		if cursor != nil {
+			query = query.Where("favorite_count < ? OR (favorite_count = ? AND (created_at < ? OR (created_at = ? AND id < ?)))",
+				cursor.Score, cursor.Score, cursor.At(), cursor.At(), cursor.ID)
 		}
-	default: // time
-		// 按时间排序
+	case "top":
+		if !since.IsZero() {
+			query = query.Where("created_at >= ?", since)
+		}
+		query = query.Order("points DESC, created_at DESC, id DESC")
+		if cursor != nil {
+			query = query.Where("points < ? OR (points = ? AND (created_at < ? OR (created_at = ? AND id < ?)))",
+				cursor.Value, cursor.Value, cursor.At(), cursor.At(), cursor.ID)
+		}
+	default:
 		query = query.Order("created_at DESC, id DESC")
-		if !cursorTime.IsZero() && cursorID > 0 {
-			// 使用复合游标：时间戳 + ID
-			query = query.Where("(created_at < ?) OR (created_at = ? AND id < ?)",
-				cursorTime, cursorTime, cursorID)
+		if cursor != nil {
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.At(), cursor.At(), cursor.ID)
 		}
 	}
 
+	// 多查一条判断是否还有下一页
 	var notes []models.Note
-	// 多查一条，用于判断是否有更多数据
-	query.Limit(limit + 1).Find(&notes)
-
-	// 判断是否有更多数据
-	hasMore := len(notes) > limit
-	if hasMore {
-		notes = notes[:limit] // 去掉多查的一条
-	}
-
-	// 如果没有数据，直接返回
-	if len(notes) == 0 {
-		utils.Success(c, gin.H{
-			"notes":    []interface{}{},
-			"has_more": false,
-			"cursor":   "",
-		})
-		return
+	if err := query.Limit(limit + 1).Find(&notes).Error; err != nil {
+		return nil, nil, err
+	}
+	var next *pagination.Cursor
+	if len(notes) > limit {
+		notes = notes[:limit]
+		last := notes[limit-1]
+		next = &pagination.Cursor{
+			Sort:  cursorSort,
+			Time:  last.CreatedAt.UnixMicro(),
+			Score: int64(last.FavoriteCount),
+			Value: last.Points,
+			ID:    last.ID,
+		}
 	}
+	return notes, next, nil
+}
 
-	// 注入"当前用户是否已收藏"
-	noteIDs := make([]uint, len(notes))
-	for i, n := range notes {
-		noteIDs[i] = n.ID
+// hotNotes 按热门榜的顺序加载笔记，榜单中已经不可见的笔记直接跳过
+func (h *NoteHandler) hotNotes(ctx context.Context, cursor *pagination.Cursor, limit int) ([]models.Note, *pagination.Cursor, error) {
+	var after *ranking.Entry
+	if cursor != nil {
+		after = &ranking.Entry{NoteID: cursor.ID, Score: cursor.Value}
 	}
-
-	var favorites []models.Favorite
-	h.db.Where("user_id = ? AND note_id IN ?", userID, noteIDs).Find(&favorites)
-	favSet := make(map[uint]bool)
-	for _, f := range favorites {
-		favSet[f.NoteID] = true
+	entries, err := h.svc.Ranking.Hot(ctx, after, limit+1)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 构造返回结构
-	type NoteDTO struct {
-		ID            uint      `json:"id"`
-		Title         string    `json:"title"`
-		Content       string    `json:"content"`
-		FavoriteCount int       `json:"favorite_count"`
-		IsFavorite    bool      `json:"is_favorite"`
-		CreatedAt     time.Time `json:"created_at"`
+	var next *pagination.Cursor
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		next = &pagination.Cursor{Sort: "hot", Value: last.Score, ID: last.NoteID}
 	}
-
-	result := make([]NoteDTO, len(notes))
-	for i, n := range notes {
-		result[i] = NoteDTO{
-			ID:            n.ID,
-			Title:         n.Title,
-			Content:       n.Content,
-			FavoriteCount: n.FavoriteCount,
-			IsFavorite:    favSet[n.ID],
-			CreatedAt:     n.CreatedAt,
-		}
+	if len(entries) == 0 {
+		return []models.Note{}, next, nil
 	}
 
-	// 生成下一页游标（最后一条记录的信息）
-	nextCursor := ""
-	if hasMore && len(notes) > 0 {
-		lastNote := notes[len(notes)-1]
-		// 格式：时间戳_ID
-		nextCursor = fmt.Sprintf("%s_%d",
-			lastNote.CreatedAt.Format(time.RFC3339Nano),
-			lastNote.ID)
+	ids := make([]uint, len(entries))
+	for i, e := range entries {
+		ids[i] = e.NoteID
+	}
+	var found []models.Note
+	if err := h.svc.DB.Where("id IN ? AND is_private = ?", ids, false).Find(&found).Error; err != nil {
+		return nil, nil, err
+	}
+	noteMap := make(map[uint]models.Note, len(found))
+	for _, n := range found {
+		noteMap[n.ID] = n
 	}
 
-	utils.Success(c, gin.H{
-		"notes":    result,
-		"has_more": hasMore,
-		"cursor":   nextCursor,
-	})
+	notes := make([]models.Note, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := noteMap[id]; ok {
+			notes = append(notes, n)
+		}
+	}
+	return notes, next, nil
 }
-*/
@@ -0,0 +1,200 @@
+package note
+
+import (
+	"context"
+	"fmt"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// feedEntry 时间线中的一条
+type feedEntry struct {
+	NoteID   uint
+	PostTime int64
+}
+
+// before 按 (发布时间, ID) 倒序时是否排在 o 之后
+func (e feedEntry) before(o feedEntry) bool {
+	if e.PostTime != o.PostTime {
+		return e.PostTime < o.PostTime
+	}
+	return e.NoteID < o.NoteID
+}
+
+// timelineAfter 从推送时间线读取排在 after 之后的至多 count 条 (after 为 nil 时从头读)
+func (h *NoteHandler) timelineAfter(ctx context.Context, userID uint, after *feedEntry, count int) ([]feedEntry, error) {
+	key := cache.TimelineKey(userID)
+	max := "+inf"
+	var ties int64
+	if after != nil {
+		max = strconv.FormatInt(after.PostTime, 10)
+		// 同一秒的条目在 ZSET 里按 member 字符串排序，和 ID 顺序不一致，把它们都取出来再过滤
+		ties, _ = h.svc.Cache.ZCount(ctx, key, max, max)
+	}
+
+	zs, err := h.svc.Cache.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: int64(count) + ties,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]feedEntry, 0, len(zs))
+	for _, z := range zs {
+		id, _ := strconv.ParseUint(fmt.Sprint(z.Member), 10, 64)
+		e := feedEntry{NoteID: uint(id), PostTime: int64(z.Score)}
+		if e.NoteID == cache.TimelineSentinel {
+			continue
+		}
+		if after == nil || e.before(*after) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// seedTimeline 时间线不存在 (新用户、Redis 数据丢失、从旧的 LIST 时间线升级) 时从数据库重建：
+// 写入关注的非大 V 作者最近 TimelineMaxLength 篇公开笔记，之后由推送增量维护
+func (h *NoteHandler) seedTimeline(ctx context.Context, userID uint) error {
+	exists, err := h.svc.Cache.Exists(ctx, cache.TimelineKey(userID))
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	followed := h.svc.DB.Model(&models.UserFollow{}).Select("user_follows.followed_id").Where("user_follows.follower_id = ?", userID)
+	if threshold := h.svc.Config.FeedBigAuthorFans; threshold > 0 {
+		// 大 V 的笔记读时拉取，不进时间线
+		followed = followed.Joins("JOIN users ON users.id = user_follows.followed_id").Where("users.fan_count < ?", threshold)
+	}
+	var notes []models.Note
+	err = h.svc.DB.Select("id, created_at").
+		Where("user_id IN (?) AND is_private = ?", followed, false).
+		Order("created_at DESC, id DESC").
+		Limit(cache.TimelineMaxLength).
+		Find(&notes).Error
+	if err != nil {
+		return err
+	}
+
+	entries := make([]redis.Z, len(notes))
+	for i, n := range notes {
+		entries[i] = redis.Z{Score: float64(n.CreatedAt.Unix()), Member: n.ID}
+	}
+	return h.svc.Cache.SeedTimeline(ctx, userID, entries)
+}
+
+// feedAfter 数据库查询中只保留排在 after 之后的笔记 (发布时间按秒比较，和时间线的 score 一致)
+func feedAfter(db *gorm.DB, after *feedEntry) *gorm.DB {
+	if after == nil {
+		return db
+	}
+	t := time.Unix(after.PostTime, 0)
+	return db.Where("notes.created_at < ? OR (notes.created_at < ? AND notes.id < ?)", t, t.Add(time.Second), after.NoteID)
+}
+
+func toFeedEntries(notes []models.Note) []feedEntry {
+	entries := make([]feedEntry, len(notes))
+	for i, n := range notes {
+		entries[i] = feedEntry{NoteID: n.ID, PostTime: n.CreatedAt.Unix()}
+	}
+	return entries
+}
+
+// pullFollowedNotes 不经过时间线，直接查询所有关注的人排在 after 之后的公开笔记
+func (h *NoteHandler) pullFollowedNotes(userID uint, after *feedEntry, limit int) ([]feedEntry, error) {
+	followed := h.svc.DB.Model(&models.UserFollow{}).Select("followed_id").Where("follower_id = ?", userID)
+
+	var notes []models.Note
+	err := feedAfter(h.svc.DB.Select("id, created_at"), after).
+		Where("user_id IN (?) AND is_private = ?", followed, false).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+	return toFeedEntries(notes), nil
+}
+
+// pullTopicNotes 关注的话题下、关注之后发布的其他人的公开笔记，不包括屏蔽的作者
+func (h *NoteHandler) pullTopicNotes(userID uint, after *feedEntry, limit int) ([]feedEntry, error) {
+	var follows int64
+	if err := h.svc.DB.Model(&models.TopicFollow{}).Where("user_id = ?", userID).Count(&follows).Error; err != nil || follows == 0 {
+		return nil, err
+	}
+
+	var notes []models.Note
+	err := feedAfter(h.svc.DB.Select("notes.id, notes.created_at"), after).
+		Joins("JOIN note_tags ON note_tags.note_id = notes.id").
+		Joins("JOIN tags ON tags.id = note_tags.tag_id").
+		Joins("JOIN topic_follows ON topic_follows.topic = tags.topic AND topic_follows.user_id = ?", userID).
+		Where("notes.is_private = ? AND notes.user_id <> ?", false, userID).
+		Where("notes.created_at >= topic_follows.created_at").
+		Where("notes.user_id NOT IN (?)", h.svc.DB.Model(&models.UserBlock{}).Select("blocked_id").Where("blocker_id = ?", userID)).
+		Group("notes.id, notes.created_at").
+		Order("notes.created_at DESC, notes.id DESC").
+		Limit(limit).
+		Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+	return toFeedEntries(notes), nil
+}
+
+// pullBigAuthorNotes 当前用户关注的大 V 排在 after 之后的公开笔记 (这些作者发帖时不推送)
+func (h *NoteHandler) pullBigAuthorNotes(userID uint, after *feedEntry, limit int) ([]feedEntry, error) {
+	threshold := h.svc.Config.FeedBigAuthorFans
+	if threshold <= 0 {
+		return nil, nil
+	}
+
+	var bigAuthors []uint
+	err := h.svc.DB.Model(&models.UserFollow{}).
+		Joins("JOIN users ON users.id = user_follows.followed_id").
+		Where("user_follows.follower_id = ? AND users.fan_count >= ?", userID, threshold).
+		Pluck("user_follows.followed_id", &bigAuthors).Error
+	if err != nil || len(bigAuthors) == 0 {
+		return nil, err
+	}
+
+	var notes []models.Note
+	err = feedAfter(h.svc.DB.Select("id, created_at"), after).
+		Where("user_id IN ? AND is_private = ?", bigAuthors, false).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+	return toFeedEntries(notes), nil
+}
+
+// mergeFeed 合并推送和拉取的结果，去重后按发布时间倒序 (同一秒按 ID 倒序)。
+// 作者刚成为大 V 时，之前推送的笔记可能同时出现在两边；关注的人的笔记也可能同时属于关注的话题
+func mergeFeed(lists ...[]feedEntry) []feedEntry {
+	seen := make(map[uint]bool)
+	var merged []feedEntry
+	for _, list := range lists {
+		for _, e := range list {
+			if !seen[e.NoteID] {
+				seen[e.NoteID] = true
+				merged = append(merged, e)
+			}
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].PostTime != merged[j].PostTime {
+			return merged[i].PostTime > merged[j].PostTime
+		}
+		return merged[i].NoteID > merged[j].NoteID
+	})
+	return merged
+}
@@ -1,7 +1,12 @@
 package note
 
 import (
+	"context"
+	"encoding/json"
+	"note/internal/models"
 	"note/internal/svc"
+
+	"go.uber.org/zap"
 )
 
 type NoteHandler struct {
@@ -11,3 +16,33 @@ type NoteHandler struct {
 func NewNoteHandler(svc *svc.ServiceContext) *NoteHandler {
 	return &NoteHandler{svc: svc}
 }
+
+// indexNote 后台为笔记生成向量，成功后按新向量更新所属主题
+func (h *NoteHandler) indexNote(note models.Note) {
+	go func(n models.Note) {
+		ctx := context.Background()
+		if err := h.svc.Indexer.IndexNote(ctx, &n); err != nil {
+			zap.L().Error("Index note failed", zap.Uint("note_id", n.ID), zap.Error(err))
+			return
+		}
+		h.svc.Topics.NoteIndexed(ctx, &n)
+	}(note)
+}
+
+// sendVectorSync 通过队列同步向量索引 (失败会重试)；没有 MQ 时直接同步
+func (h *NoteHandler) sendVectorSync(noteID uint, action string) {
+	if h.svc.Rabbit == nil {
+		go func() {
+			if err := h.svc.Indexer.Sync(context.Background(), noteID, action); err != nil {
+				zap.L().Error("Vector sync failed", zap.Uint("note_id", noteID), zap.String("action", action), zap.Error(err))
+			}
+		}()
+		return
+	}
+
+	msg := models.VectorSyncMsg{NoteID: noteID, Action: action}
+	body, _ := json.Marshal(msg)
+	if err := h.svc.Rabbit.Publish("vector_queue", body); err != nil {
+		zap.L().Error("Publish vector sync failed", zap.Uint("note_id", noteID), zap.Error(err))
+	}
+}
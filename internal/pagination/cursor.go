@@ -0,0 +1,127 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"note/config"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidCursor 游标被篡改、格式错误或不属于当前排序方式
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor 上一页最后一条的排序键，不同接口只用到其中几个字段
+type Cursor struct {
+	Sort  string  `json:"s,omitempty"` // 排序方式，换了排序后旧游标失效
+	Time  int64   `json:"t,omitempty"` // 微秒时间戳
+	Score int64   `json:"n,omitempty"` // 收藏数、时间线 score 等
+	Value float64 `json:"v,omitempty"` // 热度、互动分等小数排序键
+	ID    uint    `json:"i,omitempty"`
+}
+
+// At 游标里的时间
+func (c *Cursor) At() time.Time {
+	return time.UnixMicro(c.Time)
+}
+
+// Page 列表接口统一的返回结构
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// Pager 解析 limit 和游标，生成带签名的下一页游标。
+// 游标对客户端是不透明的：base64(JSON) + HMAC，客户端无法伪造排序键去扫表
+type Pager struct {
+	secret       []byte
+	defaultLimit int
+	maxLimit     int
+}
+
+// New 用 CURSOR_SECRET 签名，配置加载时已保证非空且不同于 JWT 密钥
+func New(cfg *config.Config) *Pager {
+	return &Pager{
+		secret:       []byte(cfg.CursorSecret),
+		defaultLimit: cfg.PageDefaultLimit,
+		maxLimit:     cfg.PageMaxLimit,
+	}
+}
+
+// Parse 读取 limit 和 cursor 参数，cursor 为空时返回 nil (第一页)。
+// sorts 为当前接口可以接受的排序方式，游标的 Sort 不在其中时返回 ErrInvalidCursor，
+// 防止把别的接口或别的排序下发的游标拿来翻页
+func (p *Pager) Parse(c *gin.Context, sorts ...string) (*Cursor, int, error) {
+	limit := p.defaultLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, 0, errors.New("invalid limit")
+		}
+		limit = n
+	}
+	if p.maxLimit > 0 && limit > p.maxLimit {
+		limit = p.maxLimit
+	}
+
+	token := c.Query("cursor")
+	if token == "" {
+		return nil, limit, nil
+	}
+	cur, err := p.Decode(token)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !slices.Contains(sorts, cur.Sort) {
+		return nil, 0, ErrInvalidCursor
+	}
+	return cur, limit, nil
+}
+
+// Encode 游标序列化并签名
+func (p *Pager) Encode(cur Cursor) string {
+	data, _ := json.Marshal(cur)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + p.sign(payload)
+}
+
+// Decode 校验签名并解析游标
+func (p *Pager) Decode(token string) (*Cursor, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(p.sign(payload))) {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cur Cursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cur, nil
+}
+
+// Page 组装返回结构，next 为 nil 表示没有下一页
+func (p *Pager) Page(items interface{}, next *Cursor) Page {
+	page := Page{Items: items}
+	if next != nil {
+		page.NextCursor = p.Encode(*next)
+		page.HasMore = true
+	}
+	return page
+}
+
+func (p *Pager) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
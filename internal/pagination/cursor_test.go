@@ -0,0 +1,97 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"note/config"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newPager(secret string) *Pager {
+	return New(&config.Config{CursorSecret: secret, PageDefaultLimit: 20, PageMaxLimit: 100})
+}
+
+func parse(p *Pager, query string, sorts ...string) (*Cursor, int, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+	return p.Parse(c, sorts...)
+}
+
+func TestEncodeDecode(t *testing.T) {
+	p := newPager("secret")
+	cur := Cursor{Sort: "history", Time: 1700000000000000, Score: 3, Value: 1.5, ID: 42}
+	token := p.Encode(cur)
+	payload, sig, _ := strings.Cut(token, ".")
+	other, _, _ := strings.Cut(p.Encode(Cursor{Sort: "history", ID: 1}), ".")
+
+	tests := []struct {
+		name    string
+		pager   *Pager
+		token   string
+		wantErr bool
+	}{
+		{"round trip", p, token, false},
+		{"other secret", newPager("other"), token, true},
+		{"tampered payload", p, other + "." + sig, true},
+		{"missing signature", p, payload, true},
+		{"garbage", p, "not-a-cursor", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.pager.Decode(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Fatalf("Decode() error = %v, want ErrInvalidCursor", err)
+				}
+				return
+			}
+			if err != nil || *got != cur {
+				t.Fatalf("Decode() = %+v, %v, want %+v", got, err, cur)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	p := newPager("secret")
+	history := p.Encode(Cursor{Sort: "history", ID: 1})
+	unsorted := p.Encode(Cursor{ID: 1})
+
+	tests := []struct {
+		name      string
+		query     string
+		sorts     []string
+		wantLimit int
+		wantSort  string
+		wantErr   bool
+	}{
+		{"first page", "", []string{"history"}, 20, "", false},
+		{"limit capped", "limit=500", []string{"history"}, 100, "", false},
+		{"invalid limit", "limit=0", []string{"history"}, 0, "", true},
+		{"matching sort", "cursor=" + history, []string{"history"}, 20, "history", false},
+		{"one of several sorts", "limit=5&cursor=" + history, []string{"timeline", "history"}, 5, "history", false},
+		{"other endpoint's cursor", "cursor=" + history, []string{"favorites"}, 0, "", true},
+		{"cursor without sort", "cursor=" + unsorted, []string{"favorites"}, 0, "", true},
+		{"endpoint without cursors", "cursor=" + unsorted, nil, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, limit, err := parse(p, tt.query, tt.sorts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if limit != tt.wantLimit {
+				t.Fatalf("limit = %d, want %d", limit, tt.wantLimit)
+			}
+			if (cur == nil) != (tt.wantSort == "") || (cur != nil && cur.Sort != tt.wantSort) {
+				t.Fatalf("cursor = %+v, want sort %q", cur, tt.wantSort)
+			}
+		})
+	}
+}
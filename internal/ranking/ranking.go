@@ -0,0 +1,256 @@
+package ranking
+
+import (
+	"context"
+	"errors"
+	"math"
+	"note/config"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// hotKey 热门榜 (ZSET，member 为笔记 ID，score 为热度)
+	hotKey         = "rank:hot"
+	rebuildLockKey = "rank:hot:rebuild"
+	rebuildLockTTL = 10 * time.Minute
+	// emptyKey 重建时窗口内没有公开笔记，短时间内不再触发重建
+	emptyKey     = "rank:hot:empty"
+	emptyTTL     = 5 * time.Minute
+	rebuildBatch = 500
+)
+
+// 排行榜时间窗口
+const (
+	WindowDay   = "day"
+	WindowWeek  = "week"
+	WindowMonth = "month"
+	WindowAll   = "all"
+)
+
+// Since 窗口的起始时间，all 返回零值；窗口不合法时 ok 为 false
+func Since(window string, now time.Time) (since time.Time, ok bool) {
+	switch window {
+	case WindowDay:
+		return now.AddDate(0, 0, -1), true
+	case WindowWeek:
+		return now.AddDate(0, 0, -7), true
+	case WindowMonth:
+		return now.AddDate(0, -1, 0), true
+	case WindowAll:
+		return time.Time{}, true
+	}
+	return time.Time{}, false
+}
+
+// Entry 热门榜中的一条
+type Entry struct {
+	NoteID uint
+	Score  float64
+}
+
+// before 按 (热度, ID) 倒序时是否排在 o 之后
+func (e Entry) before(o Entry) bool {
+	if e.Score != o.Score {
+		return e.Score < o.Score
+	}
+	return e.NoteID < o.NoteID
+}
+
+// Service 维护笔记的互动分 (notes.points，用于按时间窗口排行) 和热门榜
+type Service struct {
+	db    *gorm.DB
+	cache *cache.RedisCache
+	cfg   *config.Config
+}
+
+func New(db *gorm.DB, cache *cache.RedisCache, cfg *config.Config) *Service {
+	return &Service{db: db, cache: cache, cfg: cfg}
+}
+
+// HotScore Reddit 式热度：互动分取对数，再加上发布时间按衰减周期折算的分数。
+// 互动分每多 10 倍，相当于晚发布一个衰减周期；分数本身不随时间变化，只需要在互动变化时更新
+func HotScore(points float64, createdAt time.Time, decay time.Duration) float64 {
+	return math.Log10(1+points) + float64(createdAt.Unix())/decay.Seconds()
+}
+
+// points 收藏、表情回应、浏览 (去重后的浏览数) 按权重相加
+func (s *Service) points(note *models.Note) float64 {
+	reactions := 0
+	for _, n := range note.ReactionCounts {
+		reactions += n
+	}
+	return s.cfg.RankFavoriteWeight*float64(note.FavoriteCount) +
+		s.cfg.RankReactionWeight*float64(reactions) +
+		s.cfg.RankViewWeight*float64(note.ViewCount)
+}
+
+// Refresh 笔记的互动变化后重新计算互动分和热度，私密或已删除的笔记移出热门榜
+func (s *Service) Refresh(ctx context.Context, noteID uint) error {
+	var note models.Note
+	err := s.db.WithContext(ctx).
+		Select("id, is_private, created_at, favorite_count, reaction_counts, view_count").
+		First(&note, noteID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.Remove(ctx, noteID)
+	}
+	if err != nil {
+		return err
+	}
+
+	points := s.points(&note)
+	// UpdateColumn 不会改 updated_at
+	if err := s.db.WithContext(ctx).Model(&models.Note{}).Where("id = ?", noteID).UpdateColumn("points", points).Error; err != nil {
+		return err
+	}
+
+	if note.IsPrivate {
+		return s.Remove(ctx, noteID)
+	}
+	if s.cache == nil {
+		return nil
+	}
+	pipe := s.cache.Pipeline()
+	pipe.ZAdd(ctx, hotKey, redis.Z{Score: HotScore(points, note.CreatedAt, s.cfg.RankHotDecay), Member: noteID})
+	// 只保留最热的 RankHotSize 条
+	pipe.ZRemRangeByRank(ctx, hotKey, 0, -int64(s.cfg.RankHotSize)-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Remove 笔记删除或改为私密后移出热门榜
+func (s *Service) Remove(ctx context.Context, noteID uint) error {
+	if s.cache == nil {
+		return nil
+	}
+	_, err := s.cache.ZRem(ctx, hotKey, noteID)
+	return err
+}
+
+// Rebuild 从数据库重新计算所有公开笔记的互动分，并用最近 RankHotWindow 内的笔记整体替换热门榜。
+// 浏览数定时批量写入，不触发重算，靠定时重建纳入；Redis 数据丢失后也靠它恢复
+func (s *Service) Rebuild(ctx context.Context) error {
+	since := time.Now().Add(-s.cfg.RankHotWindow)
+	var entries []redis.Z
+	var notes []models.Note
+
+	err := s.db.WithContext(ctx).
+		Select("id, created_at, favorite_count, reaction_counts, view_count, points").
+		Where("is_private = ?", false).
+		FindInBatches(&notes, rebuildBatch, func(tx *gorm.DB, _ int) error {
+			for i := range notes {
+				points := s.points(&notes[i])
+				if points != notes[i].Points {
+					if err := s.db.WithContext(ctx).Model(&models.Note{}).Where("id = ?", notes[i].ID).UpdateColumn("points", points).Error; err != nil {
+						return err
+					}
+				}
+				if notes[i].CreatedAt.After(since) {
+					entries = append(entries, redis.Z{Score: HotScore(points, notes[i].CreatedAt, s.cfg.RankHotDecay), Member: notes[i].ID})
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+	if s.cache == nil {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	if len(entries) > s.cfg.RankHotSize {
+		entries = entries[:s.cfg.RankHotSize]
+	}
+
+	// 先写临时 key 再改名，读的一方不会看到写了一半的榜单
+	tmpKey := hotKey + ":tmp"
+	pipe := s.cache.Pipeline()
+	pipe.Del(ctx, tmpKey)
+	if len(entries) > 0 {
+		pipe.ZAdd(ctx, tmpKey, entries...)
+		pipe.Rename(ctx, tmpKey, hotKey)
+		pipe.Del(ctx, emptyKey)
+	} else {
+		pipe.Del(ctx, hotKey)
+		pipe.Set(ctx, emptyKey, "1", emptyTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	zap.L().Info("Hot ranking rebuilt", zap.Int("notes", len(entries)))
+	return nil
+}
+
+// Hot 读取热门榜中排在 after 之后的至多 count 条 (after 为 nil 时从头读)。
+// 榜单为空 (Redis 数据丢失或刚上线) 时返回空列表并在后台重建一次；窗口内确实没有公开笔记时，emptyTTL 内不再重建
+func (s *Service) Hot(ctx context.Context, after *Entry, count int) ([]Entry, error) {
+	if s.cache == nil {
+		return nil, errors.New("hot ranking requires redis")
+	}
+	entries, err := s.hotAfter(ctx, after, count)
+	if err != nil || len(entries) > 0 || after != nil {
+		return entries, err
+	}
+	s.rebuildInBackground(ctx)
+	return entries, nil
+}
+
+// rebuildInBackground 在后台重建热门榜，同一时间只有一个实例在重建
+func (s *Service) rebuildInBackground(ctx context.Context) {
+	empty, err := s.cache.Exists(ctx, emptyKey)
+	if err != nil || empty > 0 {
+		return
+	}
+	locked, err := s.cache.SetNX(ctx, rebuildLockKey, "1", rebuildLockTTL)
+	if err != nil || !locked {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		defer func() { _ = s.cache.Del(ctx, rebuildLockKey) }()
+		if err := s.Rebuild(ctx); err != nil {
+			zap.L().Error("Rebuild hot ranking failed", zap.Error(err))
+		}
+	}()
+}
+
+func (s *Service) hotAfter(ctx context.Context, after *Entry, count int) ([]Entry, error) {
+	max := "+inf"
+	var ties int64
+	if after != nil {
+		max = strconv.FormatFloat(after.Score, 'g', -1, 64)
+		// 热度相同的条目按 member 字符串排序，和 ID 顺序不一致，把它们都取出来再过滤
+		ties, _ = s.cache.ZCount(ctx, hotKey, max, max)
+	}
+
+	zs, err := s.cache.ZRevRangeByScoreWithScores(ctx, hotKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: int64(count) + ties,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(zs))
+	for _, z := range zs {
+		id, _ := strconv.ParseUint(z.Member.(string), 10, 64)
+		e := Entry{NoteID: uint(id), Score: z.Score}
+		if after == nil || e.before(*after) {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[j].before(entries[i]) })
+	if len(entries) > count {
+		entries = entries[:count]
+	}
+	return entries, nil
+}
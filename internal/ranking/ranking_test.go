@@ -0,0 +1,100 @@
+package ranking
+
+import (
+	"context"
+	"note/config"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+	"time"
+)
+
+func TestHotScore(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	decay := 12 * time.Hour
+
+	tests := []struct {
+		name       string
+		points     float64
+		createdAt  time.Time
+		higherThan func() float64
+	}{
+		{"more points beat fewer points at the same time", 99, base, func() float64 { return HotScore(9, base, decay) }},
+		{"newer beats older with the same points", 10, base.Add(time.Hour), func() float64 { return HotScore(10, base, decay) }},
+		{"10x points outweigh a little less than one decay period", 999, base, func() float64 { return HotScore(99, base.Add(decay-time.Minute), decay) }},
+		{"one decay period later outweighs less than 10x points", 0, base.Add(decay), func() float64 { return HotScore(8, base, decay) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, other := HotScore(tt.points, tt.createdAt, decay), tt.higherThan(); got <= other {
+				t.Fatalf("score %v should be higher than %v", got, other)
+			}
+		})
+	}
+
+	// 分数不随读取时间变化
+	if HotScore(5, base, decay) != HotScore(5, base, decay) {
+		t.Fatal("hot score must be deterministic")
+	}
+}
+
+func newTestService(t *testing.T) (*Service, func(key string) bool) {
+	db := testutil.DB(t, &models.Note{})
+	rdb, mr := testutil.Cache(t)
+	cfg := &config.Config{RankHotWindow: 72 * time.Hour, RankHotSize: 100, RankHotDecay: 12 * time.Hour, RankFavoriteWeight: 1}
+	return New(db, rdb, cfg), mr.Exists
+}
+
+// waitFor 后台重建完成 (锁被释放)
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for background rebuild")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHotEmptyWindowRebuildsInBackground(t *testing.T) {
+	s, exists := newTestService(t)
+	ctx := context.Background()
+
+	entries, err := s.Hot(ctx, nil, 10)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Hot() = %v, %v; want empty list", entries, err)
+	}
+	waitFor(t, func() bool { return !exists(rebuildLockKey) })
+	if !exists(emptyKey) {
+		t.Fatal("empty window should leave a sentinel so requests don't rebuild again")
+	}
+
+	// 有了笔记之后，重建会写入榜单并清掉空标记
+	s.db.Create(&models.Note{Title: "t", Content: "c", FavoriteCount: 3})
+	if err := s.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if exists(emptyKey) {
+		t.Fatal("rebuild with entries must clear the empty sentinel")
+	}
+	entries, err = s.Hot(ctx, nil, 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Hot() = %v, %v; want one entry", entries, err)
+	}
+}
+
+func TestHotRebuildsMissingBoard(t *testing.T) {
+	s, exists := newTestService(t)
+	ctx := context.Background()
+	s.db.Create(&models.Note{Title: "t", Content: "c", FavoriteCount: 1})
+
+	// 第一次读不阻塞在重建上
+	if entries, _ := s.Hot(ctx, nil, 10); len(entries) != 0 {
+		t.Fatalf("first read should not wait for the rebuild, got %v", entries)
+	}
+	waitFor(t, func() bool { return !exists(rebuildLockKey) })
+	if entries, _ := s.Hot(ctx, nil, 10); len(entries) != 1 {
+		t.Fatalf("board should be rebuilt in the background, got %v", entries)
+	}
+}
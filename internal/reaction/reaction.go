@@ -0,0 +1,27 @@
+// Package reaction 表情回应的查询，笔记和用户主页的接口共用
+package reaction
+
+import (
+	"note/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Mine 用户对这些笔记点过的 emoji，按点的先后排列
+func Mine(db *gorm.DB, userID uint, noteIDs []uint) (map[uint][]string, error) {
+	mine := make(map[uint][]string)
+	if len(noteIDs) == 0 {
+		return mine, nil
+	}
+	var reactions []models.Reaction
+	if err := db.Select("note_id, emoji").
+		Where("user_id = ? AND note_id IN ?", userID, noteIDs).
+		Order("id ASC").
+		Find(&reactions).Error; err != nil {
+		return mine, err
+	}
+	for _, r := range reactions {
+		mine[r.NoteID] = append(mine[r.NoteID], r.Emoji)
+	}
+	return mine, nil
+}
@@ -0,0 +1,44 @@
+package reaction
+
+import (
+	"note/internal/models"
+	"note/internal/testutil"
+	"reflect"
+	"testing"
+)
+
+func TestMine(t *testing.T) {
+	db := testutil.DB(t, &models.Reaction{})
+	for _, r := range []models.Reaction{
+		{UserID: 1, NoteID: 10, Emoji: "👍"},
+		{UserID: 2, NoteID: 10, Emoji: "🎉"},
+		{UserID: 1, NoteID: 10, Emoji: "❤️"},
+		{UserID: 1, NoteID: 11, Emoji: "🎉"},
+		{UserID: 1, NoteID: 12, Emoji: "👍"},
+	} {
+		db.Create(&r)
+	}
+
+	tests := []struct {
+		name    string
+		userID  uint
+		noteIDs []uint
+		want    map[uint][]string
+	}{
+		{"no notes", 1, nil, map[uint][]string{}},
+		{"in reaction order", 1, []uint{10, 11}, map[uint][]string{10: {"👍", "❤️"}, 11: {"🎉"}}},
+		{"only the viewer's reactions", 2, []uint{10, 11, 12}, map[uint][]string{10: {"🎉"}}},
+		{"no reactions", 3, []uint{10}, map[uint][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Mine(db, tt.userID, tt.noteIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Mine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
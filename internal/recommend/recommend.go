@@ -0,0 +1,321 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"note/config"
+	"note/internal/infra/cache"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"note/internal/ranking"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 兴趣向量中各类互动的权重
+const (
+	favoriteWeight = 3
+	reactionWeight = 2
+	viewWeight     = 1
+)
+
+// Service 为用户生成"为你推荐"列表：
+// 用收藏、表情回应、浏览过的笔记向量加权得到兴趣向量，检索相似的公开笔记，再按比例混入最新和热门笔记用于探索。
+// 一次生成的列表作为会话存到 Redis，翻页时顺序稳定
+type Service struct {
+	db      *gorm.DB
+	cache   *cache.RedisCache
+	index   vector.VectorIndex
+	ranking *ranking.Service
+	cfg     *config.Config
+}
+
+func New(db *gorm.DB, cache *cache.RedisCache, index vector.VectorIndex, ranking *ranking.Service, cfg *config.Config) *Service {
+	return &Service{db: db, cache: cache, index: index, ranking: ranking, cfg: cfg}
+}
+
+// Page 推荐列表中的一页
+type Page struct {
+	Session int64 // 会话 ID，会话过期后重新生成，Offset 从 0 开始
+	Offset  int
+	NoteIDs []uint
+	HasMore bool
+}
+
+func sessionKey(userID uint, session int64) string {
+	return fmt.Sprintf("recommend:session:%d:%d", userID, session)
+}
+
+// Page 读取会话中从 offset 开始的至多 count 条；session 为 0 或已过期时重新生成列表
+func (s *Service) Page(ctx context.Context, userID uint, session int64, offset, count int) (*Page, error) {
+	if s.cache != nil && session != 0 {
+		key := sessionKey(userID, session)
+		// 每翻一页顺延会话的过期时间；返回 false 说明会话已经过期
+		if ok, err := s.cache.Expire(ctx, key, s.cfg.RecommendSessionTTL); err == nil && ok {
+			vals, err := s.cache.LRange(ctx, key, int64(offset), int64(offset+count))
+			if err != nil {
+				return nil, err
+			}
+			ids := make([]uint, 0, len(vals))
+			for _, v := range vals {
+				id, _ := strconv.ParseUint(v, 10, 64)
+				ids = append(ids, uint(id))
+			}
+			return newPage(session, offset, ids, count), nil
+		}
+	}
+
+	ids, err := s.Build(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache == nil {
+		// 没有 Redis 存不了会话，每页都重新生成再从 offset 截取；
+		// 两次生成之间列表可能有变化，顺序不保证稳定，但翻页能走到头
+		if session == 0 {
+			session = time.Now().UnixMicro()
+		}
+		start := min(max(offset, 0), len(ids))
+		return newPage(session, start, ids[start:min(start+count+1, len(ids))], count), nil
+	}
+	session = time.Now().UnixMicro()
+	if len(ids) > 0 {
+		key := sessionKey(userID, session)
+		values := make([]interface{}, len(ids))
+		for i, id := range ids {
+			values[i] = id
+		}
+		pipe := s.cache.Pipeline()
+		pipe.RPush(ctx, key, values...)
+		pipe.Expire(ctx, key, s.cfg.RecommendSessionTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			zap.L().Warn("Save recommend session failed", zap.Uint("uid", userID), zap.Error(err))
+		}
+	}
+	if len(ids) > count+1 {
+		ids = ids[:count+1]
+	}
+	return newPage(session, 0, ids, count), nil
+}
+
+// newPage ids 最多比 count 多一条，用于判断是否还有下一页
+func newPage(session int64, offset int, ids []uint, count int) *Page {
+	page := &Page{Session: session, Offset: offset, NoteIDs: ids}
+	if len(ids) > count {
+		page.NoteIDs = ids[:count]
+		page.HasMore = true
+	}
+	return page
+}
+
+// Build 生成完整的推荐列表 (笔记 ID，按推荐顺序)
+func (s *Service) Build(ctx context.Context, userID uint) ([]uint, error) {
+	blocked, err := s.BlockedAuthors(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	excludeOwners := append(blocked, userID)
+
+	signals, err := s.signals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make([]uint, 0, len(signals))
+	for id := range signals {
+		seen = append(seen, id)
+	}
+
+	pool := s.cfg.RecommendPoolSize
+	var similar []uint
+	if interest := s.interest(ctx, signals); interest != nil {
+		hits, err := s.index.Search(ctx, interest, uint64(pool), vector.Filter{
+			PublicOnly:      true,
+			ExcludeIDs:      seen,
+			ExcludeOwnerIDs: excludeOwners,
+		})
+		if err != nil {
+			// 向量检索失败时只用探索内容
+			zap.L().Warn("Recommend vector search failed", zap.Uint("uid", userID), zap.Error(err))
+		}
+		for _, hit := range hits {
+			similar = append(similar, hit.ID)
+		}
+	}
+
+	explore, err := s.explore(ctx, excludeOwners, signals, pool)
+	if err != nil {
+		return nil, err
+	}
+	return mix(similar, explore, s.cfg.RecommendExploreRatio, pool), nil
+}
+
+// BlockedAuthors 用户屏蔽的作者
+func (s *Service) BlockedAuthors(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := s.db.WithContext(ctx).Model(&models.UserBlock{}).
+		Where("blocker_id = ?", userID).
+		Pluck("blocked_id", &ids).Error
+	return ids, err
+}
+
+// signals 用户最近收藏、表情回应、浏览过的笔记及其权重，同一篇笔记的多种互动权重相加
+func (s *Service) signals(ctx context.Context, userID uint) (map[uint]float32, error) {
+	limit := s.cfg.RecommendSignalLimit
+	signals := make(map[uint]float32)
+
+	sources := []struct {
+		model  interface{}
+		order  string
+		weight float32
+	}{
+		{&models.Favorite{}, "created_at DESC", favoriteWeight},
+		{&models.Reaction{}, "created_at DESC", reactionWeight},
+		{&models.History{}, "updated_at DESC", viewWeight},
+	}
+	for _, src := range sources {
+		var ids []uint
+		err := s.db.WithContext(ctx).Model(src.model).
+			Where("user_id = ?", userID).
+			Order(src.order).
+			Limit(limit).
+			Pluck("note_id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			signals[id] += src.weight
+		}
+	}
+	return signals, nil
+}
+
+// interest 互动过的笔记向量 (归一化后) 的加权平均，没有可用向量时返回 nil
+func (s *Service) interest(ctx context.Context, signals map[uint]float32) []float32 {
+	if len(signals) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(signals))
+	for id := range signals {
+		ids = append(ids, id)
+	}
+	points, err := s.index.Retrieve(ctx, ids, true)
+	if err != nil {
+		zap.L().Warn("Retrieve interest vectors failed", zap.Error(err))
+		return nil
+	}
+
+	var sum []float32
+	for _, p := range points {
+		if len(p.Vector) == 0 {
+			continue
+		}
+		if sum == nil {
+			sum = make([]float32, len(p.Vector))
+		}
+		var norm float64
+		for _, x := range p.Vector {
+			norm += float64(x) * float64(x)
+		}
+		if norm == 0 || len(p.Vector) != len(sum) {
+			continue
+		}
+		scale := signals[p.ID] / float32(math.Sqrt(norm))
+		for i, x := range p.Vector {
+			sum[i] += x * scale
+		}
+	}
+	return sum
+}
+
+// explore 最新和热门的公开笔记交替排列，去掉看过的和屏蔽作者的
+func (s *Service) explore(ctx context.Context, excludeOwners []uint, seen map[uint]float32, limit int) ([]uint, error) {
+	var fresh []uint
+	err := s.db.WithContext(ctx).Model(&models.Note{}).
+		Where("is_private = ? AND user_id NOT IN ?", false, excludeOwners).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Pluck("id", &fresh).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var hot []uint
+	entries, err := s.ranking.Hot(ctx, nil, limit)
+	if err != nil {
+		zap.L().Warn("Read hot ranking for recommend failed", zap.Error(err))
+	} else if len(entries) > 0 {
+		ids := make([]uint, len(entries))
+		for i, e := range entries {
+			ids[i] = e.NoteID
+		}
+		var visible []uint
+		err := s.db.WithContext(ctx).Model(&models.Note{}).
+			Where("id IN ? AND is_private = ? AND user_id NOT IN ?", ids, false, excludeOwners).
+			Pluck("id", &visible).Error
+		if err != nil {
+			return nil, err
+		}
+		ok := make(map[uint]bool, len(visible))
+		for _, id := range visible {
+			ok[id] = true
+		}
+		for _, id := range ids {
+			if ok[id] {
+				hot = append(hot, id)
+			}
+		}
+	}
+
+	added := make(map[uint]bool)
+	out := make([]uint, 0, len(fresh)+len(hot))
+	for i := 0; i < len(fresh) || i < len(hot); i++ {
+		for _, list := range [][]uint{hot, fresh} {
+			if i >= len(list) {
+				continue
+			}
+			id := list[i]
+			if _, ok := seen[id]; ok || added[id] {
+				continue
+			}
+			added[id] = true
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// mix 按 ratio 的比例在相似结果中穿插探索条目 (例如 0.2 即每 5 条有 1 条)，一边不够时用另一边补齐，去重后最多 size 条
+func mix(similar, explore []uint, ratio float64, size int) []uint {
+	every := 0
+	if ratio > 0 {
+		every = int(math.Round(1 / ratio))
+		if every < 1 {
+			every = 1
+		}
+	}
+
+	added := make(map[uint]bool, size)
+	out := make([]uint, 0, size)
+	si, ei := 0, 0
+	for len(out) < size && (si < len(similar) || ei < len(explore)) {
+		useExplore := ei < len(explore) &&
+			(si >= len(similar) || (every > 0 && (len(out)+1)%every == 0))
+		var id uint
+		if useExplore {
+			id = explore[ei]
+			ei++
+		} else {
+			id = similar[si]
+			si++
+		}
+		if !added[id] {
+			added[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package recommend
+
+import (
+	"context"
+	"note/config"
+	"note/internal/infra/cache"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"note/internal/ranking"
+	"note/internal/testutil"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMix(t *testing.T) {
+	tests := []struct {
+		name             string
+		similar, explore []uint
+		ratio            float64
+		size             int
+		want             []uint
+	}{
+		{"every other item explores", []uint{1, 2, 3}, []uint{10, 11, 12}, 0.5, 6, []uint{1, 10, 2, 11, 3, 12}},
+		{"one in five explores", []uint{1, 2, 3, 4, 5}, []uint{10}, 0.2, 6, []uint{1, 2, 3, 4, 10, 5}},
+		{"zero ratio appends explore after similar", []uint{1, 2}, []uint{10, 11}, 0, 4, []uint{1, 2, 10, 11}},
+		{"explore fills in when similar runs out", []uint{1}, []uint{10, 11, 12}, 0.2, 4, []uint{1, 10, 11, 12}},
+		{"duplicates across lists appear once", []uint{1, 10}, []uint{10, 11}, 0.5, 4, []uint{1, 10, 11}},
+		{"capped at size", []uint{1, 2, 3}, []uint{10, 11}, 0.5, 3, []uint{1, 10, 2}},
+		{"nothing to mix", nil, nil, 0.2, 5, []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mix(tt.similar, tt.explore, tt.ratio, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("mix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestService(t *testing.T, rdb *cache.RedisCache) *Service {
+	t.Helper()
+	db := testutil.DB(t, &models.Note{}, &models.Favorite{}, &models.Reaction{}, &models.History{}, &models.UserBlock{})
+	index, err := vector.NewLocalIndex(t.TempDir(), "notes", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = index.Close() })
+	cfg := &config.Config{
+		RankHotWindow:         72 * time.Hour,
+		RankHotSize:           100,
+		RankHotDecay:          12 * time.Hour,
+		RankFavoriteWeight:    1,
+		RecommendPoolSize:     50,
+		RecommendExploreRatio: 0.2,
+		RecommendSessionTTL:   time.Hour,
+		RecommendSignalLimit:  100,
+	}
+	return New(db, rdb, index, ranking.New(db, rdb, cfg), cfg)
+}
+
+func TestExplore(t *testing.T) {
+	rdb, _ := testutil.Cache(t)
+	s := newTestService(t, rdb)
+	ctx := context.Background()
+
+	const viewer, blocked uint = 1, 3
+	now := time.Now()
+	// 最热的笔记最旧，按时间排在最后
+	hot := models.Note{UserID: 2, Title: "hot", Content: "c", FavoriteCount: 50, CreatedAt: now.Add(-time.Hour)}
+	s.db.Create(&hot)
+	var fresh []uint
+	for i := range 3 {
+		n := models.Note{UserID: 2, Title: "fresh", Content: "c", CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		s.db.Create(&n)
+		fresh = append(fresh, n.ID)
+	}
+	private := models.Note{UserID: 2, Title: "private", Content: "c", IsPrivate: true, FavoriteCount: 90, CreatedAt: now}
+	fromBlocked := models.Note{UserID: blocked, Title: "blocked", Content: "c", FavoriteCount: 90, CreatedAt: now}
+	own := models.Note{UserID: viewer, Title: "own", Content: "c", CreatedAt: now}
+	seen := models.Note{UserID: 2, Title: "seen", Content: "c", CreatedAt: now.Add(-2 * time.Hour)}
+	for _, n := range []*models.Note{&private, &fromBlocked, &own, &seen} {
+		s.db.Create(n)
+	}
+	if err := s.ranking.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.explore(ctx, []uint{blocked, viewer}, map[uint]float32{seen.ID: 1}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || got[0] != hot.ID {
+		t.Fatalf("explore() = %v, want the hottest note %d first", got, hot.ID)
+	}
+	want := append([]uint{hot.ID}, fresh...)
+	slices.Sort(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("explore() = %v, want %v", got, want)
+	}
+}
+
+// pageAll 从头翻到最后一页
+func pageAll(t *testing.T, s *Service, userID uint, count int) []uint {
+	t.Helper()
+	var session int64
+	var all []uint
+	offset := 0
+	for range 20 {
+		page, err := s.Page(context.Background(), userID, session, offset, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, page.NoteIDs...)
+		if !page.HasMore {
+			return all
+		}
+		session, offset = page.Session, page.Offset+len(page.NoteIDs)
+	}
+	t.Fatal("paging never reached the end")
+	return nil
+}
+
+func TestPage(t *testing.T) {
+	for _, withRedis := range []bool{true, false} {
+		name := "without redis"
+		if withRedis {
+			name = "with redis"
+		}
+		t.Run(name, func(t *testing.T) {
+			var rdb *cache.RedisCache
+			if withRedis {
+				rdb, _ = testutil.Cache(t)
+			}
+			s := newTestService(t, rdb)
+			var want []uint
+			for range 7 {
+				n := models.Note{UserID: 2, Title: "t", Content: "c"}
+				s.db.Create(&n)
+				want = append(want, n.ID)
+			}
+
+			got := pageAll(t, s, 1, 3)
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("paged ids = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPageSessionIsStable(t *testing.T) {
+	rdb, _ := testutil.Cache(t)
+	s := newTestService(t, rdb)
+	ctx := context.Background()
+	for range 4 {
+		s.db.Create(&models.Note{UserID: 2, Title: "t", Content: "c"})
+	}
+
+	first, err := s.Page(ctx, 1, 0, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 会话生成之后发布的笔记不会插进后面的页
+	late := models.Note{UserID: 2, Title: "late", Content: "c"}
+	s.db.Create(&late)
+	second, err := s.Page(ctx, 1, first.Session, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Session != first.Session || second.HasMore || len(second.NoteIDs) != 2 {
+		t.Fatalf("second page = %+v", second)
+	}
+	if slices.Contains(second.NoteIDs, late.ID) || slices.ContainsFunc(second.NoteIDs, func(id uint) bool { return slices.Contains(first.NoteIDs, id) }) {
+		t.Fatalf("second page %v overlaps first %v or includes the late note", second.NoteIDs, first.NoteIDs)
+	}
+}
@@ -3,6 +3,9 @@ package svc
 import (
 	"context"
 	"note/config"
+	"note/internal/analytics"
+	"note/internal/digest"
+	"note/internal/indexer"
 	"note/internal/infra/ai"
 	"note/internal/infra/cache"
 	"note/internal/infra/db"
@@ -10,6 +13,11 @@ import (
 	"note/internal/infra/storage"
 	"note/internal/infra/vector"
 	"note/internal/middleware"
+	"note/internal/pagination"
+	"note/internal/ranking"
+	"note/internal/recommend"
+	"note/internal/topics"
+	"note/internal/usage"
 	"note/internal/utils"
 	"os"
 	"time"
@@ -25,8 +33,20 @@ type ServiceContext struct {
 	Cache  *cache.RedisCache
 	Rabbit *mq2.RabbitMQ
 	AI     *ai.AIService
-	Qdrant *vector.QdrantService
+	Vector vector.VectorIndex
 	Minio  *storage.FileStorage
+	Pager  *pagination.Pager
+
+	Usage *usage.Meter
+
+	Indexer     *indexer.Indexer
+	Reindexer   *indexer.Reindexer
+	VectorAdmin vector.Admin
+	Topics      *topics.Service
+	Digest      *digest.Service
+	Ranking     *ranking.Service
+	Recommend   *recommend.Service
+	Analytics   *analytics.Service
 
 	// 私有字段，用于存储需要关闭的资源
 	tracerProvider *trace.TracerProvider
@@ -50,11 +70,40 @@ func NewServiceContext(cfg *config.Config) *ServiceContext {
 		zap.L().Warn("RabbitMQ connection failed", zap.Error(err))
 	}
 
-	qdrant := vector.NewQdrantService(cfg.QdrantHost, cfg.QdrantPort, "notes_collection", cfg.QdrantAPIKey)
+	usageMeter := usage.NewMeter(dbConn, rdb, cfg)
+	aiService, err := ai.NewAIService(cfg, rdb, usageMeter)
+	if err != nil {
+		zap.L().Fatal("failed to init ai service", zap.Error(err))
+	}
+	zap.L().Info("AI service initialized",
+		zap.String("chat_provider", cfg.AIChatProvider),
+		zap.String("embed_provider", cfg.AIEmbedProvider),
+		zap.String("embed_model", aiService.EmbedModel()))
+
+	// 向量维度跟随当前向量模型，已有索引维度不一致时直接退出，避免写入无法检索的数据
+	vectorIndex, err := vector.New(cfg, aiService.EmbedDimension())
+	if err != nil {
+		zap.L().Fatal("failed to init vector index",
+			zap.String("backend", cfg.VectorBackend),
+			zap.String("collection", cfg.VectorCollection),
+			zap.Error(err))
+	}
+
+	vectorAdmin, err := vector.NewAdmin(cfg)
+	if err != nil {
+		zap.L().Fatal("failed to init vector admin", zap.Error(err))
+	}
+
+	noteIndexer := indexer.New(dbConn, aiService, vectorIndex, rdb)
+	reindexer := indexer.NewReindexer(dbConn, aiService, rdb, vectorAdmin, cfg.VectorCollection, vectorIndex)
+
+	topicService := topics.New(dbConn, aiService, vectorIndex, rdb, cfg)
 
-	aiService := ai.NewAIService(cfg)
+	rankingService := ranking.New(dbConn, rdb, cfg)
 
-	consumer := mq2.NewConsumer(dbConn, rdb, rabbit, aiService, qdrant)
+	analyticsService := analytics.New(dbConn, rdb, cfg)
+
+	consumer := mq2.NewConsumer(dbConn, rdb, rabbit, aiService, noteIndexer, rankingService, analyticsService, cfg)
 
 	minioSvc, _ := storage.NewFileStorage(
 		cfg.MinioEndpoint,  // 内部连接用: "minio:9000"
@@ -81,8 +130,18 @@ func NewServiceContext(cfg *config.Config) *ServiceContext {
 		Cache:          rdb,
 		Rabbit:         rabbit,
 		AI:             aiService,
-		Qdrant:         qdrant,
+		Usage:          usageMeter,
+		Vector:         vectorIndex,
+		Indexer:        noteIndexer,
+		Reindexer:      reindexer,
+		VectorAdmin:    vectorAdmin,
+		Topics:         topicService,
+		Digest:         digest.New(dbConn, aiService, rdb),
+		Ranking:        rankingService,
+		Recommend:      recommend.New(dbConn, rdb, vectorIndex, rankingService, cfg),
+		Analytics:      analyticsService,
 		Minio:          minioSvc,
+		Pager:          pagination.New(cfg),
 		Consumer:       consumer,
 		tracerProvider: tp,
 	}
@@ -98,6 +157,13 @@ func (s *ServiceContext) Close() {
 		}
 	}
 
+	// 关闭向量索引 (local 模式下会把未落盘的数据写入文件)
+	if s.Vector != nil {
+		if err := s.Vector.Close(); err != nil {
+			zap.L().Error("Vector index close error", zap.Error(err))
+		}
+	}
+
 	// 关闭 RabbitMQ
 	if s.Rabbit != nil {
 		s.Rabbit.Close()
@@ -117,6 +117,7 @@ func (h *NoteTag) CreateTag(c *gin.Context) {
 
 	tag := models.Tag{
 		Name:   req.Name,
+		Topic:  models.TopicName(req.Name),
 		Color:  req.Color,
 		UserID: userID,
 	}
@@ -157,6 +158,7 @@ func (h *NoteTag) UpdateTag(c *gin.Context) {
 
 	if err := h.svc.DB.Model(&tag).Updates(models.Tag{
 		Name:  req.Name,
+		Topic: models.TopicName(req.Name),
 		Color: req.Color,
 	}).Error; err != nil {
 		utils.Error(c, http.StatusBadRequest, "更新失败，可能标签名已存在")
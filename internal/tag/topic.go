@@ -0,0 +1,25 @@
+package tag
+
+import (
+	"note/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// BackfillTopics 为还没有话题名的老标签补算 (启动时执行，已经补过的不会重复处理)
+func BackfillTopics(db *gorm.DB) error {
+	var tags []models.Tag
+	return db.Select("id, name").Where("topic = ? AND name <> ?", "", "").
+		FindInBatches(&tags, 500, func(tx *gorm.DB, _ int) error {
+			for _, t := range tags {
+				topic := models.TopicName(t.Name)
+				if topic == "" {
+					continue
+				}
+				if err := db.Model(&models.Tag{}).Where("id = ?", t.ID).UpdateColumn("topic", topic).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
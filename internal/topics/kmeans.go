@@ -0,0 +1,133 @@
+package topics
+
+import (
+	"math"
+	"math/rand"
+)
+
+// kmeans 球面 k-means：向量先归一化，用余弦相似度 (点积) 分配，k-means++ 初始化。
+// seed 固定时结果可复现，同一用户重复聚类不会无故变化
+func kmeans(vectors [][]float32, k, iterations int, seed int64) (assign []int, centroids [][]float32) {
+	n := len(vectors)
+	if k > n {
+		k = n
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+	for _, v := range vectors {
+		normalize(v)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	centroids = initCentroids(vectors, k, rng)
+	assign = make([]int, n)
+
+	for iter := 0; iter < iterations; iter++ {
+		changed := false
+		for i, v := range vectors {
+			if best, _ := nearest(v, centroids); assign[i] != best {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if iter > 0 && !changed {
+			break
+		}
+
+		dim := len(vectors[0])
+		sums := make([][]float32, k)
+		for c := range sums {
+			sums[c] = make([]float32, dim)
+		}
+		counts := make([]int, k)
+		for i, v := range vectors {
+			c := assign[i]
+			counts[c]++
+			for d := range v {
+				sums[c][d] += v[d]
+			}
+		}
+		for c := range centroids {
+			// 空簇保留原来的中心
+			if counts[c] == 0 {
+				continue
+			}
+			normalize(sums[c])
+			centroids[c] = sums[c]
+		}
+	}
+	return assign, centroids
+}
+
+// initCentroids k-means++：离已有中心越远的点越可能被选为下一个中心
+func initCentroids(vectors [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := make([][]float32, 0, k)
+	centroids = append(centroids, clone(vectors[rng.Intn(len(vectors))]))
+
+	dist := make([]float64, len(vectors))
+	for len(centroids) < k {
+		var total float64
+		for i, v := range vectors {
+			_, sim := nearest(v, centroids)
+			d := math.Max(0, 1-float64(sim))
+			dist[i] = d * d
+			total += dist[i]
+		}
+		if total == 0 {
+			// 剩下的点都和已有中心重合
+			break
+		}
+		target := rng.Float64() * total
+		idx := len(vectors) - 1
+		for i, d := range dist {
+			target -= d
+			if target <= 0 {
+				idx = i
+				break
+			}
+		}
+		centroids = append(centroids, clone(vectors[idx]))
+	}
+	return centroids
+}
+
+// nearest 返回最相似的中心及相似度
+func nearest(v []float32, centroids [][]float32) (int, float32) {
+	best, bestSim := 0, float32(-2)
+	for c, centroid := range centroids {
+		if sim := dot(v, centroid); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	return best, bestSim
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func normalize(v []float32) {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / norm)
+	}
+}
+
+func clone(v []float32) []float32 {
+	return append([]float32(nil), v...)
+}
@@ -0,0 +1,72 @@
+package topics
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// blobs 围绕每个方向生成 n 个带噪声的向量
+func blobs(directions [][]float32, n int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	var vectors [][]float32
+	for _, dir := range directions {
+		for range n {
+			v := clone(dir)
+			for d := range v {
+				v[d] += float32(rng.NormFloat64() * 0.05)
+			}
+			vectors = append(vectors, v)
+		}
+	}
+	return vectors
+}
+
+func TestKMeans(t *testing.T) {
+	directions := [][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	tests := []struct {
+		name       string
+		vectors    [][]float32
+		k          int
+		wantGroups int
+	}{
+		{"separated clusters", blobs(directions, 10, 1), 3, 3},
+		{"k larger than n", blobs(directions[:1], 2, 2), 5, 2},
+		{"identical vectors collapse", [][]float32{{1, 1}, {1, 1}, {2, 2}}, 3, 1},
+		{"k zero", blobs(directions, 2, 3), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assign, centroids := kmeans(tt.vectors, tt.k, 20, 42)
+			groups := make(map[int]bool)
+			for _, c := range assign {
+				groups[c] = true
+			}
+			if len(groups) != tt.wantGroups {
+				t.Fatalf("got %d groups (%d centroids), want %d", len(groups), len(centroids), tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestKMeansSeparatesBlobs(t *testing.T) {
+	vectors := blobs([][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}, 10, 1)
+	assign, _ := kmeans(vectors, 3, 20, 42)
+	// 同一团的向量分到同一簇，不同团分到不同簇
+	for b := range 3 {
+		for i := b * 10; i < (b+1)*10; i++ {
+			if assign[i] != assign[b*10] {
+				t.Fatalf("blob %d split: %v", b, assign)
+			}
+		}
+	}
+	if assign[0] == assign[10] || assign[10] == assign[20] || assign[0] == assign[20] {
+		t.Fatalf("blobs merged: %v", assign)
+	}
+
+	// 同样的输入和 seed 结果相同
+	again, _ := kmeans(blobs([][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}, 10, 1), 3, 20, 42)
+	if !reflect.DeepEqual(assign, again) {
+		t.Fatalf("not reproducible: %v vs %v", assign, again)
+	}
+}
@@ -0,0 +1,335 @@
+package topics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"note/config"
+	"note/internal/infra/ai"
+	"note/internal/infra/cache"
+	"note/internal/infra/vector"
+	"note/internal/models"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// dirtyKey 需要重新聚类的用户 (SET)
+	dirtyKey = "topics:dirty"
+
+	kmeansIterations = 30
+	retrieveBatch    = 256
+	// 新旧簇中心相似度达到这个值视为同一个主题，沿用原来的名字和标签，不再调用 AI 命名
+	reuseThreshold = 0.9
+	// 命名时最多给模型看多少个标题
+	namingTitles = 20
+	// 少于这么多篇笔记的簇不算主题
+	minTopicSize = 2
+)
+
+// Service 按用户对笔记向量做聚类，生成主题
+type Service struct {
+	db    *gorm.DB
+	ai    *ai.AIService
+	index vector.VectorIndex
+	cache *cache.RedisCache
+	cfg   *config.Config
+}
+
+func New(db *gorm.DB, ai *ai.AIService, index vector.VectorIndex, cache *cache.RedisCache, cfg *config.Config) *Service {
+	return &Service{db: db, ai: ai, index: index, cache: cache, cfg: cfg}
+}
+
+// NoteIndexed 笔记向量写入后调用：和已有主题足够接近时直接归入，否则标记该用户下次定时任务时重新聚类
+func (s *Service) NoteIndexed(ctx context.Context, note *models.Note) {
+	if err := s.assign(ctx, note); err != nil {
+		s.MarkDirty(ctx, note.UserID)
+	}
+}
+
+// NoteDeleted 笔记删除后移出所属主题并更新主题大小
+func (s *Service) NoteDeleted(ctx context.Context, noteID, userID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id = ?", noteID).Delete(&models.NoteTopic{}).Error; err != nil {
+			return err
+		}
+		return s.recountSizes(tx, userID)
+	})
+}
+
+var errNoCloseTopic = errors.New("no close topic")
+
+func (s *Service) assign(ctx context.Context, note *models.Note) error {
+	var topics []models.Topic
+	if err := s.db.WithContext(ctx).Where("user_id = ?", note.UserID).Find(&topics).Error; err != nil {
+		return err
+	}
+	if len(topics) == 0 {
+		return errNoCloseTopic
+	}
+	points, err := s.index.Retrieve(ctx, []uint{note.ID}, true)
+	if err != nil || len(points) == 0 {
+		return fmt.Errorf("retrieve vector of note %d failed: %v", note.ID, err)
+	}
+	vec := points[0].Vector
+	normalize(vec)
+
+	centroids := make([][]float32, len(topics))
+	for i, t := range topics {
+		centroids[i] = t.Centroid
+	}
+	best, sim := nearest(vec, centroids)
+	if sim < s.cfg.TopicAssignThreshold {
+		return errNoCloseTopic
+	}
+
+	topic := topics[best]
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		link := models.NoteTopic{NoteID: note.ID, TopicID: topic.ID, UserID: note.UserID, Similarity: sim}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&link).Error; err != nil {
+			return err
+		}
+		return s.recountSizes(tx, note.UserID)
+	})
+}
+
+// MarkDirty 标记用户需要重新聚类
+func (s *Service) MarkDirty(ctx context.Context, userID uint) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.SAdd(ctx, dirtyKey, userID); err != nil {
+		zap.L().Warn("Mark topics dirty failed", zap.Uint("uid", userID), zap.Error(err))
+	}
+}
+
+// RunDirty 为被标记的用户重新聚类，每次最多处理 limit 个用户
+func (s *Service) RunDirty(ctx context.Context, limit int) error {
+	if s.cache == nil {
+		return nil
+	}
+	members, err := s.cache.SPopN(ctx, dirtyKey, int64(limit))
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		userID, _ := strconv.ParseUint(m, 10, 64)
+		if userID == 0 {
+			continue
+		}
+		if err := s.Recluster(ctx, uint(userID)); err != nil {
+			zap.L().Error("Recluster topics failed", zap.Uint64("uid", userID), zap.Error(err))
+			// 放回去，下次再试
+			s.MarkDirty(ctx, uint(userID))
+		}
+	}
+	return nil
+}
+
+type cluster struct {
+	centroid []float32
+	members  []uint
+	sims     map[uint]float32
+}
+
+// Recluster 对用户的全部笔记重新聚类。和旧主题中心足够接近的簇沿用原来的主题 (名字、已转换的标签都保留)，
+// 只有新出现的簇才调用 AI 命名
+func (s *Service) Recluster(ctx context.Context, userID uint) error {
+	ids, vectors, err := s.loadVectors(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(ids) < s.cfg.TopicMinNotes {
+		return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ?", userID).Delete(&models.NoteTopic{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("user_id = ?", userID).Delete(&models.Topic{}).Error
+		})
+	}
+
+	k := int(math.Round(math.Sqrt(float64(len(ids)) / 2)))
+	if k < 2 {
+		k = 2
+	}
+	if k > s.cfg.TopicMaxClusters {
+		k = s.cfg.TopicMaxClusters
+	}
+	assign, centroids := kmeans(vectors, k, kmeansIterations, int64(userID))
+
+	clusters := make([]*cluster, len(centroids))
+	for c := range clusters {
+		clusters[c] = &cluster{centroid: centroids[c], sims: make(map[uint]float32)}
+	}
+	for i, c := range assign {
+		clusters[c].members = append(clusters[c].members, ids[i])
+		clusters[c].sims[ids[i]] = dot(vectors[i], centroids[c])
+	}
+
+	var old []models.Topic
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&old).Error; err != nil {
+		return err
+	}
+	reused := make(map[uint]bool)
+
+	var topics []models.Topic
+	var links []models.NoteTopic
+	for _, cl := range clusters {
+		if len(cl.members) < minTopicSize {
+			continue
+		}
+
+		topic := models.Topic{UserID: userID}
+		bestSim := float32(reuseThreshold)
+		for _, t := range old {
+			if sim := dot(cl.centroid, t.Centroid); !reused[t.ID] && sim >= bestSim {
+				topic, bestSim = t, sim
+			}
+		}
+		if topic.ID != 0 {
+			reused[topic.ID] = true
+		} else if err := s.name(ctx, &topic, cl); err != nil {
+			return err
+		}
+		topic.Centroid = cl.centroid
+		topic.Size = len(cl.members)
+		topics = append(topics, topic)
+
+		for _, id := range cl.members {
+			links = append(links, models.NoteTopic{NoteID: id, UserID: userID, Similarity: cl.sims[id]})
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stale []uint
+		for _, t := range old {
+			if !reused[t.ID] {
+				stale = append(stale, t.ID)
+			}
+		}
+		if len(stale) > 0 {
+			if err := tx.Delete(&models.Topic{}, stale).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.NoteTopic{}).Error; err != nil {
+			return err
+		}
+
+		offset := 0
+		for i := range topics {
+			if err := tx.Save(&topics[i]).Error; err != nil {
+				return err
+			}
+			for j := 0; j < topics[i].Size; j++ {
+				links[offset+j].TopicID = topics[i].ID
+			}
+			offset += topics[i].Size
+		}
+		if len(links) > 0 {
+			return tx.CreateInBatches(links, 500).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("Topics reclustered",
+		zap.Uint("uid", userID),
+		zap.Int("notes", len(ids)),
+		zap.Int("topics", len(topics)),
+		zap.Int("reused", len(reused)))
+	return nil
+}
+
+// name 用离中心最近的若干篇笔记标题让 AI 命名，额度用完或调用失败时退回用最近一篇的标题
+func (s *Service) name(ctx context.Context, topic *models.Topic, cl *cluster) error {
+	members := append([]uint(nil), cl.members...)
+	sort.Slice(members, func(i, j int) bool { return cl.sims[members[i]] > cl.sims[members[j]] })
+	if len(members) > namingTitles {
+		members = members[:namingTitles]
+	}
+
+	var notes []models.Note
+	if err := s.db.WithContext(ctx).Select("id, title").Where("id IN ?", members).Find(&notes).Error; err != nil {
+		return err
+	}
+	titleOf := make(map[uint]string, len(notes))
+	for _, n := range notes {
+		titleOf[n.ID] = n.Title
+	}
+	titles := make([]string, 0, len(members))
+	for _, id := range members {
+		if t := titleOf[id]; t != "" {
+			titles = append(titles, t)
+		}
+	}
+	if len(titles) == 0 {
+		topic.Name = "未命名主题"
+		return nil
+	}
+
+	var setting models.UserSetting
+	s.db.WithContext(ctx).Where("user_id = ?", topic.UserID).Limit(1).Find(&setting)
+	gen, err := s.ai.NameTopic(topic.UserID, titles, ai.Preferences{Language: setting.AILanguage, Style: setting.AIStyle})
+	if err != nil || gen.Content == "" {
+		if err != nil && !errors.Is(err, ai.ErrQuotaExceeded) {
+			zap.L().Warn("Name topic failed", zap.Uint("uid", topic.UserID), zap.Error(err))
+		}
+		topic.Name = truncateRunes(titles[0], 64)
+		return nil
+	}
+	topic.Name = truncateRunes(gen.Content, 64)
+	topic.PromptVersion = gen.PromptVersion
+	return nil
+}
+
+// loadVectors 读取用户全部笔记在索引中的向量，还没有向量的笔记跳过
+func (s *Service) loadVectors(ctx context.Context, userID uint) ([]uint, [][]float32, error) {
+	var noteIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Note{}).Where("user_id = ?", userID).Order("id ASC").Pluck("id", &noteIDs).Error; err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint, 0, len(noteIDs))
+	vectors := make([][]float32, 0, len(noteIDs))
+	for start := 0; start < len(noteIDs); start += retrieveBatch {
+		end := start + retrieveBatch
+		if end > len(noteIDs) {
+			end = len(noteIDs)
+		}
+		points, err := s.index.Retrieve(ctx, noteIDs[start:end], true)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range points {
+			if len(p.Vector) == 0 {
+				continue
+			}
+			ids = append(ids, p.ID)
+			vectors = append(vectors, p.Vector)
+		}
+	}
+	return ids, vectors, nil
+}
+
+func (s *Service) recountSizes(tx *gorm.DB, userID uint) error {
+	return tx.Exec(`UPDATE topics SET size = (SELECT COUNT(*) FROM note_topics WHERE note_topics.topic_id = topics.id) WHERE user_id = ?`, userID).Error
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
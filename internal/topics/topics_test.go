@@ -0,0 +1,40 @@
+package topics
+
+import (
+	"context"
+	"note/internal/models"
+	"note/internal/testutil"
+	"testing"
+)
+
+func TestNoteDeletedRecountsSize(t *testing.T) {
+	db := testutil.DB(t, &models.Topic{}, &models.NoteTopic{})
+	s := New(db, nil, nil, nil, nil)
+
+	topic := models.Topic{UserID: 1, Name: "go", Size: 2}
+	other := models.Topic{UserID: 2, Name: "other", Size: 1}
+	db.Create(&topic)
+	db.Create(&other)
+	db.Create(&models.NoteTopic{NoteID: 10, TopicID: topic.ID, UserID: 1})
+	db.Create(&models.NoteTopic{NoteID: 11, TopicID: topic.ID, UserID: 1})
+	db.Create(&models.NoteTopic{NoteID: 20, TopicID: other.ID, UserID: 2})
+
+	if err := s.NoteDeleted(context.Background(), 10, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var links int64
+	db.Model(&models.NoteTopic{}).Where("note_id = ?", 10).Count(&links)
+	if links != 0 {
+		t.Fatal("note topic link not deleted")
+	}
+	var got models.Topic
+	db.First(&got, topic.ID)
+	if got.Size != 1 {
+		t.Fatalf("topic size = %d, want 1", got.Size)
+	}
+	db.First(&got, other.ID)
+	if got.Size != 1 {
+		t.Fatalf("other user's topic size = %d, want 1", got.Size)
+	}
+}
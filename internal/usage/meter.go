@@ -0,0 +1,187 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note/config"
+	"note/internal/infra/ai"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// Meter 按用户统计 AI 用量并执行配额。
+// 配额判断用 Redis 计数 (快)，明细按天汇总写入 MySQL (持久，用于展示)。
+type Meter struct {
+	db    *gorm.DB
+	cache *cache.RedisCache
+	cfg   *config.Config
+}
+
+func NewMeter(db *gorm.DB, cache *cache.RedisCache, cfg *config.Config) *Meter {
+	return &Meter{db: db, cache: cache, cfg: cfg}
+}
+
+// Quota 某个周期的用量和上限，Limit 为 0 表示不限
+type Quota struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// Exceeded 是否已用完
+func (q Quota) Exceeded() bool {
+	return q.Limit > 0 && q.Used >= q.Limit
+}
+
+// Summary 用户的用量概览
+type Summary struct {
+	Tier   string           `json:"tier"`
+	Day    Quota            `json:"day"`
+	Month  Quota            `json:"month"`
+	ByTask []models.AIUsage `json:"by_task"` // 本月按任务类型汇总
+}
+
+func dayKey(userID uint, t time.Time) string {
+	return fmt.Sprintf("ai:usage:day:%d:%s", userID, t.Format(dayLayout))
+}
+
+func monthKey(userID uint, t time.Time) string {
+	return fmt.Sprintf("ai:usage:month:%d:%s", userID, t.Format(monthLayout))
+}
+
+// Allow 实现 ai.UsageMeter。没有 Redis 时不限额
+func (m *Meter) Allow(ctx context.Context, userID uint) error {
+	if m.cache == nil {
+		return nil
+	}
+
+	daily, monthly := m.limits(m.tier(ctx, userID))
+	if daily <= 0 && monthly <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if (Quota{Used: m.counter(ctx, dayKey(userID, now)), Limit: daily}).Exceeded() ||
+		(Quota{Used: m.counter(ctx, monthKey(userID, now)), Limit: monthly}).Exceeded() {
+		return ai.ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Record 实现 ai.UsageMeter
+func (m *Meter) Record(ctx context.Context, userID uint, task string, usage ai.Usage) {
+	now := time.Now()
+	total := int64(usage.Total())
+
+	if m.cache != nil && total > 0 {
+		pipe := m.cache.Pipeline()
+		pipe.IncrBy(ctx, dayKey(userID, now), total)
+		pipe.Expire(ctx, dayKey(userID, now), 48*time.Hour)
+		pipe.IncrBy(ctx, monthKey(userID, now), total)
+		pipe.Expire(ctx, monthKey(userID, now), 32*24*time.Hour)
+		if _, err := pipe.Exec(ctx); err != nil {
+			zap.L().Warn("Record ai usage counter failed", zap.Uint("uid", userID), zap.Error(err))
+		}
+	}
+
+	row := models.AIUsage{
+		UserID:           userID,
+		Day:              now.Format(dayLayout),
+		Task:             task,
+		Calls:            1,
+		PromptTokens:     int64(usage.PromptTokens),
+		CompletionTokens: int64(usage.CompletionTokens),
+	}
+	err := m.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "task"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"calls":             gorm.Expr("calls + 1"),
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", row.PromptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", row.CompletionTokens),
+			"updated_at":        now,
+		}),
+	}).Create(&row).Error
+	if err != nil {
+		zap.L().Error("Record ai usage failed", zap.Uint("uid", userID), zap.String("task", task), zap.Error(err))
+	}
+}
+
+// Summary 用户今天、本月的用量和配额
+func (m *Meter) Summary(ctx context.Context, userID uint) (*Summary, error) {
+	now := time.Now()
+	tier := m.tier(ctx, userID)
+	daily, monthly := m.limits(tier)
+
+	var byTask []models.AIUsage
+	err := m.db.WithContext(ctx).Model(&models.AIUsage{}).
+		Select("task, SUM(calls) AS calls, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens").
+		Where("user_id = ? AND day LIKE ?", userID, now.Format(monthLayout)+"-%").
+		Group("task").
+		Order("task").
+		Scan(&byTask).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var dayUsed int64
+	err = m.db.WithContext(ctx).Model(&models.AIUsage{}).
+		Select("COALESCE(SUM(prompt_tokens + completion_tokens), 0)").
+		Where("user_id = ? AND day = ?", userID, now.Format(dayLayout)).
+		Scan(&dayUsed).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var monthUsed int64
+	for i := range byTask {
+		byTask[i].UserID = userID
+		monthUsed += byTask[i].PromptTokens + byTask[i].CompletionTokens
+	}
+
+	return &Summary{
+		Tier:   tier,
+		Day:    Quota{Used: dayUsed, Limit: daily},
+		Month:  Quota{Used: monthUsed, Limit: monthly},
+		ByTask: byTask,
+	}, nil
+}
+
+func (m *Meter) tier(ctx context.Context, userID uint) string {
+	var tiers []string
+	err := m.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Pluck("tier", &tiers).Error
+	if err != nil || len(tiers) == 0 || tiers[0] == "" {
+		return models.TierFree
+	}
+	return tiers[0]
+}
+
+func (m *Meter) limits(tier string) (daily, monthly int64) {
+	if tier == models.TierPro {
+		return m.cfg.AIQuotaProDaily, m.cfg.AIQuotaProMonthly
+	}
+	return m.cfg.AIQuotaFreeDaily, m.cfg.AIQuotaFreeMonthly
+}
+
+func (m *Meter) counter(ctx context.Context, key string) int64 {
+	val, err := m.cache.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			zap.L().Warn("Read ai usage counter failed", zap.String("key", key), zap.Error(err))
+		}
+		return 0
+	}
+	n, _ := strconv.ParseInt(val, 10, 64)
+	return n
+}
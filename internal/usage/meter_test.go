@@ -0,0 +1,83 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"note/config"
+	"note/internal/infra/ai"
+	"note/internal/models"
+	"note/internal/testutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMeterQuota(t *testing.T) {
+	db := testutil.DB(t, &models.User{}, &models.AIUsage{})
+	rdb, mr := testutil.Cache(t)
+	m := NewMeter(db, rdb, &config.Config{
+		AIQuotaFreeDaily:   100,
+		AIQuotaFreeMonthly: 150,
+		AIQuotaProDaily:    1000,
+	})
+	ctx := context.Background()
+
+	free := models.User{Username: "free"}
+	pro := models.User{Username: "pro", Tier: models.TierPro}
+	db.Create(&free)
+	db.Create(&pro)
+
+	if err := m.Allow(ctx, free.ID); err != nil {
+		t.Fatalf("fresh user denied: %v", err)
+	}
+	m.Record(ctx, free.ID, ai.TaskEmbed, ai.Usage{PromptTokens: 60})
+	m.Record(ctx, free.ID, ai.TaskEmbed, ai.Usage{PromptTokens: 30, CompletionTokens: 10})
+	if err := m.Allow(ctx, free.ID); !errors.Is(err, ai.ErrQuotaExceeded) {
+		t.Fatalf("free user over the daily limit: %v", err)
+	}
+
+	// 同样的用量没有超过 pro 的日限额，pro 不限月额度
+	m.Record(ctx, pro.ID, ai.TaskEmbed, ai.Usage{PromptTokens: 100})
+	if err := m.Allow(ctx, pro.ID); err != nil {
+		t.Fatalf("pro user denied: %v", err)
+	}
+
+	// 第二天日额度清零，月额度还在累计
+	now := time.Now()
+	mr.Del(dayKey(free.ID, now))
+	if err := m.Allow(ctx, free.ID); err != nil {
+		t.Fatalf("free user denied with daily usage reset: %v", err)
+	}
+	_ = mr.Set(monthKey(free.ID, now), strconv.Itoa(150))
+	if err := m.Allow(ctx, free.ID); !errors.Is(err, ai.ErrQuotaExceeded) {
+		t.Fatalf("free user over the monthly limit: %v", err)
+	}
+
+	summary, err := m.Summary(ctx, free.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Tier != models.TierFree || summary.Day != (Quota{Used: 100, Limit: 100}) || summary.Month != (Quota{Used: 100, Limit: 150}) {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if len(summary.ByTask) != 1 || summary.ByTask[0].Calls != 2 || summary.ByTask[0].PromptTokens != 90 {
+		t.Fatalf("by task = %+v", summary.ByTask)
+	}
+}
+
+func TestMeterWithoutRedis(t *testing.T) {
+	db := testutil.DB(t, &models.User{}, &models.AIUsage{})
+	m := NewMeter(db, nil, &config.Config{AIQuotaFreeDaily: 1})
+	ctx := context.Background()
+
+	// 没有 Redis 时不限额，但明细照常记录
+	m.Record(ctx, 1, ai.TaskEmbed, ai.Usage{PromptTokens: 10})
+	if err := m.Allow(ctx, 1); err != nil {
+		t.Fatalf("Allow() without redis: %v", err)
+	}
+	var rows []models.AIUsage
+	db.Find(&rows)
+	if len(rows) != 1 || rows[0].PromptTokens != 10 {
+		t.Fatalf("usage rows = %+v", rows)
+	}
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"net/http"
+	"note/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetMyAIUsage 查看自己今天和本月的 AI 用量及配额
+func (h *UserHandler) GetMyAIUsage(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "请先登录")
+		return
+	}
+
+	summary, err := h.svc.Usage.Summary(c, userID)
+	if err != nil {
+		zap.L().Error("Get ai usage failed", zap.Uint("uid", userID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取用量失败")
+		return
+	}
+
+	utils.Success(c, summary)
+}
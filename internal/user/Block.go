@@ -0,0 +1,119 @@
+package user
+
+import (
+	"net/http"
+	"note/internal/models"
+	"note/internal/pagination"
+	"note/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// BlockUser 屏蔽用户，之后推荐中不再出现他的笔记
+func (h *UserHandler) BlockUser(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	me, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if me == uint(targetID) {
+		utils.Error(c, http.StatusBadRequest, "不能屏蔽自己")
+		return
+	}
+
+	var exists int64
+	h.svc.DB.Model(&models.User{}).Where("id = ?", targetID).Count(&exists)
+	if exists == 0 {
+		utils.Error(c, http.StatusNotFound, "用户不存在")
+		return
+	}
+
+	block := models.UserBlock{BlockerID: me, BlockedID: uint(targetID)}
+	if err := h.svc.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error; err != nil {
+		zap.L().Error("Block user failed", zap.Uint("uid", me), zap.Uint64("target", targetID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "屏蔽失败")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// UnblockUser 取消屏蔽
+func (h *UserHandler) UnblockUser(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的用户ID")
+		return
+	}
+
+	me, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.svc.DB.Where("blocker_id = ? AND blocked_id = ?", me, targetID).Delete(&models.UserBlock{}).Error; err != nil {
+		zap.L().Error("Unblock user failed", zap.Uint("uid", me), zap.Uint64("target", targetID), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "取消屏蔽失败")
+		return
+	}
+
+	utils.Success(c, nil)
+}
+
+// blocksSort 屏蔽列表的游标排序标识
+const blocksSort = "blocks"
+
+// ListMyBlocks 我屏蔽的用户，按屏蔽时间倒序
+func (h *UserHandler) ListMyBlocks(c *gin.Context) {
+	me, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	cursor, limit, err := h.svc.Pager.Parse(c, blocksSort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
+
+	query := h.svc.DB.Table("users").
+		Select("users.id, users.username, users.avatar, users.bio, user_blocks.id AS block_id").
+		Joins("JOIN user_blocks ON users.id = user_blocks.blocked_id").
+		Where("user_blocks.blocker_id = ?", me)
+	if cursor != nil {
+		query = query.Where("user_blocks.id < ?", cursor.ID)
+	}
+
+	var rows []struct {
+		models.UserBrief
+		BlockID uint
+	}
+	if err := query.Order("user_blocks.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		zap.L().Error("List blocks failed", zap.Uint("uid", me), zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取列表失败")
+		return
+	}
+
+	var next *pagination.Cursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &pagination.Cursor{Sort: blocksSort, ID: rows[limit-1].BlockID}
+	}
+	users := make([]models.UserBrief, len(rows))
+	for i, r := range rows {
+		users[i] = r.UserBrief
+	}
+
+	utils.Success(c, h.svc.Pager.Page(users, next))
+}
@@ -2,8 +2,10 @@ package user
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"note/internal/models"
+	"note/internal/pagination"
 	"note/internal/utils"
 	"strconv"
 
@@ -78,6 +80,7 @@ func (h *UserHandler) FollowUser(c *gin.Context) {
 		}
 		return
 	}
+	h.svc.Rabbit.PublishFeed(models.FeedMsg{Event: models.FeedFollow, AuthorID: targetID, FollowerID: me})
 	utils.Success(c, gin.H{"message": "Followed successfully"})
 }
 
@@ -96,6 +99,7 @@ func (h *UserHandler) UnfollowUser(c *gin.Context) {
 		return
 	}
 
+	unfollowed := false
 	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
 		result := tx.Where("follower_id = ? AND followed_id = ?", me, targetID).Delete(&models.UserFollow{})
 
@@ -106,6 +110,7 @@ func (h *UserHandler) UnfollowUser(c *gin.Context) {
 		if result.RowsAffected == 0 {
 			return nil
 		}
+		unfollowed = true
 
 		if err := tx.Model(&models.User{}).Where("id = ?", me).
 			Update("follow_count", gorm.Expr("follow_count - 1")).Error; err != nil {
@@ -124,6 +129,9 @@ func (h *UserHandler) UnfollowUser(c *gin.Context) {
 		utils.Error(c, http.StatusInternalServerError, "取消关注失败")
 		return
 	}
+	if unfollowed {
+		h.svc.Rabbit.PublishFeed(models.FeedMsg{Event: models.FeedUnfollow, AuthorID: targetID, FollowerID: me})
+	}
 	utils.Success(c, gin.H{"message": "Unfollowed successfully"})
 }
 
@@ -148,25 +156,7 @@ func (h *UserHandler) GetFollowingList(c *gin.Context) {
 		targetID = uint(idUint64)
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
-	offset := (page - 1) * size
-
-	var users []models.UserBrief
-	err = h.svc.DB.Table("users").
-		Select("users.id, users.username, users.avatar, users.bio").
-		Joins("JOIN user_follows ON users.id = user_follows.followed_id").
-		Where("user_follows.follower_id = ?", targetID).
-		Limit(size).Offset(offset).
-		Scan(&users).Error
-
-	if err != nil {
-		zap.L().Error("get following list failed", zap.Error(err))
-		utils.Error(c, http.StatusInternalServerError, "获取列表失败")
-		return
-	}
-
-	utils.Success(c, users)
+	h.listFollows(c, "following", "user_follows.followed_id", "user_follows.follower_id", targetID)
 }
 
 func (h *UserHandler) GetFollowersList(c *gin.Context) {
@@ -190,24 +180,46 @@ func (h *UserHandler) GetFollowersList(c *gin.Context) {
 		targetID = uint(idUint64)
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
-	offset := (page - 1) * size
+	h.listFollows(c, "followers", "user_follows.follower_id", "user_follows.followed_id", targetID)
+}
 
-	var users []models.UserBrief
+// listFollows 按关注时间倒序分页列出关注关系另一端的用户，游标为关注记录的 ID，
+// 只能在同一个用户的同一个列表 (kind) 里使用
+func (h *UserHandler) listFollows(c *gin.Context, kind, userColumn, targetColumn string, targetID uint) {
+	sort := fmt.Sprintf("%s:%d", kind, targetID)
+	cursor, limit, err := h.svc.Pager.Parse(c, sort)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, "无效的分页参数")
+		return
+	}
 
-	err = h.svc.DB.Table("users").
-		Select("users.id, users.username, users.avatar, users.bio").
-		Joins("JOIN user_follows ON users.id = user_follows.follower_id").
-		Where("user_follows.followed_id = ?", targetID).
-		Limit(size).Offset(offset).
-		Scan(&users).Error
+	query := h.svc.DB.Table("users").
+		Select("users.id, users.username, users.avatar, users.bio, user_follows.id AS follow_id").
+		Joins("JOIN user_follows ON users.id = "+userColumn).
+		Where(targetColumn+" = ?", targetID)
+	if cursor != nil {
+		query = query.Where("user_follows.id < ?", cursor.ID)
+	}
 
-	if err != nil {
-		zap.L().Error("get followers list failed", zap.Error(err))
+	var rows []struct {
+		models.UserBrief
+		FollowID uint
+	}
+	if err := query.Order("user_follows.id DESC").Limit(limit + 1).Scan(&rows).Error; err != nil {
+		zap.L().Error("list follows failed", zap.String("target_column", targetColumn), zap.Error(err))
 		utils.Error(c, http.StatusInternalServerError, "获取列表失败")
 		return
 	}
 
-	utils.Success(c, users)
+	var next *pagination.Cursor
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next = &pagination.Cursor{Sort: sort, ID: rows[limit-1].FollowID}
+	}
+	users := make([]models.UserBrief, len(rows))
+	for i, r := range rows {
+		users[i] = r.UserBrief
+	}
+
+	utils.Success(c, h.svc.Pager.Page(users, next))
 }
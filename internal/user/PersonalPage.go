@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"note/internal/models"
+	"note/internal/reaction"
 	"note/internal/utils"
 	"note/internal/validators"
 	"strconv"
@@ -54,7 +55,7 @@ func (h *UserHandler) PersonalPage(c *gin.Context) {
 
 	var notes []models.Note
 	query := h.svc.DB.Preload("Tags").
-		Select("id, title, summary, is_private, is_pinned, favorite_count, created_at, updated_at").
+		Select("id, title, summary, is_private, is_pinned, favorite_count, reaction_counts, created_at, updated_at").
 		Where("user_id = ?", targetID).
 		Order("is_pinned DESC, updated_at DESC")
 
@@ -68,6 +69,17 @@ func (h *UserHandler) PersonalPage(c *gin.Context) {
 		return
 	}
 
+	var collections []models.Collection
+	collectionQuery := h.svc.DB.Where("user_id = ?", targetID).Order("updated_at DESC")
+	if !isOwner {
+		collectionQuery = collectionQuery.Where("is_private = ?", false)
+	}
+	if err := collectionQuery.Find(&collections).Error; err != nil {
+		zap.L().Error("db query collections failed", zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "获取合集列表失败")
+		return
+	}
+
 	isFollowing := false
 	if !isOwner {
 		var count int64
@@ -77,6 +89,15 @@ func (h *UserHandler) PersonalPage(c *gin.Context) {
 		isFollowing = count > 0
 	}
 
+	noteIDs := make([]uint, len(notes))
+	for i, n := range notes {
+		noteIDs[i] = n.ID
+	}
+	myReactions, err := reaction.Mine(h.svc.DB, viewerID, noteIDs)
+	if err != nil {
+		zap.L().Warn("Load my reactions failed", zap.Uint("uid", viewerID), zap.Error(err))
+	}
+
 	noteBriefs := make([]models.NoteBrief, len(notes))
 	for i, n := range notes {
 		var tagNames []string
@@ -92,6 +113,22 @@ func (h *UserHandler) PersonalPage(c *gin.Context) {
 			IsPinned:      n.IsPinned,
 			Tags:          tagNames,
 			UpdatedAt:     n.UpdatedAt.Format("2006-01-02"),
+
+			ReactionCounts: n.ReactionCounts,
+			MyReactions:    myReactions[n.ID],
+		}
+	}
+
+	collectionBriefs := make([]models.CollectionBrief, len(collections))
+	for i, col := range collections {
+		collectionBriefs[i] = models.CollectionBrief{
+			ID:            col.ID,
+			Name:          col.Name,
+			Description:   col.Description,
+			IsPrivate:     col.IsPrivate,
+			ItemCount:     col.ItemCount,
+			FollowerCount: col.FollowerCount,
+			UpdatedAt:     col.UpdatedAt.Format("2006-01-02"),
 		}
 	}
 
@@ -105,6 +142,7 @@ func (h *UserHandler) PersonalPage(c *gin.Context) {
 		IsFollowing: isFollowing,
 		CreatedAt:   targetUser.CreatedAt.Format("2006-01-02"),
 		Documents:   noteBriefs,
+		Collections: collectionBriefs,
 	}
 
 	utils.Success(c, page)
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"note/internal/infra/cache"
+	"note/internal/models"
+	"note/internal/utils"
+	"note/internal/validators"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (h *UserHandler) GetMySettings(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "请先登录")
+		return
+	}
+
+	setting := models.UserSetting{UserID: userID}
+	if err := h.svc.DB.Where("user_id = ?", userID).First(&setting).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.Error(c, http.StatusInternalServerError, "获取设置失败")
+		return
+	}
+
+	utils.Success(c, setting)
+}
+
+func (h *UserHandler) UpdateMySettings(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "请先登录")
+		return
+	}
+
+	var req validators.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, "参数错误")
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.AutoApplyTags != nil {
+		updates["auto_apply_tags"] = *req.AutoApplyTags
+	}
+	if req.AILanguage != nil {
+		updates["ai_language"] = *req.AILanguage
+	}
+	if req.AIStyle != nil {
+		updates["ai_style"] = strings.TrimSpace(*req.AIStyle)
+	}
+	if req.PauseHistory != nil {
+		updates["pause_history"] = *req.PauseHistory
+	}
+	if req.DigestFrequency != nil {
+		frequency := *req.DigestFrequency
+		if frequency == "off" {
+			frequency = models.DigestOff
+		}
+		updates["digest_frequency"] = frequency
+	}
+	if req.DigestSections != nil {
+		// map 更新不会经过字段的 serializer，需要自己编码
+		sections, _ := json.Marshal(*req.DigestSections)
+		updates["digest_sections"] = string(sections)
+	}
+
+	setting := models.UserSetting{UserID: userID}
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		// 第一次修改时先插入一条默认设置
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&setting).Error; err != nil {
+			return err
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&setting).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return tx.First(&setting, "user_id = ?", userID).Error
+	})
+	if err != nil {
+		zap.L().Error("Update user settings failed", zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "更新设置失败")
+		return
+	}
+	if req.PauseHistory != nil {
+		_ = h.svc.Cache.Del(c, cache.HistoryPausedKey(userID))
+	}
+
+	utils.Success(c, setting)
+}
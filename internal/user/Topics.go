@@ -0,0 +1,145 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"note/internal/models"
+	"note/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultTopicNotes = 20
+	maxTopicNotes     = 100
+)
+
+type TopicNote struct {
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Similarity float32   `json:"similarity"`
+}
+
+type TopicWithNotes struct {
+	models.Topic
+	Notes []TopicNote `json:"notes"`
+}
+
+// GetMyTopics 当前用户的自动主题，每个主题带上离主题中心最近的若干篇笔记
+func (h *UserHandler) GetMyTopics(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "请先登录")
+		return
+	}
+
+	perTopic, _ := strconv.Atoi(c.DefaultQuery("notes_per_topic", strconv.Itoa(defaultTopicNotes)))
+	if perTopic <= 0 || perTopic > maxTopicNotes {
+		perTopic = defaultTopicNotes
+	}
+
+	var topics []models.Topic
+	if err := h.svc.DB.Where("user_id = ?", userID).Order("size DESC, id ASC").Find(&topics).Error; err != nil {
+		utils.Error(c, http.StatusInternalServerError, "获取主题失败")
+		return
+	}
+
+	type row struct {
+		TopicNote
+		TopicID uint
+	}
+	var rows []row
+	err = h.svc.DB.Table("note_topics").
+		Select("notes.id, notes.title, notes.updated_at, note_topics.similarity, note_topics.topic_id").
+		Joins("JOIN notes ON notes.id = note_topics.note_id").
+		Where("note_topics.user_id = ?", userID).
+		Order("note_topics.similarity DESC").
+		Scan(&rows).Error
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, "获取主题失败")
+		return
+	}
+
+	byTopic := make(map[uint][]TopicNote, len(topics))
+	for _, r := range rows {
+		if len(byTopic[r.TopicID]) < perTopic {
+			byTopic[r.TopicID] = append(byTopic[r.TopicID], r.TopicNote)
+		}
+	}
+
+	result := make([]TopicWithNotes, len(topics))
+	for i, t := range topics {
+		notes := byTopic[t.ID]
+		if notes == nil {
+			notes = []TopicNote{}
+		}
+		result[i] = TopicWithNotes{Topic: t, Notes: notes}
+	}
+
+	utils.Success(c, result)
+}
+
+// ConvertTopicToTag 把主题变成真正的标签：同名标签已存在时直接复用，再把主题下的全部笔记打上这个标签
+func (h *UserHandler) ConvertTopicToTag(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.Error(c, http.StatusUnauthorized, "请先登录")
+		return
+	}
+
+	var topic models.Topic
+	var tag models.Tag
+	var noteIDs []uint
+	err = h.svc.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", c.Param("tid"), userID).First(&topic).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("user_id = ? AND name = ?", userID, topic.Name).First(&tag).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag = models.Tag{UserID: userID, Name: topic.Name, Topic: models.TopicName(topic.Name), Color: h.svc.Config.AISuggestTagColor}
+			err = tx.Create(&tag).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.NoteTopic{}).Where("topic_id = ?", topic.ID).Pluck("note_id", &noteIDs).Error; err != nil {
+			return err
+		}
+		if len(noteIDs) > 0 {
+			links := make([]models.NoteTag, len(noteIDs))
+			for i, id := range noteIDs {
+				links[i] = models.NoteTag{NoteID: id, TagID: tag.ID}
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&topic).Update("tag_id", tag.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, "主题不存在")
+			return
+		}
+		zap.L().Error("Convert topic to tag failed", zap.Error(err))
+		utils.Error(c, http.StatusInternalServerError, "操作失败")
+		return
+	}
+
+	_ = h.svc.Cache.Del(c, fmt.Sprintf("tags:user:%d", userID))
+	for _, id := range noteIDs {
+		_ = h.svc.Cache.Del(c, fmt.Sprintf("note:%d", id))
+	}
+	_ = h.svc.Cache.ClearCacheByPattern(c, h.svc.Cache, fmt.Sprintf("notes:user:%d*", userID))
+
+	utils.Success(c, gin.H{"tag": tag, "tagged_notes": len(noteIDs)})
+}
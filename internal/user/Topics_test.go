@@ -0,0 +1,87 @@
+package user
+
+import (
+	"net/http"
+	"note/config"
+	"note/internal/models"
+	"note/internal/svc"
+	"note/internal/testutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestConvertTopicToTag(t *testing.T) {
+	db := testutil.DB(t, &models.Note{}, &models.Tag{}, &models.Topic{}, &models.NoteTopic{})
+	rdb, _ := testutil.Cache(t)
+	h := NewUserHandler(&svc.ServiceContext{Config: &config.Config{AISuggestTagColor: "#888888"}, DB: db, Cache: rdb})
+
+	const userID uint = 7
+	topic := models.Topic{UserID: userID, Name: "Go 并发"}
+	db.Create(&topic)
+	other := models.Topic{UserID: userID + 1, Name: "别人的主题"}
+	db.Create(&other)
+	var noteIDs []uint
+	for i := 0; i < 3; i++ {
+		n := models.Note{UserID: userID, Title: "t", Content: "c"}
+		db.Create(&n)
+		db.Create(&models.NoteTopic{NoteID: n.ID, TopicID: topic.ID, UserID: userID})
+		noteIDs = append(noteIDs, n.ID)
+	}
+	// 其中一篇已经手动打过同名标签
+	existing := models.Tag{UserID: userID, Name: topic.Name, Color: "#ff0000"}
+	db.Create(&existing)
+	db.Create(&models.NoteTag{NoteID: noteIDs[0], TagID: existing.ID})
+
+	convert := func(tid uint) int {
+		c, w := testutil.Context(http.MethodPost, "/users/me/topics/x/tag", userID)
+		c.Params = gin.Params{{Key: "tid", Value: strconv.FormatUint(uint64(tid), 10)}}
+		h.ConvertTopicToTag(c)
+		return w.Code
+	}
+
+	// 重复转换是幂等的
+	for i := 0; i < 2; i++ {
+		if code := convert(topic.ID); code != http.StatusOK {
+			t.Fatalf("convert #%d: status %d", i+1, code)
+		}
+	}
+
+	var tags []models.Tag
+	db.Where("user_id = ?", userID).Find(&tags)
+	if len(tags) != 1 || tags[0].ID != existing.ID {
+		t.Fatalf("expected existing tag to be reused, got %+v", tags)
+	}
+	var links int64
+	db.Model(&models.NoteTag{}).Where("tag_id = ? AND note_id IN ?", existing.ID, noteIDs).Count(&links)
+	if links != int64(len(noteIDs)) {
+		t.Fatalf("expected %d tagged notes, got %d", len(noteIDs), links)
+	}
+	db.First(&topic, topic.ID)
+	if topic.TagID == nil || *topic.TagID != existing.ID {
+		t.Fatalf("topic.tag_id = %v, want %d", topic.TagID, existing.ID)
+	}
+
+	if code := convert(other.ID); code != http.StatusNotFound {
+		t.Fatalf("converting another user's topic: status %d, want 404", code)
+	}
+}
+
+// MySQL 下 DoNothing 要靠模型的主键生成 ON DUPLICATE KEY UPDATE，没有模型时会生成空的子句
+func TestNoteTagInsertMySQL(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/x", SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&[]models.NoteTag{{NoteID: 1, TagID: 2}})
+	})
+	if !strings.HasSuffix(sql, "ON DUPLICATE KEY UPDATE `note_id`=`note_id`") {
+		t.Fatalf("unexpected SQL: %s", sql)
+	}
+}
@@ -13,3 +13,51 @@ type UpdateNoteRequest struct {
 	IsPrivate *bool   `json:"isPrivate"`
 	TagIDs    *[]uint `json:"tag_ids"`
 }
+
+type CreateAITaskRequest struct {
+	Task string `json:"task" binding:"required,oneof=generate_title generate_summary suggest_tags generate_flashcards"`
+}
+
+// AssistRequest Start/End 为选中文本的 UTF-16 下标 (和浏览器里字符串、selectionStart 的下标一致)，都不传表示整篇笔记
+type AssistRequest struct {
+	Action         string `json:"action" binding:"required,oneof=rewrite shorten expand grammar tone translate continue"`
+	Start          *int   `json:"start" binding:"omitempty,min=0"`
+	End            *int   `json:"end" binding:"omitempty,min=0"`
+	Tone           string `json:"tone" binding:"max=32"`
+	TargetLanguage string `json:"target_language" binding:"omitempty,oneof=zh en ja"`
+}
+
+// ReviewFlashcardRequest Grade 为 SM-2 的回忆质量：0 完全不记得 … 5 轻松想起
+type ReviewFlashcardRequest struct {
+	Grade *int `json:"grade" binding:"required,min=0,max=5"`
+}
+
+type MergeNotesRequest struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1,max=20"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=500"`
+	IsPrivate   bool   `json:"is_private"`
+}
+
+type UpdateCollectionRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=64"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	IsPrivate   *bool   `json:"is_private"`
+}
+
+type AddCollectionItemRequest struct {
+	NoteID     uint   `json:"note_id" binding:"required"`
+	Annotation string `json:"annotation" binding:"max=500"`
+}
+
+type UpdateCollectionItemRequest struct {
+	Annotation *string `json:"annotation" binding:"required,max=500"`
+}
+
+// ReorderCollectionRequest NoteIDs 为合集中全部笔记的新顺序
+type ReorderCollectionRequest struct {
+	NoteIDs []uint `json:"note_ids" binding:"required,min=1"`
+}
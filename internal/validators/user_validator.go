@@ -15,3 +15,14 @@ type UpdateProfileRequest struct {
 	Avatar   *string `json:"avatar,omitempty" binding:"omitempty,url"`
 	Bio      *string `json:"bio,omitempty" binding:"omitempty,max=150"`
 }
+
+type UpdateSettingsRequest struct {
+	AutoApplyTags *bool `json:"auto_apply_tags"`
+	// AILanguage 传空字符串表示恢复默认 (中文)
+	AILanguage   *string `json:"ai_language" binding:"omitempty,oneof=zh en ja"`
+	AIStyle      *string `json:"ai_style" binding:"omitempty,max=32"`
+	PauseHistory *bool   `json:"pause_history"`
+	// DigestFrequency off/daily/weekly；DigestSections 为空表示全部
+	DigestFrequency *string   `json:"digest_frequency" binding:"omitempty,oneof=off daily weekly"`
+	DigestSections  *[]string `json:"digest_sections" binding:"omitempty,dive,oneof=notes activity"`
+}
@@ -9,6 +9,7 @@ import SearchPage from './pages/SearchPage';
 import ProfilePage from './pages/ProfilePage';
 import SettingsPage from './pages/SettingsPage';
 import FollowingPage from './pages/FollowingPage';
+import ForYouPage from './pages/ForYouPage';
 import NoteDetailPage from './pages/NoteDetailPage';
 
 const queryClient = new QueryClient();
@@ -38,6 +39,7 @@ function App() {
                         <Route path="u/:id" element={<ProfilePage />} />
                         <Route path="settings" element={<SettingsPage />} />
                         <Route path="following" element={<FollowingPage />} />
+                        <Route path="foryou" element={<ForYouPage />} />
                         <Route path="/notes/:id" element={<NoteDetailPage />} />
                     </Route>
                 </Routes>
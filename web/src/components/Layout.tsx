@@ -1,7 +1,7 @@
 import React from 'react';
 import { Link, useLocation, Outlet, useNavigate } from 'react-router-dom';
 import { useQuery } from '@tanstack/react-query';
-import { Home, Globe, Settings, LogOut, Menu, Search, UserCheck, Tag as TagIcon } from 'lucide-react';
+import { Home, Globe, Settings, LogOut, Menu, Search, UserCheck, Sparkles, Tag as TagIcon } from 'lucide-react';
 import { cn } from '@/lib/utils';
 import api from '@/lib/axios';
 import type {UserProfile, Tag} from '@/types';
@@ -68,6 +68,7 @@ export default function Layout() {
         { icon: Home, label: '我的笔记', path: '/' },
         { icon: Search, label: '搜索', path: '/search' },
         { icon: UserCheck, label: '关注动态', path: '/following' },
+        { icon: Sparkles, label: '为你推荐', path: '/foryou' },
         { icon: Globe, label: '探索广场', path: '/community' },
     ];
 
@@ -1,4 +1,5 @@
 import React, { useState, useRef, useEffect } from 'react';
+import { useQuery } from '@tanstack/react-query';
 import { Link, useNavigate } from 'react-router-dom';
 import ReactMarkdown from 'react-markdown';
 import { formatDistanceToNow } from 'date-fns';
@@ -15,7 +16,7 @@ interface NoteCardProps {
     onUpdate?: () => void;
 }
 
-// 预设的常用 Emoji 列表
+// 后端没有返回可用 Emoji 时的兜底列表
 const QUICK_EMOJIS = ["👍", "❤️", "😂", "😮", "😢", "🔥", "🎉", "👀"];
 
 export const NoteCard: React.FC<NoteCardProps> = ({ note, onDelete, onUpdate }) => {
@@ -31,6 +32,17 @@ export const NoteCard: React.FC<NoteCardProps> = ({ note, onDelete, onUpdate })
 
     // --- Emoji Reactions ---
     const [reactions, setReactions] = useState<Record<string, number>>(note.reaction_counts || {});
+    const [myReactions, setMyReactions] = useState<string[]>(note.my_reactions || []);
+
+    // 可用的 Emoji 由部署配置决定，所有卡片共用一次请求
+    const { data: emojis = QUICK_EMOJIS } = useQuery({
+        queryKey: ['reaction-emojis'],
+        queryFn: async () => {
+            const res = await api.get<any, any>('/notes/reaction-emojis');
+            return (res.data as string[]) || QUICK_EMOJIS;
+        },
+        staleTime: Infinity,
+    });
 
     // 🛡️ 用户名显示修复逻辑
     const displayUser = note.user || {
@@ -92,13 +104,15 @@ export const NoteCard: React.FC<NoteCardProps> = ({ note, onDelete, onUpdate })
         }
     };
 
-    // 处理 Emoji 点击 (对接后端 API)
+    // 处理 Emoji 点击 (对接后端 API，再点一次取消)
     const handleReaction = async (emoji: string) => {
+        const reacted = myReactions.includes(emoji);
         // 乐观更新
         setReactions(prev => {
             const currentCount = prev[emoji] || 0;
-            return { ...prev, [emoji]: currentCount + 1 };
+            return { ...prev, [emoji]: Math.max(currentCount + (reacted ? -1 : 1), 0) };
         });
+        setMyReactions(prev => reacted ? prev.filter(e => e !== emoji) : [...prev, emoji]);
         setShowEmojiPicker(false);
 
         try {
@@ -182,7 +196,10 @@ export const NoteCard: React.FC<NoteCardProps> = ({ note, onDelete, onUpdate })
                             <button
                                 key={emoji}
                                 onClick={(e) => { e.stopPropagation(); handleReaction(emoji); }}
-                                className="flex items-center gap-1 px-2 py-1 bg-slate-50 border border-slate-200 rounded-full text-xs font-medium hover:bg-indigo-50 hover:border-indigo-200 transition-colors group/emoji"
+                                className={cn(
+                                    "flex items-center gap-1 px-2 py-1 border rounded-full text-xs font-medium hover:bg-indigo-50 hover:border-indigo-200 transition-colors group/emoji",
+                                    myReactions.includes(emoji) ? "bg-indigo-50 border-indigo-200" : "bg-slate-50 border-slate-200"
+                                )}
                             >
                                 <span>{emoji}</span>
                                 <span className="text-slate-500 group-hover/emoji:text-indigo-600">{count}</span>
@@ -205,7 +222,7 @@ export const NoteCard: React.FC<NoteCardProps> = ({ note, onDelete, onUpdate })
                                     className="absolute bottom-full left-0 mb-2 bg-white border border-slate-200 shadow-xl rounded-xl p-2 z-20 flex gap-1 w-max animate-in fade-in zoom-in-95 duration-200"
                                     onClick={(e) => e.stopPropagation()}
                                 >
-                                    {QUICK_EMOJIS.map(emoji => (
+                                    {emojis.map(emoji => (
                                         <button
                                             key={emoji}
                                             onClick={() => handleReaction(emoji)}
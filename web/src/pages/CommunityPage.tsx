@@ -19,9 +19,9 @@ export default function CommunityPage() {
         if (Array.isArray(responseData)) {
             // 情况 1: 后端直接返回数组 [Note, Note]
             notes = responseData;
-        } else if (responseData.notes) {
-            // 情况 2: 后端返回 { notes: [...] } (根据你的API文档，社区接口应该是这个)
-            notes = responseData.notes;
+        } else if (responseData.items) {
+            // 情况 2: 分页接口返回 { items: [...], next_cursor, has_more }
+            notes = responseData.items;
         } else if (responseData.list) {
             // 情况 3: 后端返回 { list: [...] } (为了兼容性)
             notes = responseData.list;
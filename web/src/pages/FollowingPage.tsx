@@ -8,9 +8,9 @@ export default function FollowingPage() {
     const { data, isLoading } = useQuery({
         queryKey: ['following-notes'],
         queryFn: async () => {
-            // 假设后端返回的是 { data: [...] } 或 { data: { list: [] } }
+            // 后端返回 { items, next_cursor, has_more }
             const res = await api.get<any, any>('/notes/follow');
-            return Array.isArray(res.data) ? res.data : (res.data?.list || []);
+            return res.data?.items || [];
         },
     });
 
@@ -0,0 +1,52 @@
+import { useQuery } from '@tanstack/react-query';
+import { Loader2, Sparkles } from 'lucide-react';
+import api from '@/lib/axios';
+import { NoteCard } from '@/components/NoteCard';
+import type {Note} from '@/types';
+
+export default function ForYouPage() {
+    const { data, isLoading } = useQuery({
+        queryKey: ['foryou-notes'],
+        queryFn: async () => {
+            // 后端返回 { items, next_cursor, has_more }
+            const res = await api.get<any, any>('/notes/foryou');
+            return res.data?.items || [];
+        },
+    });
+
+    const notes = data || [];
+
+    return (
+        <div className="max-w-2xl mx-auto pb-20">
+            <div className="flex items-center gap-3 mb-6">
+                <div className="p-2 bg-indigo-100 text-indigo-600 rounded-lg">
+                    <Sparkles size={24} />
+                </div>
+                <div>
+                    <h2 className="text-xl font-bold text-slate-800">为你推荐</h2>
+                    <p className="text-xs text-slate-400">根据你收藏、回应和浏览过的笔记推荐</p>
+                </div>
+            </div>
+
+            <div className="space-y-4">
+                {isLoading ? (
+                    <div className="flex justify-center py-10 text-slate-400">
+                        <Loader2 className="animate-spin" />
+                    </div>
+                ) : notes.length > 0 ? (
+                    notes.map((note: Note) => (
+                        <NoteCard key={note.id} note={note} />
+                    ))
+                ) : (
+                    <div className="text-center py-16">
+                        <div className="inline-block p-4 bg-slate-100 rounded-full mb-4 text-slate-400">
+                            <Sparkles size={32} />
+                        </div>
+                        <p className="text-slate-500 mb-2">暂时没有推荐</p>
+                        <p className="text-sm text-slate-400">多收藏、回应一些笔记，推荐会更懂你</p>
+                    </div>
+                )}
+            </div>
+        </div>
+    );
+}
\ No newline at end of file
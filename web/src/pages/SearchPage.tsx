@@ -30,10 +30,10 @@ export default function SearchPage() {
             const res = await api.get<any, any>(`${searchApi}?q=${debouncedQuery}`);
 
             // 数据标准化处理（兼容后端两种不同的返回结构）
-            // 普通搜索: res.data.notes
+            // 普通搜索: res.data.items
             // AI搜索: res.data (直接是数组)
             if (mode === 'normal') {
-                return { list: res.data?.notes || [] };
+                return { list: res.data?.items || [] };
             } else {
                 return { list: Array.isArray(res.data) ? res.data : [] };
             }
@@ -47,6 +47,7 @@ export interface Note {
     user?: User;             // 作者信息 (如果后端 Preload 了)
     Tags?: Tag[];            // 注意大写 T
     reaction_counts?: Record<string, number>; // 对应 map[string]int
+    my_reactions?: string[];  // 当前用户点过的 emoji
 }
 
 // 通用 API 响应结构
@@ -67,14 +68,14 @@ export interface AuthResponse {
 }
 
 // 笔记列表响应
-// 后端 /notes 直接返回数组，但 /notes/search 返回对象，这里做联合类型兼容
+// 后端 /notes 直接返回数组，分页接口 (社区、关注动态、搜索等) 返回 { items, next_cursor, has_more }
 export interface NoteListResponse {
     code: number;
     message: string;
     data: Note[] | {
         list?: Note[];
-        notes?: Note[]; // 搜索接口返回的是 notes 字段
-        total: number;
-        page?: number;
+        items?: Note[];
+        next_cursor?: string;
+        has_more?: boolean;
     };
 }
\ No newline at end of file